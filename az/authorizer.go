@@ -1,6 +1,8 @@
 package az
 
 import (
+	"sync"
+
 	"github.com/aserto-dev/go-aserto"
 	"google.golang.org/grpc"
 
@@ -13,6 +15,8 @@ import (
 type Client struct {
 	authz.AuthorizerClient
 	conn *grpc.ClientConn
+
+	closeOnce sync.Once
 }
 
 // NewClient creates a Client with the specified connection options.
@@ -37,8 +41,15 @@ func FromConnection(conn *grpc.ClientConn) *Client {
 }
 
 // Close closes the underlying connection.
+// It is safe to call Close more than once and from multiple goroutines. Calls after the first return nil.
 func (c *Client) Close() error {
-	return c.conn.Close()
+	var err error
+
+	c.closeOnce.Do(func() {
+		err = c.conn.Close()
+	})
+
+	return err
 }
 
 // Connection returns the underlying grpc connection.
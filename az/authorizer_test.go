@@ -0,0 +1,47 @@
+package az_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto"
+	"github.com/aserto-dev/go-aserto/az"
+	assrt "github.com/stretchr/testify/assert"
+)
+
+func TestCloseIsIdempotent(t *testing.T) {
+	assert := assrt.New(t)
+
+	client, err := az.New(aserto.WithAddr("localhost:8282"), aserto.WithNoTLS(true))
+	assert.NoError(err)
+
+	assert.NoError(client.Close())
+	assert.NoError(client.Close())
+}
+
+func TestConcurrentClose(t *testing.T) {
+	assert := assrt.New(t)
+
+	client, err := az.New(aserto.WithAddr("localhost:8282"), aserto.WithNoTLS(true))
+	assert.NoError(err)
+
+	var wg sync.WaitGroup
+
+	errs := make(chan error, 10)
+
+	for range 10 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			errs <- client.Close()
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(err)
+	}
+}
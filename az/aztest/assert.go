@@ -0,0 +1,103 @@
+package aztest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// TestingT is the subset of testing.TB used by the assertion helpers.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...any)
+}
+
+// AssertAllowed calls client.Is with req and reports a test failure unless every returned decision is true.
+//
+// On failure, the message includes the policy, identity, and resource of the request along with the decisions
+// returned by the authorizer. AssertAllowed returns true if the assertion succeeded.
+func AssertAllowed(t TestingT, client authz.AuthorizerClient, req *authz.IsRequest) bool {
+	t.Helper()
+
+	return assertDecision(t, client, req, true)
+}
+
+// AssertDenied calls client.Is with req and reports a test failure unless every returned decision is false.
+//
+// On failure, the message includes the policy, identity, and resource of the request along with the decisions
+// returned by the authorizer. AssertDenied returns true if the assertion succeeded.
+func AssertDenied(t TestingT, client authz.AuthorizerClient, req *authz.IsRequest) bool {
+	t.Helper()
+
+	return assertDecision(t, client, req, false)
+}
+
+func assertDecision(t TestingT, client authz.AuthorizerClient, req *authz.IsRequest, expected bool) bool {
+	t.Helper()
+
+	verb := "denied"
+	if expected {
+		verb = "allowed"
+	}
+
+	resp, err := client.Is(context.Background(), req)
+	if err != nil {
+		t.Errorf("expected request to be %s, but the call failed: %v\n%s", verb, err, describeRequest(req))
+		return false
+	}
+
+	decisions := resp.GetDecisions()
+
+	ok := len(decisions) > 0
+	for _, decision := range decisions {
+		ok = ok && decision.GetIs() == expected
+	}
+
+	if !ok {
+		t.Errorf("expected request to be %s\n%s%s", verb, describeRequest(req), describeDecisions(decisions))
+	}
+
+	return ok
+}
+
+func describeRequest(req *authz.IsRequest) string {
+	var sb strings.Builder
+
+	policy := req.GetPolicyContext()
+	fmt.Fprintf(&sb, "policy:    %s %v\n", policy.GetPath(), policy.GetDecisions())
+
+	if instance := req.GetPolicyInstance(); instance != nil {
+		fmt.Fprintf(&sb, "instance:  %s\n", instance.GetName())
+	}
+
+	identity := req.GetIdentityContext()
+	fmt.Fprintf(&sb, "identity:  %s %q\n", identity.GetType(), identity.GetIdentity())
+
+	resource := "{}"
+	if req.GetResourceContext() != nil {
+		resource = protojson.Format(req.GetResourceContext())
+	}
+
+	fmt.Fprintf(&sb, "resource:  %s\n", resource)
+
+	return sb.String()
+}
+
+func describeDecisions(decisions []*authz.Decision) string {
+	if len(decisions) == 0 {
+		return "decisions: none\n"
+	}
+
+	var sb strings.Builder
+
+	sb.WriteString("decisions:\n")
+
+	for _, decision := range decisions {
+		fmt.Fprintf(&sb, "  %s: %t\n", decision.GetDecision(), decision.GetIs())
+	}
+
+	return sb.String()
+}
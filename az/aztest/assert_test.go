@@ -0,0 +1,60 @@
+package aztest_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/az/aztest"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// recorder captures assertion failures.
+type recorder struct {
+	failures []string
+}
+
+func (*recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestAssertDecision(t *testing.T) {
+	mock := aztest.NewMock().
+		OnIs("allowed").Allow().
+		OnIs("denied").Deny().
+		OnIs("failed").Error(status.Error(codes.Unavailable, "down"))
+
+	req := func(path string) *authz.IsRequest {
+		resource, _ := structpb.NewStruct(map[string]interface{}{"id": "doc-1"})
+
+		return &authz.IsRequest{
+			IdentityContext: &api.IdentityContext{Type: api.IdentityType_IDENTITY_TYPE_SUB, Identity: "beth"},
+			PolicyContext:   &api.PolicyContext{Path: path, Decisions: []string{"allowed"}},
+			ResourceContext: resource,
+		}
+	}
+
+	r := &recorder{}
+	assert.True(t, aztest.AssertAllowed(r, mock, req("allowed")))
+	assert.True(t, aztest.AssertDenied(r, mock, req("denied")))
+	assert.Empty(t, r.failures)
+
+	assert.False(t, aztest.AssertAllowed(r, mock, req("denied")))
+	assert.Len(t, r.failures, 1)
+	assert.Contains(t, r.failures[0], "expected request to be allowed")
+	assert.Contains(t, r.failures[0], "policy:    denied [allowed]")
+	assert.Contains(t, r.failures[0], `identity:  IDENTITY_TYPE_SUB "beth"`)
+	assert.Contains(t, r.failures[0], `"doc-1"`)
+	assert.Contains(t, r.failures[0], "allowed: false")
+
+	assert.False(t, aztest.AssertDenied(r, mock, req("failed")))
+	assert.Len(t, r.failures, 2)
+	assert.Contains(t, r.failures[1], "the call failed")
+	assert.Contains(t, r.failures[1], "down")
+}
@@ -0,0 +1,206 @@
+/*
+Package aztest provides utilities for testing AuthorizerClient implementations.
+
+RunConformance verifies that a custom AuthorizerClient, such as a caching or failover wrapper, behaves like the client
+returned by az.New.
+
+Mock is a scriptable AuthorizerClient for testing code that makes authorization calls, such as handlers protected by
+the middleware. AssertAllowed and AssertDenied check the decisions returned for a request and report the full request
+and the actual decisions when they fail.
+
+Server is an in-memory gRPC authorizer server for integration tests that exercise a client's gRPC code paths.
+*/
+package aztest
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Factory creates the AuthorizerClient under test.
+//
+// backend is a client connected to an in-process reference authorizer. Implementations that wrap another client
+// must delegate to backend so the conformance suite can control the authorizer's responses.
+type Factory func(backend authz.AuthorizerClient) authz.AuthorizerClient
+
+const (
+	// blockField is a resource field that causes the reference authorizer to block until the call is canceled.
+	blockField = "block"
+
+	// codeField is a resource field that causes the reference authorizer to fail with the given gRPC code.
+	codeField = "code"
+
+	bufSize = 1024 * 1024
+)
+
+// RunConformance runs a suite of subtests that verify the AuthorizerClient created by factory behaves like the
+// standard client:
+//
+//   - Is returns the authorizer's decisions.
+//   - Errors returned by the authorizer are propagated with their gRPC status code.
+//   - Canceled contexts and expired deadlines abort calls with codes.Canceled and codes.DeadlineExceeded.
+//   - Clients are safe for concurrent use.
+//
+// Each subtest creates a new client using factory.
+func RunConformance(t *testing.T, factory Factory) {
+	t.Helper()
+
+	backend := newBackend(t)
+
+	t.Run("Is returns decisions", func(t *testing.T) {
+		client := factory(backend)
+
+		for _, allowed := range []bool{true, false} {
+			resp, err := client.Is(context.Background(), isRequest(t, map[string]interface{}{"allowed": allowed}, "allowed"))
+			require.NoError(t, err)
+			require.Len(t, resp.GetDecisions(), 1)
+			assert.Equal(t, "allowed", resp.GetDecisions()[0].GetDecision())
+			assert.Equal(t, allowed, resp.GetDecisions()[0].GetIs())
+		}
+	})
+
+	t.Run("Is returns multiple decisions", func(t *testing.T) {
+		client := factory(backend)
+
+		resp, err := client.Is(
+			context.Background(),
+			isRequest(t, map[string]interface{}{"allowed": true, "visible": false}, "allowed", "visible"),
+		)
+		require.NoError(t, err)
+		require.Len(t, resp.GetDecisions(), 2)
+
+		decisions := map[string]bool{}
+		for _, decision := range resp.GetDecisions() {
+			decisions[decision.GetDecision()] = decision.GetIs()
+		}
+
+		assert.Equal(t, map[string]bool{"allowed": true, "visible": false}, decisions)
+	})
+
+	t.Run("Is propagates errors", func(t *testing.T) {
+		client := factory(backend)
+
+		for _, code := range []codes.Code{codes.InvalidArgument, codes.NotFound, codes.PermissionDenied} {
+			_, err := client.Is(context.Background(), isRequest(t, map[string]interface{}{codeField: int(code)}, "allowed"))
+			require.Error(t, err)
+			assert.Equal(t, code, status.Code(err), "unexpected code for %s", code)
+		}
+	})
+
+	t.Run("Is aborts canceled calls", func(t *testing.T) {
+		client := factory(backend)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err := client.Is(ctx, isRequest(t, map[string]interface{}{blockField: true}, "allowed"))
+		require.Error(t, err)
+		assert.Equal(t, codes.Canceled, status.Code(err))
+	})
+
+	t.Run("Is honors deadlines", func(t *testing.T) {
+		client := factory(backend)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		_, err := client.Is(ctx, isRequest(t, map[string]interface{}{blockField: true}, "allowed"))
+		require.Error(t, err)
+		assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	})
+
+	t.Run("Is is safe for concurrent use", func(t *testing.T) {
+		client := factory(backend)
+
+		var wg sync.WaitGroup
+
+		for i := range 20 {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				allowed := i%2 == 0
+
+				resp, err := client.Is(
+					context.Background(),
+					isRequest(t, map[string]interface{}{"allowed": allowed}, "allowed"),
+				)
+				if assert.NoError(t, err) && assert.Len(t, resp.GetDecisions(), 1) {
+					assert.Equal(t, allowed, resp.GetDecisions()[0].GetIs())
+				}
+			}()
+		}
+
+		wg.Wait()
+	})
+}
+
+func isRequest(t *testing.T, resource map[string]interface{}, decisions ...string) *authz.IsRequest {
+	res, err := structpb.NewStruct(resource)
+	require.NoError(t, err)
+
+	return &authz.IsRequest{
+		IdentityContext: &api.IdentityContext{Type: api.IdentityType_IDENTITY_TYPE_SUB, Identity: "conformance"},
+		PolicyContext:   &api.PolicyContext{Path: "conformance", Decisions: decisions},
+		ResourceContext: res,
+		PolicyInstance:  &api.PolicyInstance{Name: "conformance", InstanceLabel: "conformance"},
+	}
+}
+
+// newBackend starts an in-process reference authorizer and returns a client connected to it.
+func newBackend(t *testing.T) authz.AuthorizerClient {
+	t.Helper()
+
+	lis := serve(t, &referenceAuthorizer{})
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return authz.NewAuthorizerClient(conn)
+}
+
+// referenceAuthorizer returns a decision for each requested decision name. The outcome of each decision is the value
+// of the resource field with the same name.
+type referenceAuthorizer struct {
+	authz.UnimplementedAuthorizerServer
+}
+
+func (*referenceAuthorizer) Is(ctx context.Context, req *authz.IsRequest) (*authz.IsResponse, error) {
+	fields := req.GetResourceContext().GetFields()
+
+	if code, ok := fields[codeField]; ok {
+		return nil, status.Error(codes.Code(code.GetNumberValue()), "conformance error")
+	}
+
+	if _, ok := fields[blockField]; ok {
+		<-ctx.Done()
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+
+	resp := &authz.IsResponse{}
+	for _, name := range req.GetPolicyContext().GetDecisions() {
+		resp.Decisions = append(resp.Decisions, &authz.Decision{Decision: name, Is: fields[name].GetBoolValue()})
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,39 @@
+package aztest_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/az/aztest"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"google.golang.org/grpc"
+)
+
+func TestConformance(t *testing.T) {
+	aztest.RunConformance(t, func(backend authz.AuthorizerClient) authz.AuthorizerClient {
+		return backend
+	})
+}
+
+// countingClient is an example wrapper that counts calls to Is.
+type countingClient struct {
+	authz.AuthorizerClient
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (c *countingClient) Is(ctx context.Context, in *authz.IsRequest, opts ...grpc.CallOption) (*authz.IsResponse, error) {
+	c.mu.Lock()
+	c.calls++
+	c.mu.Unlock()
+
+	return c.AuthorizerClient.Is(ctx, in, opts...)
+}
+
+func TestConformanceWrapper(t *testing.T) {
+	aztest.RunConformance(t, func(backend authz.AuthorizerClient) authz.AuthorizerClient {
+		return &countingClient{AuthorizerClient: backend}
+	})
+}
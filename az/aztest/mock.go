@@ -0,0 +1,157 @@
+package aztest
+
+import (
+	"context"
+	"sync"
+
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// Mock is a scriptable AuthorizerClient for testing code that makes authorization calls.
+//
+// Responses to Is calls are scripted per policy path using OnIs. Calls to unscripted policy paths fail with
+// codes.NotFound. All other AuthorizerClient methods fail with codes.Unimplemented.
+//
+// Mock records the requests it receives. It is safe for concurrent use.
+type Mock struct {
+	mu        sync.Mutex
+	responses map[string]response
+	requests  []*authz.IsRequest
+}
+
+var _ authz.AuthorizerClient = (*Mock)(nil)
+
+// NewMock returns a Mock with no scripted responses.
+func NewMock() *Mock {
+	return &Mock{responses: map[string]response{}}
+}
+
+// OnIs scripts the response to Is calls with the specified policy path.
+//
+//	mock := aztest.NewMock()
+//	mock.OnIs("myapp.GET.api.users").Allow()
+//	mock.OnIs("myapp.DELETE.api.users").Deny()
+func (m *Mock) OnIs(policyPath string) *MockResponse {
+	return &MockResponse{mock: m, path: policyPath}
+}
+
+// Requests returns the Is requests received by the mock, in the order they were received.
+func (m *Mock) Requests() []*authz.IsRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	requests := make([]*authz.IsRequest, len(m.requests))
+	copy(requests, m.requests)
+
+	return requests
+}
+
+// Reset clears all scripted responses and recorded requests.
+func (m *Mock) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.responses = map[string]response{}
+	m.requests = nil
+}
+
+// MockResponse scripts the response to Is calls for a policy path.
+type MockResponse struct {
+	mock *Mock
+	path string
+}
+
+// Allow makes Is calls return true for every requested decision.
+func (r *MockResponse) Allow() *Mock {
+	return r.set(response{is: true})
+}
+
+// Deny makes Is calls return false for every requested decision.
+func (r *MockResponse) Deny() *Mock {
+	return r.set(response{is: false})
+}
+
+// Error makes Is calls fail with the specified error.
+func (r *MockResponse) Error(err error) *Mock {
+	return r.set(response{err: err})
+}
+
+func (r *MockResponse) set(resp response) *Mock {
+	r.mock.mu.Lock()
+	defer r.mock.mu.Unlock()
+
+	r.mock.responses[r.path] = resp
+
+	return r.mock
+}
+
+type response struct {
+	is  bool
+	err error
+}
+
+func (m *Mock) Is(_ context.Context, in *authz.IsRequest, _ ...grpc.CallOption) (*authz.IsResponse, error) {
+	path := in.GetPolicyContext().GetPath()
+
+	m.mu.Lock()
+	m.requests = append(m.requests, proto.Clone(in).(*authz.IsRequest))
+	resp, ok := m.responses[path]
+	m.mu.Unlock()
+
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "aztest: no response scripted for policy path %q", path)
+	}
+
+	if resp.err != nil {
+		return nil, resp.err
+	}
+
+	decisions := in.GetPolicyContext().GetDecisions()
+
+	result := &authz.IsResponse{Decisions: make([]*authz.Decision, 0, len(decisions))}
+	for _, decision := range decisions {
+		result.Decisions = append(result.Decisions, &authz.Decision{Decision: decision, Is: resp.is})
+	}
+
+	return result, nil
+}
+
+func (*Mock) DecisionTree(
+	context.Context,
+	*authz.DecisionTreeRequest,
+	...grpc.CallOption,
+) (*authz.DecisionTreeResponse, error) {
+	return nil, unimplemented("DecisionTree")
+}
+
+func (*Mock) Query(context.Context, *authz.QueryRequest, ...grpc.CallOption) (*authz.QueryResponse, error) {
+	return nil, unimplemented("Query")
+}
+
+func (*Mock) Compile(context.Context, *authz.CompileRequest, ...grpc.CallOption) (*authz.CompileResponse, error) {
+	return nil, unimplemented("Compile")
+}
+
+func (*Mock) ListPolicies(
+	context.Context,
+	*authz.ListPoliciesRequest,
+	...grpc.CallOption,
+) (*authz.ListPoliciesResponse, error) {
+	return nil, unimplemented("ListPolicies")
+}
+
+func (*Mock) GetPolicy(context.Context, *authz.GetPolicyRequest, ...grpc.CallOption) (*authz.GetPolicyResponse, error) {
+	return nil, unimplemented("GetPolicy")
+}
+
+func (*Mock) Info(context.Context, *authz.InfoRequest, ...grpc.CallOption) (*authz.InfoResponse, error) {
+	return nil, unimplemented("Info")
+}
+
+func unimplemented(method string) error {
+	return status.Errorf(codes.Unimplemented, "aztest: %s is not implemented by Mock", method)
+}
@@ -0,0 +1,69 @@
+package aztest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/az/aztest"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func isRequest(path string, decisions ...string) *authz.IsRequest {
+	return &authz.IsRequest{PolicyContext: &api.PolicyContext{Path: path, Decisions: decisions}}
+}
+
+func TestMock(t *testing.T) {
+	errTest := errors.New("test error")
+
+	mock := aztest.NewMock().
+		OnIs("allowed").Allow().
+		OnIs("denied").Deny().
+		OnIs("failed").Error(errTest)
+
+	ctx := context.Background()
+
+	resp, err := mock.Is(ctx, isRequest("allowed", "allowed", "visible"))
+	require.NoError(t, err)
+	assert.Equal(t, "allowed", resp.GetDecisions()[0].GetDecision())
+	assert.True(t, resp.GetDecisions()[0].GetIs())
+	assert.Equal(t, "visible", resp.GetDecisions()[1].GetDecision())
+	assert.True(t, resp.GetDecisions()[1].GetIs())
+
+	resp, err = mock.Is(ctx, isRequest("denied", "allowed"))
+	require.NoError(t, err)
+	assert.False(t, resp.GetDecisions()[0].GetIs())
+
+	_, err = mock.Is(ctx, isRequest("failed", "allowed"))
+	assert.ErrorIs(t, err, errTest)
+
+	_, err = mock.Is(ctx, isRequest("unscripted", "allowed"))
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	requests := mock.Requests()
+	require.Len(t, requests, 4)
+	assert.Equal(t, "allowed", requests[0].GetPolicyContext().GetPath())
+	assert.Equal(t, "unscripted", requests[3].GetPolicyContext().GetPath())
+
+	mock.OnIs("allowed").Deny()
+
+	resp, err = mock.Is(ctx, isRequest("allowed", "allowed"))
+	require.NoError(t, err)
+	assert.False(t, resp.GetDecisions()[0].GetIs())
+
+	mock.Reset()
+	assert.Empty(t, mock.Requests())
+
+	_, err = mock.Is(ctx, isRequest("allowed", "allowed"))
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestMockUnimplemented(t *testing.T) {
+	_, err := aztest.NewMock().Info(context.Background(), &authz.InfoRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
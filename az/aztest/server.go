@@ -0,0 +1,137 @@
+package aztest
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// Server is an in-memory gRPC authorizer server for integration tests.
+//
+// Unlike calling a Mock directly, connecting to a Server exercises the client's gRPC code paths, such as request
+// serialization, interceptors, and metadata. Calls are handled by a backend AuthorizerClient, typically a Mock.
+type Server struct {
+	backend authz.AuthorizerClient
+	lis     *bufconn.Listener
+
+	mu       sync.Mutex
+	metadata []metadata.MD
+}
+
+// NewServer starts an in-memory authorizer server that handles calls using backend.
+// The server is stopped when the test completes.
+//
+//	mock := aztest.NewMock().OnIs("myapp.GET.api.users").Allow()
+//	server := aztest.NewServer(t, mock)
+//
+//	azClient, err := az.New(server.ConnectionOption())
+func NewServer(t *testing.T, backend authz.AuthorizerClient) *Server {
+	t.Helper()
+
+	s := &Server{backend: backend}
+	s.lis = serve(t, &serverAdapter{server: s})
+
+	return s
+}
+
+// ConnectionOption returns a connection option that connects clients to the server.
+//
+// The connection doesn't use TLS, so it can't be combined with options that require it, such as WithInsecure.
+func (s *Server) ConnectionOption() aserto.ConnectionOption {
+	return func(options *aserto.ConnectionOptions) error {
+		options.Address = "passthrough:///bufconn"
+		options.NoTLS = true
+		options.DialOptions = append(options.DialOptions, grpc.WithContextDialer(s.dial))
+
+		return nil
+	}
+}
+
+// Metadata returns the incoming metadata of the calls received by the server, in the order they were received.
+func (s *Server) Metadata() []metadata.MD {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	md := make([]metadata.MD, len(s.metadata))
+	copy(md, s.metadata)
+
+	return md
+}
+
+func (s *Server) dial(ctx context.Context, _ string) (net.Conn, error) {
+	return s.lis.DialContext(ctx)
+}
+
+func (s *Server) record(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	s.mu.Lock()
+	s.metadata = append(s.metadata, md.Copy())
+	s.mu.Unlock()
+
+	return ctx
+}
+
+// serve starts a gRPC server for srv on a bufconn listener. The server is stopped when the test completes.
+func serve(t *testing.T, srv authz.AuthorizerServer) *bufconn.Listener {
+	t.Helper()
+
+	lis := bufconn.Listen(bufSize)
+
+	server := grpc.NewServer()
+	authz.RegisterAuthorizerServer(server, srv)
+
+	go func() { _ = server.Serve(lis) }()
+
+	t.Cleanup(server.Stop)
+
+	return lis
+}
+
+// serverAdapter implements AuthorizerServer by calling the Server's backend.
+type serverAdapter struct {
+	authz.UnimplementedAuthorizerServer
+
+	server *Server
+}
+
+func (a *serverAdapter) DecisionTree(
+	ctx context.Context,
+	req *authz.DecisionTreeRequest,
+) (*authz.DecisionTreeResponse, error) {
+	return a.server.backend.DecisionTree(a.server.record(ctx), req)
+}
+
+func (a *serverAdapter) Is(ctx context.Context, req *authz.IsRequest) (*authz.IsResponse, error) {
+	return a.server.backend.Is(a.server.record(ctx), req)
+}
+
+func (a *serverAdapter) Query(ctx context.Context, req *authz.QueryRequest) (*authz.QueryResponse, error) {
+	return a.server.backend.Query(a.server.record(ctx), req)
+}
+
+func (a *serverAdapter) Compile(ctx context.Context, req *authz.CompileRequest) (*authz.CompileResponse, error) {
+	return a.server.backend.Compile(a.server.record(ctx), req)
+}
+
+func (a *serverAdapter) ListPolicies(
+	ctx context.Context,
+	req *authz.ListPoliciesRequest,
+) (*authz.ListPoliciesResponse, error) {
+	return a.server.backend.ListPolicies(a.server.record(ctx), req)
+}
+
+func (a *serverAdapter) GetPolicy(ctx context.Context, req *authz.GetPolicyRequest) (*authz.GetPolicyResponse, error) {
+	return a.server.backend.GetPolicy(a.server.record(ctx), req)
+}
+
+func (a *serverAdapter) Info(ctx context.Context, req *authz.InfoRequest) (*authz.InfoResponse, error) {
+	return a.server.backend.Info(a.server.record(ctx), req)
+}
@@ -0,0 +1,43 @@
+package aztest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto"
+	"github.com/aserto-dev/go-aserto/az"
+	"github.com/aserto-dev/go-aserto/az/aztest"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestServer(t *testing.T) {
+	mock := aztest.NewMock().OnIs("allowed").Allow()
+	server := aztest.NewServer(t, mock)
+
+	client, err := az.New(server.ConnectionOption(), aserto.WithTenantID("tenant"))
+	require.NoError(t, err)
+
+	defer client.Close()
+
+	ctx := context.Background()
+
+	aztest.AssertAllowed(t, client, isRequest("allowed", "allowed"))
+
+	_, err = client.Is(ctx, isRequest("unscripted", "allowed"))
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = client.Info(ctx, &authz.InfoRequest{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+
+	requests := mock.Requests()
+	require.Len(t, requests, 2)
+	assert.Equal(t, "allowed", requests[0].GetPolicyContext().GetPath())
+
+	md := server.Metadata()
+	require.Len(t, md, 3)
+	assert.Equal(t, []string{"tenant"}, md[0].Get("aserto-tenant-id"))
+}
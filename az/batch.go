@@ -0,0 +1,127 @@
+package az
+
+import (
+	"context"
+	"sync"
+
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/aserto-dev/go-authorizer/pkg/aerr"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// DefaultConcurrency is the maximum number of concurrent authorizer calls made by IsMany.
+const DefaultConcurrency = 10
+
+// IsManyOption functions are used to configure calls to IsMany.
+type IsManyOption func(*isManyOptions)
+
+type isManyOptions struct {
+	concurrency    int
+	policyInstance *api.PolicyInstance
+}
+
+// WithConcurrency caps the number of concurrent authorizer calls made by IsMany. Default: DefaultConcurrency.
+func WithConcurrency(n int) IsManyOption {
+	return func(o *isManyOptions) {
+		if n > 0 {
+			o.concurrency = n
+		}
+	}
+}
+
+// WithPolicyInstance sets the policy instance included in each authorization call.
+func WithPolicyInstance(instance *api.PolicyInstance) IsManyOption {
+	return func(o *isManyOptions) {
+		o.policyInstance = instance
+	}
+}
+
+// IsMany authorizes the same identity and policy against each of the given resources.
+//
+// Calls are made concurrently. The returned slice is aligned with the input resources. If any calls fail, their
+// errors are combined in the returned error and the corresponding results are false.
+// If ctx is canceled, calls that haven't started yet are skipped.
+func IsMany(
+	ctx context.Context,
+	client authz.AuthorizerClient,
+	identity *api.IdentityContext,
+	policy *api.PolicyContext,
+	resources []*structpb.Struct,
+	opts ...IsManyOption,
+) ([]bool, error) {
+	options := &isManyOptions{concurrency: DefaultConcurrency}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	results := make([]bool, len(resources))
+	errs := make([]error, len(resources))
+	sem := make(chan struct{}, options.concurrency)
+
+	var (
+		wg       sync.WaitGroup
+		canceled error
+	)
+
+dispatch:
+	for i, resource := range resources {
+		if canceled = ctx.Err(); canceled != nil {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			canceled = ctx.Err()
+			break dispatch
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			results[i], errs[i] = is(ctx, client, &authz.IsRequest{
+				IdentityContext: identity,
+				PolicyContext:   policy,
+				ResourceContext: resource,
+				PolicyInstance:  options.policyInstance,
+			})
+		}()
+	}
+
+	wg.Wait()
+
+	var combined error
+
+	for i, err := range errs {
+		if err != nil {
+			combined = multierror.Append(combined, errors.Wrapf(err, "resource %d", i))
+		}
+	}
+
+	if canceled != nil {
+		combined = multierror.Append(combined, canceled)
+	}
+
+	return results, combined
+}
+
+func is(ctx context.Context, client authz.AuthorizerClient, req *authz.IsRequest) (bool, error) {
+	resp, err := client.Is(ctx, req)
+	if err != nil {
+		return false, err
+	}
+
+	if len(resp.GetDecisions()) != 1 {
+		return false, aerr.ErrInvalidDecision
+	}
+
+	return resp.GetDecisions()[0].GetIs(), nil
+}
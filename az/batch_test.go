@@ -0,0 +1,137 @@
+package az_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/az"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/aserto-dev/go-authorizer/pkg/aerr"
+	assrt "github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// fakeAuthorizer allows resources with an "allowed" field set to true and fails resources with an "error" field.
+// Resources with an "invalid" field get a response without decisions.
+type fakeAuthorizer struct {
+	authz.AuthorizerClient
+
+	inFlight    atomic.Int32
+	maxInFlight atomic.Int32
+}
+
+func (f *fakeAuthorizer) Is(_ context.Context, in *authz.IsRequest, _ ...grpc.CallOption) (*authz.IsResponse, error) {
+	n := f.inFlight.Add(1)
+	defer f.inFlight.Add(-1)
+
+	for {
+		current := f.maxInFlight.Load()
+		if n <= current || f.maxInFlight.CompareAndSwap(current, n) {
+			break
+		}
+	}
+
+	fields := in.GetResourceContext().GetFields()
+	if _, ok := fields["error"]; ok {
+		return nil, status.Error(codes.Internal, "failed")
+	}
+
+	if _, ok := fields["invalid"]; ok {
+		return &authz.IsResponse{}, nil
+	}
+
+	return &authz.IsResponse{
+		Decisions: []*authz.Decision{{Decision: "allowed", Is: fields["allowed"].GetBoolValue()}},
+	}, nil
+}
+
+func resources(t *testing.T, values ...map[string]interface{}) []*structpb.Struct {
+	result := make([]*structpb.Struct, len(values))
+
+	for i, v := range values {
+		s, err := structpb.NewStruct(v)
+		assrt.NoError(t, err)
+
+		result[i] = s
+	}
+
+	return result
+}
+
+var (
+	identity = &api.IdentityContext{Type: api.IdentityType_IDENTITY_TYPE_SUB, Identity: "alice"}
+	policy   = &api.PolicyContext{Path: "documents.read", Decisions: []string{"allowed"}}
+)
+
+func TestIsMany(t *testing.T) {
+	assert := assrt.New(t)
+
+	res := resources(t,
+		map[string]interface{}{"allowed": true},
+		map[string]interface{}{"allowed": false},
+		map[string]interface{}{"allowed": true},
+	)
+
+	results, err := az.IsMany(context.Background(), &fakeAuthorizer{}, identity, policy, res)
+	assert.NoError(err)
+	assert.Equal([]bool{true, false, true}, results)
+}
+
+func TestIsManyErrors(t *testing.T) {
+	assert := assrt.New(t)
+
+	res := resources(t,
+		map[string]interface{}{"allowed": true},
+		map[string]interface{}{"error": true},
+	)
+
+	results, err := az.IsMany(context.Background(), &fakeAuthorizer{}, identity, policy, res)
+	assert.Error(err)
+	assert.Equal(codes.Internal, status.Code(err))
+	assert.Equal([]bool{true, false}, results)
+}
+
+func TestIsManyInvalidDecision(t *testing.T) {
+	assert := assrt.New(t)
+
+	res := resources(t,
+		map[string]interface{}{"allowed": true},
+		map[string]interface{}{"invalid": true},
+	)
+
+	results, err := az.IsMany(context.Background(), &fakeAuthorizer{}, identity, policy, res)
+	assert.ErrorIs(err, aerr.ErrInvalidDecision)
+	assert.Equal([]bool{true, false}, results)
+}
+
+func TestIsManyConcurrency(t *testing.T) {
+	assert := assrt.New(t)
+
+	values := make([]map[string]interface{}, 50)
+	for i := range values {
+		values[i] = map[string]interface{}{"allowed": true}
+	}
+
+	client := &fakeAuthorizer{}
+
+	results, err := az.IsMany(context.Background(), client, identity, policy, resources(t, values...), az.WithConcurrency(3))
+	assert.NoError(err)
+	assert.Len(results, 50)
+	assert.LessOrEqual(client.maxInFlight.Load(), int32(3))
+}
+
+func TestIsManyCanceled(t *testing.T) {
+	assert := assrt.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results, err := az.IsMany(ctx, &fakeAuthorizer{}, identity, policy, resources(t, map[string]interface{}{"allowed": true}))
+	assert.ErrorIs(err, context.Canceled)
+	assert.Equal([]bool{false}, results)
+}
@@ -0,0 +1,233 @@
+package az
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+// ErrBreakerOpen is returned by calls that are short-circuited by an open circuit breaker.
+// Its code is codes.Unavailable, so middleware treat it like an unreachable authorizer.
+var ErrBreakerOpen = status.Error(codes.Unavailable, "authorizer circuit breaker is open")
+
+// BreakerState is the state of a circuit breaker.
+type BreakerState int
+
+const (
+	// BreakerClosed lets calls through. This is the initial state.
+	BreakerClosed BreakerState = iota
+
+	// BreakerOpen fails calls immediately with ErrBreakerOpen.
+	BreakerOpen
+
+	// BreakerHalfOpen lets a single probe call through to test whether the authorizer has recovered.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// BreakerOptions configure a circuit breaker.
+//
+// Zero values are replaced with defaults: DefaultBreakerThreshold, DefaultBreakerCooldown, and a predicate that
+// counts calls that fail with codes.Unavailable, codes.DeadlineExceeded, or codes.ResourceExhausted as failures.
+type BreakerOptions struct {
+	// Threshold is the number of consecutive failed calls that trip the breaker open.
+	Threshold int
+
+	// Cooldown is how long the breaker stays open before it lets a probe call through.
+	Cooldown time.Duration
+
+	// IsFailure reports whether an error returned by the authorizer counts as a failure.
+	IsFailure func(error) bool
+
+	// OnStateChange, if set, is called when the breaker changes state, for example to record metrics.
+	// It is called synchronously and must not call the breaker.
+	OnStateChange func(from, to BreakerState)
+}
+
+// Breaker is an AuthorizerClient that stops calling the authorizer while it is failing.
+//
+// After Threshold consecutive failures, the breaker opens and Is calls fail immediately with ErrBreakerOpen instead
+// of adding load to the struggling authorizer. Once Cooldown elapses, the breaker is half-open and lets one call
+// through. If it succeeds, the breaker closes. Otherwise, it opens again.
+//
+// Only Is calls go through the breaker. Other calls are passed to the wrapped client.
+type Breaker struct {
+	authz.AuthorizerClient
+
+	opts BreakerOptions
+
+	mu         sync.Mutex
+	state      BreakerState
+	failures   int
+	openedAt   time.Time
+	probing    bool
+	generation uint64
+}
+
+// admission records how a call was let through the breaker, so that its outcome is only applied to the state it
+// was admitted in.
+type admission struct {
+	// generation is the number of times the breaker had opened when the call was admitted.
+	generation uint64
+
+	// probe is true if the call is the half-open breaker's probe.
+	probe bool
+}
+
+// NewBreaker wraps an AuthorizerClient with a circuit breaker.
+func NewBreaker(client authz.AuthorizerClient, opts BreakerOptions) *Breaker {
+	if opts.Threshold <= 0 {
+		opts.Threshold = DefaultBreakerThreshold
+	}
+
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = DefaultBreakerCooldown
+	}
+
+	if opts.IsFailure == nil {
+		opts.IsFailure = isTransientError
+	}
+
+	return &Breaker{AuthorizerClient: client, opts: opts}
+}
+
+// State returns the breaker's current state.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == BreakerOpen && time.Now().Sub(b.openedAt) >= b.opts.Cooldown {
+		return BreakerHalfOpen
+	}
+
+	return b.state
+}
+
+// Is makes an authorization call unless the breaker is open.
+func (b *Breaker) Is(ctx context.Context, in *authz.IsRequest, opts ...grpc.CallOption) (*authz.IsResponse, error) {
+	adm, ok := b.allow()
+	if !ok {
+		return nil, ErrBreakerOpen
+	}
+
+	resp, err := b.AuthorizerClient.Is(ctx, in, opts...)
+	b.record(adm, err)
+
+	return resp, err
+}
+
+// allow returns true if a call can be made, along with the admission to pass to record once it completes.
+func (b *Breaker) allow() (admission, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerClosed:
+		return admission{generation: b.generation}, true
+	case BreakerOpen:
+		if time.Now().Sub(b.openedAt) < b.opts.Cooldown {
+			return admission{}, false
+		}
+
+		b.transition(BreakerHalfOpen)
+		b.probing = true
+
+		return admission{generation: b.generation, probe: true}, true
+	case BreakerHalfOpen:
+		if b.probing {
+			return admission{}, false
+		}
+
+		b.probing = true
+
+		return admission{generation: b.generation, probe: true}, true
+	default:
+		return admission{}, false
+	}
+}
+
+// record updates the breaker with the outcome of a call. Only the probe changes the state of a half-open breaker,
+// and calls admitted before the breaker last opened are ignored.
+func (b *Breaker) record(adm admission, err error) {
+	failed := err != nil && b.opts.IsFailure(err)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if adm.probe {
+		b.probing = false
+
+		if failed {
+			b.open()
+		} else {
+			b.failures = 0
+			b.transition(BreakerClosed)
+		}
+
+		return
+	}
+
+	if adm.generation != b.generation {
+		return
+	}
+
+	if !failed {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.opts.Threshold {
+		b.open()
+	}
+}
+
+func (b *Breaker) open() {
+	b.generation++
+	b.openedAt = time.Now()
+	b.transition(BreakerOpen)
+}
+
+func (b *Breaker) transition(to BreakerState) {
+	from := b.state
+	if from == to {
+		return
+	}
+
+	b.state = to
+
+	if b.opts.OnStateChange != nil {
+		b.opts.OnStateChange(from, to)
+	}
+}
+
+func isTransientError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
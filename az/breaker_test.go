@@ -0,0 +1,153 @@
+package az_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aserto-dev/go-aserto/az"
+	"github.com/aserto-dev/go-aserto/az/aztest"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	assrt "github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBreaker(t *testing.T) {
+	assert := assrt.New(t)
+
+	var transitions []string
+
+	upstream := &transportAuthorizer{err: status.Error(codes.Unavailable, "down")}
+	breaker := az.NewBreaker(upstream, az.BreakerOptions{
+		Threshold: 2,
+		Cooldown:  50 * time.Millisecond,
+		OnStateChange: func(from, to az.BreakerState) {
+			transitions = append(transitions, from.String()+"->"+to.String())
+		},
+	})
+
+	ctx := context.Background()
+
+	for range 2 {
+		_, err := breaker.Is(ctx, &authz.IsRequest{})
+		assert.Equal(codes.Unavailable, status.Code(err))
+	}
+
+	assert.Equal(az.BreakerOpen, breaker.State())
+
+	_, err := breaker.Is(ctx, &authz.IsRequest{})
+	assert.ErrorIs(err, az.ErrBreakerOpen)
+	assert.Equal(2, upstream.calls, "open breaker shouldn't call the authorizer")
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(az.BreakerHalfOpen, breaker.State())
+
+	// The probe fails and the breaker opens again.
+	_, err = breaker.Is(ctx, &authz.IsRequest{})
+	assert.NotErrorIs(err, az.ErrBreakerOpen)
+	assert.Equal(3, upstream.calls)
+	assert.Equal(az.BreakerOpen, breaker.State())
+
+	time.Sleep(60 * time.Millisecond)
+
+	// The probe succeeds and the breaker closes.
+	upstream.err = nil
+	resp, err := breaker.Is(ctx, &authz.IsRequest{})
+	assert.NoError(err)
+	assert.True(resp.GetDecisions()[0].GetIs())
+	assert.Equal(az.BreakerClosed, breaker.State())
+
+	assert.Equal([]string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}, transitions)
+}
+
+// gatedAuthorizer blocks each Is call until the test sends its result on the channel received from calls.
+type gatedAuthorizer struct {
+	authz.AuthorizerClient
+
+	calls chan chan error
+}
+
+func (g *gatedAuthorizer) Is(_ context.Context, _ *authz.IsRequest, _ ...grpc.CallOption) (*authz.IsResponse, error) {
+	result := make(chan error)
+	g.calls <- result
+
+	if err := <-result; err != nil {
+		return nil, err
+	}
+
+	return &authz.IsResponse{Decisions: []*authz.Decision{{Decision: "allowed", Is: true}}}, nil
+}
+
+func TestBreakerStaleCallsDontProbe(t *testing.T) {
+	assert := assrt.New(t)
+
+	upstream := &gatedAuthorizer{calls: make(chan chan error)}
+	breaker := az.NewBreaker(upstream, az.BreakerOptions{Threshold: 1, Cooldown: 20 * time.Millisecond})
+
+	call := func() <-chan error {
+		done := make(chan error, 1)
+
+		go func() {
+			_, err := breaker.Is(context.Background(), &authz.IsRequest{})
+			done <- err
+		}()
+
+		return done
+	}
+
+	// A slow call is admitted while the breaker is closed.
+	slowDone := call()
+	slow := <-upstream.calls
+
+	// Another call fails and trips the breaker.
+	tripDone := call()
+	(<-upstream.calls) <- status.Error(codes.Unavailable, "down")
+	assert.Equal(codes.Unavailable, status.Code(<-tripDone))
+	assert.Equal(az.BreakerOpen, breaker.State())
+
+	time.Sleep(30 * time.Millisecond)
+
+	// The probe is admitted once the cooldown elapses.
+	probeDone := call()
+	probe := <-upstream.calls
+
+	// The slow call succeeds, but it isn't the probe, so the breaker stays half-open.
+	slow <- nil
+	assert.NoError(<-slowDone)
+	assert.Equal(az.BreakerHalfOpen, breaker.State())
+
+	select {
+	case err := <-call():
+		assert.ErrorIs(err, az.ErrBreakerOpen)
+	case <-upstream.calls:
+		t.Fatal("only one probe should be in flight")
+	}
+
+	// The probe fails and the breaker opens again.
+	probe <- status.Error(codes.Unavailable, "down")
+	assert.Equal(codes.Unavailable, status.Code(<-probeDone))
+	assert.Equal(az.BreakerOpen, breaker.State())
+}
+
+func TestBreakerIgnoresNonTransientErrors(t *testing.T) {
+	assert := assrt.New(t)
+
+	upstream := &transportAuthorizer{err: status.Error(codes.InvalidArgument, "bad")}
+	breaker := az.NewBreaker(upstream, az.BreakerOptions{Threshold: 1})
+
+	for range 3 {
+		_, err := breaker.Is(context.Background(), &authz.IsRequest{})
+		assert.Equal(codes.InvalidArgument, status.Code(err))
+	}
+
+	assert.Equal(az.BreakerClosed, breaker.State())
+	assert.Equal(3, upstream.calls)
+}
+
+func TestBreakerConformance(t *testing.T) {
+	aztest.RunConformance(t, func(backend authz.AuthorizerClient) authz.AuthorizerClient {
+		return az.NewBreaker(backend, az.BreakerOptions{})
+	})
+}
@@ -0,0 +1,226 @@
+package az
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// DefaultUnknowns are the references treated as unknown by Compile if no unknowns are specified.
+var DefaultUnknowns = []string{"input.resource"}
+
+var ErrInvalidCompileResult = errors.New("authorizer returned an invalid compile result")
+
+// CompileOption functions are used to configure calls to Compile.
+type CompileOption func(*authz.CompileRequest)
+
+// WithUnknowns sets the references that are treated as unknown during partial evaluation.
+// Default: DefaultUnknowns.
+func WithUnknowns(unknowns ...string) CompileOption {
+	return func(req *authz.CompileRequest) {
+		req.Unknowns = unknowns
+	}
+}
+
+// WithDisableInlining prevents the given rules from being inlined in the partially evaluated queries.
+func WithDisableInlining(refs ...string) CompileOption {
+	return func(req *authz.CompileRequest) {
+		req.DisableInlining = refs
+	}
+}
+
+// PartialResult is the result of partially evaluating a query.
+//
+// Queries is a disjunction: the original query is true if any of the queries is true. Each query is a conjunction
+// of expressions that must all be true. If there are no queries, the original query is never true. A query with
+// no expressions is always true.
+type PartialResult struct {
+	Queries [][]*Expression
+}
+
+// Expression is a single expression in a partially evaluated query. For example, the expression
+// `input.resource.owner == "alice"` has the operator "eq" and two operands.
+//
+// Expressions that consist of a single term, like `input.resource.public`, have no operator and a single operand.
+type Expression struct {
+	Negated  bool
+	Operator string
+	Operands []*Term
+}
+
+// Term is an operand of an expression.
+type Term struct {
+	// Type is the Rego type of the term: "ref", "var", "string", "number", "boolean", "null", "array", "set",
+	// "object", or "call".
+	Type string
+
+	// Ref is the path of ref terms. For example, ["input", "resource", "owner"].
+	Ref []string
+
+	// Value is the value of all other terms. For scalar terms, it is a string, float64, bool, or nil.
+	Value interface{}
+}
+
+// String returns the dot-separated path of ref terms and the value of all other terms.
+func (t *Term) String() string {
+	if t.Type == "ref" {
+		return strings.Join(t.Ref, ".")
+	}
+
+	return fmt.Sprint(t.Value)
+}
+
+// Compile partially evaluates a query against the policy instance and returns the resulting queries.
+//
+// Partial evaluation treats some input values as unknown (by default, input.resource) and returns the conditions
+// they must satisfy for the query to be true. This is typically used to translate authorization policies into
+// database filters.
+// If instance is nil, the authorizer's default policy is used.
+func Compile(
+	ctx context.Context,
+	client authz.AuthorizerClient,
+	instance *api.PolicyInstance,
+	query string,
+	input map[string]interface{},
+	opts ...CompileOption,
+) (*PartialResult, error) {
+	req := &authz.CompileRequest{
+		Query:          query,
+		Unknowns:       DefaultUnknowns,
+		PolicyInstance: instance,
+	}
+
+	for _, opt := range opts {
+		opt(req)
+	}
+
+	if input != nil {
+		b, err := json.Marshal(input)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid compile input")
+		}
+
+		req.Input = string(b)
+	}
+
+	resp, err := client.Compile(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result struct {
+		Queries [][]expressionJSON `json:"queries"`
+	}
+
+	b, err := protojson.Marshal(resp.GetResult())
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid compile response")
+	}
+
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, errors.Wrap(ErrInvalidCompileResult, err.Error())
+	}
+
+	partial := &PartialResult{Queries: make([][]*Expression, len(result.Queries))}
+
+	for i, query := range result.Queries {
+		partial.Queries[i] = make([]*Expression, len(query))
+
+		for j, expr := range query {
+			if partial.Queries[i][j], err = expr.normalize(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return partial, nil
+}
+
+type termJSON struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+type expressionJSON struct {
+	Negated bool            `json:"negated"`
+	Terms   json.RawMessage `json:"terms"`
+}
+
+func (e *expressionJSON) normalize() (*Expression, error) {
+	expr := &Expression{Negated: e.Negated}
+
+	// Call expressions have an array of terms where the first term is the operator. Other expressions have a single
+	// term.
+	var terms []termJSON
+	if err := json.Unmarshal(e.Terms, &terms); err != nil {
+		var term termJSON
+		if err := json.Unmarshal(e.Terms, &term); err != nil {
+			return nil, errors.Wrap(ErrInvalidCompileResult, err.Error())
+		}
+
+		operand, err := term.normalize()
+		if err != nil {
+			return nil, err
+		}
+
+		expr.Operands = []*Term{operand}
+
+		return expr, nil
+	}
+
+	if len(terms) == 0 {
+		return nil, errors.Wrap(ErrInvalidCompileResult, "empty expression")
+	}
+
+	operator, err := terms[0].normalize()
+	if err != nil {
+		return nil, err
+	}
+
+	expr.Operator = operator.String()
+
+	for _, term := range terms[1:] {
+		operand, err := term.normalize()
+		if err != nil {
+			return nil, err
+		}
+
+		expr.Operands = append(expr.Operands, operand)
+	}
+
+	return expr, nil
+}
+
+func (t *termJSON) normalize() (*Term, error) {
+	term := &Term{Type: t.Type}
+
+	if t.Type != "ref" {
+		if err := json.Unmarshal(t.Value, &term.Value); err != nil {
+			return nil, errors.Wrap(ErrInvalidCompileResult, err.Error())
+		}
+
+		return term, nil
+	}
+
+	var path []termJSON
+	if err := json.Unmarshal(t.Value, &path); err != nil {
+		return nil, errors.Wrap(ErrInvalidCompileResult, err.Error())
+	}
+
+	for _, elem := range path {
+		var value interface{}
+		if err := json.Unmarshal(elem.Value, &value); err != nil {
+			return nil, errors.Wrap(ErrInvalidCompileResult, err.Error())
+		}
+
+		term.Ref = append(term.Ref, fmt.Sprint(value))
+	}
+
+	return term, nil
+}
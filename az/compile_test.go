@@ -0,0 +1,182 @@
+package az_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/az"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	assrt "github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func ref(path ...string) map[string]interface{} {
+	elems := []interface{}{map[string]interface{}{"type": "var", "value": path[0]}}
+	for _, p := range path[1:] {
+		elems = append(elems, map[string]interface{}{"type": "string", "value": p})
+	}
+
+	return map[string]interface{}{"type": "ref", "value": elems}
+}
+
+func call(op string, operands ...interface{}) map[string]interface{} {
+	return map[string]interface{}{"terms": append([]interface{}{ref(op)}, operands...)}
+}
+
+// compileAuthorizer returns the partial evaluation of:
+//
+//	allowed { input.resource.owner == input.user }
+//	allowed { input.resource.public; not input.resource.archived }
+type compileAuthorizer struct {
+	authz.AuthorizerClient
+
+	req *authz.CompileRequest
+}
+
+func (f *compileAuthorizer) Compile(
+	_ context.Context,
+	in *authz.CompileRequest,
+	_ ...grpc.CallOption,
+) (*authz.CompileResponse, error) {
+	f.req = in
+
+	result, err := structpb.NewStruct(map[string]interface{}{
+		"queries": []interface{}{
+			[]interface{}{
+				call("eq", ref("input", "resource", "owner"), map[string]interface{}{"type": "string", "value": "alice"}),
+			},
+			[]interface{}{
+				map[string]interface{}{"terms": ref("input", "resource", "public")},
+				map[string]interface{}{"negated": true, "terms": ref("input", "resource", "archived")},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &authz.CompileResponse{Result: result}, nil
+}
+
+func TestCompile(t *testing.T) {
+	assert := assrt.New(t)
+
+	client := &compileAuthorizer{}
+	instance := &api.PolicyInstance{Name: "docs", InstanceLabel: "docs"}
+
+	result, err := az.Compile(context.Background(), client, instance, "data.docs.allowed == true",
+		map[string]interface{}{"user": "alice"},
+	)
+	assert.NoError(err)
+
+	assert.Equal("data.docs.allowed == true", client.req.GetQuery())
+	assert.Equal(az.DefaultUnknowns, client.req.GetUnknowns())
+	assert.Equal(instance, client.req.GetPolicyInstance())
+	assert.JSONEq(`{"user": "alice"}`, client.req.GetInput())
+
+	assert.Equal(&az.PartialResult{
+		Queries: [][]*az.Expression{
+			{
+				{
+					Operator: "eq",
+					Operands: []*az.Term{
+						{Type: "ref", Ref: []string{"input", "resource", "owner"}},
+						{Type: "string", Value: "alice"},
+					},
+				},
+			},
+			{
+				{Operands: []*az.Term{{Type: "ref", Ref: []string{"input", "resource", "public"}}}},
+				{Negated: true, Operands: []*az.Term{{Type: "ref", Ref: []string{"input", "resource", "archived"}}}},
+			},
+		},
+	}, result)
+}
+
+func TestCompileOptions(t *testing.T) {
+	assert := assrt.New(t)
+
+	client := &compileAuthorizer{}
+
+	_, err := az.Compile(context.Background(), client, nil, "data.docs.allowed == true", nil,
+		az.WithUnknowns("input.document"),
+		az.WithDisableInlining("data.docs.owner"),
+	)
+	assert.NoError(err)
+
+	assert.Equal([]string{"input.document"}, client.req.GetUnknowns())
+	assert.Equal([]string{"data.docs.owner"}, client.req.GetDisableInlining())
+	assert.Empty(client.req.GetInput())
+}
+
+var sqlOperators = map[string]string{"eq": "=", "neq": "<>", "lt": "<", "lte": "<=", "gt": ">", "gte": ">="}
+
+// sqlOperand maps references to input.resource to columns and other terms to literals.
+func sqlOperand(term *az.Term) string {
+	if term.Type == "ref" {
+		return strings.Join(term.Ref[2:], ".")
+	}
+
+	if s, ok := term.Value.(string); ok {
+		return fmt.Sprintf("'%s'", strings.ReplaceAll(s, "'", "''"))
+	}
+
+	return fmt.Sprint(term.Value)
+}
+
+// toSQL converts a partial result to a WHERE clause. It only supports comparisons and boolean columns.
+func toSQL(result *az.PartialResult) string {
+	if len(result.Queries) == 0 {
+		return "FALSE"
+	}
+
+	disjuncts := make([]string, len(result.Queries))
+
+	for i, query := range result.Queries {
+		conjuncts := []string{"TRUE"}
+
+		for _, expr := range query {
+			var cond string
+			if expr.Operator == "" {
+				cond = sqlOperand(expr.Operands[0])
+			} else {
+				cond = fmt.Sprintf("%s %s %s",
+					sqlOperand(expr.Operands[0]), sqlOperators[expr.Operator], sqlOperand(expr.Operands[1]),
+				)
+			}
+
+			if expr.Negated {
+				cond = "NOT " + cond
+			}
+
+			conjuncts = append(conjuncts, cond)
+		}
+
+		if len(conjuncts) > 1 {
+			conjuncts = conjuncts[1:]
+		}
+
+		disjuncts[i] = "(" + strings.Join(conjuncts, " AND ") + ")"
+	}
+
+	return strings.Join(disjuncts, " OR ")
+}
+
+func ExampleCompile() {
+	var client authz.AuthorizerClient = &compileAuthorizer{}
+
+	result, err := az.Compile(context.Background(), client, nil, "data.docs.allowed == true",
+		map[string]interface{}{"user": "alice"},
+		az.WithUnknowns("input.resource"),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println("SELECT * FROM documents WHERE " + toSQL(result))
+	// Output: SELECT * FROM documents WHERE (owner = 'alice') OR (public AND NOT archived)
+}
@@ -0,0 +1,40 @@
+package az
+
+import (
+	"context"
+
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/connectivity"
+)
+
+// Ping calls the authorizer's Info endpoint and returns an error if the authorizer can't be reached.
+//
+// Ping can be used on startup to fail fast if the authorizer is unavailable.
+func (c *Client) Ping(ctx context.Context) error {
+	if _, err := c.Info(ctx, &authz.InfoRequest{}); err != nil {
+		return errors.Wrap(err, "authorizer ping failed")
+	}
+
+	return nil
+}
+
+// WaitForReady blocks until the underlying connection is ready or ctx is done, in which case the context's error
+// is returned.
+//
+// gRPC connections are established lazily. WaitForReady initiates the connection if it is idle, which makes it
+// suitable for gating readiness probes.
+func (c *Client) WaitForReady(ctx context.Context) error {
+	c.conn.Connect()
+
+	for {
+		state := c.conn.GetState()
+		if state == connectivity.Ready {
+			return nil
+		}
+
+		if !c.conn.WaitForStateChange(ctx, state) {
+			return errors.Wrapf(ctx.Err(), "authorizer connection not ready (%s)", state)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package az_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aserto-dev/go-aserto"
+	"github.com/aserto-dev/go-aserto/az"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	assrt "github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type infoAuthorizer struct {
+	authz.AuthorizerClient
+
+	err error
+}
+
+func (f *infoAuthorizer) Info(_ context.Context, _ *authz.InfoRequest, _ ...grpc.CallOption) (*authz.InfoResponse, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return &authz.InfoResponse{}, nil
+}
+
+func TestPing(t *testing.T) {
+	assert := assrt.New(t)
+
+	client := &az.Client{AuthorizerClient: &infoAuthorizer{}}
+	assert.NoError(client.Ping(context.Background()))
+
+	client = &az.Client{AuthorizerClient: &infoAuthorizer{err: status.Error(codes.Unavailable, "unreachable")}}
+	err := client.Ping(context.Background())
+	assert.Error(err)
+	assert.Equal(codes.Unavailable, status.Code(err))
+}
+
+func TestWaitForReady(t *testing.T) {
+	assert := assrt.New(t)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(err)
+
+	server := grpc.NewServer()
+	defer server.Stop()
+
+	go func() { _ = server.Serve(lis) }()
+
+	client, err := az.New(aserto.WithAddr(lis.Addr().String()), aserto.WithNoTLS(true))
+	assert.NoError(err)
+
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	assert.NoError(client.WaitForReady(ctx))
+}
+
+func TestWaitForReadyTimeout(t *testing.T) {
+	assert := assrt.New(t)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(err)
+
+	addr := lis.Addr().String()
+	assert.NoError(lis.Close())
+
+	client, err := az.New(aserto.WithAddr(addr), aserto.WithNoTLS(true))
+	assert.NoError(err)
+
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	assert.ErrorIs(client.WaitForReady(ctx), context.DeadlineExceeded)
+}
+
+func TestWaitForReadyCallOption(t *testing.T) {
+	assert := assrt.New(t)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(err)
+
+	addr := lis.Addr().String()
+	assert.NoError(lis.Close())
+
+	client, err := az.New(aserto.WithAddr(addr), aserto.WithNoTLS(true), aserto.WithWaitForReady())
+	assert.NoError(err)
+
+	defer client.Close()
+
+	server := grpc.NewServer()
+	defer server.Stop()
+
+	// Start the server after the call is made. The call waits for it instead of failing with Unavailable.
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+
+		if lis, err := net.Listen("tcp", addr); err == nil {
+			_ = server.Serve(lis)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// The server doesn't implement the authorizer service.
+	_, err = client.Info(ctx, &authz.InfoRequest{})
+	assert.Equal(codes.Unimplemented, status.Code(err))
+}
@@ -0,0 +1,115 @@
+package az_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto"
+	"github.com/aserto-dev/go-aserto/az"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	assrt "github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+)
+
+// metadataServer starts a gRPC server that reports the metadata of incoming calls.
+func metadataServer(t *testing.T) (string, <-chan metadata.MD) {
+	t.Helper()
+
+	calls := make(chan metadata.MD, 1)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assrt.NoError(t, err)
+
+	server := grpc.NewServer(grpc.UnknownServiceHandler(func(_ interface{}, stream grpc.ServerStream) error {
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		calls <- md
+
+		return status.Error(codes.Unimplemented, "not implemented")
+	}))
+	t.Cleanup(server.Stop)
+
+	go func() { _ = server.Serve(lis) }()
+
+	return lis.Addr().String(), calls
+}
+
+func TestWithTenant(t *testing.T) {
+	assert := assrt.New(t)
+
+	addr, calls := metadataServer(t)
+
+	client, err := az.New(aserto.WithAddr(addr), aserto.WithNoTLS(true), aserto.WithTenantID("default"))
+	assert.NoError(err)
+
+	defer client.Close()
+
+	_, _ = client.Is(context.Background(), &authz.IsRequest{})
+	assert.Equal([]string{"default"}, (<-calls).Get("aserto-tenant-id"))
+
+	_, _ = client.Is(az.WithTenant(context.Background(), "other"), &authz.IsRequest{})
+	assert.Equal([]string{"other"}, (<-calls).Get("aserto-tenant-id"))
+}
+
+func TestUserAgent(t *testing.T) {
+	assert := assrt.New(t)
+
+	for _, tc := range []struct {
+		opts     []aserto.ConnectionOption
+		expected string
+	}{
+		{nil, aserto.UserAgent()},
+		{[]aserto.ConnectionOption{aserto.WithUserAgent("my-service/1.0")}, "my-service/1.0"},
+	} {
+		addr, calls := metadataServer(t)
+
+		client, err := az.New(append(tc.opts, aserto.WithAddr(addr), aserto.WithNoTLS(true))...)
+		assert.NoError(err)
+
+		_, _ = client.Is(context.Background(), &authz.IsRequest{})
+		assert.True(strings.HasPrefix((<-calls).Get("user-agent")[0], tc.expected+" grpc-go/"))
+
+		assert.NoError(client.Close())
+	}
+}
+
+// countingStatsHandler counts the RPCs it's notified about.
+type countingStatsHandler struct {
+	rpcs atomic.Int32
+}
+
+func (h *countingStatsHandler) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	h.rpcs.Add(1)
+	return ctx
+}
+
+func (*countingStatsHandler) HandleRPC(context.Context, stats.RPCStats) {}
+
+func (*countingStatsHandler) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (*countingStatsHandler) HandleConn(context.Context, stats.ConnStats) {}
+
+func TestStatsHandler(t *testing.T) {
+	assert := assrt.New(t)
+
+	addr, calls := metadataServer(t)
+	handler := &countingStatsHandler{}
+
+	client, err := az.New(aserto.WithAddr(addr), aserto.WithNoTLS(true), aserto.WithStatsHandler(handler))
+	assert.NoError(err)
+
+	defer client.Close()
+
+	_, _ = client.Is(context.Background(), &authz.IsRequest{})
+	<-calls
+
+	assert.Equal(int32(1), handler.rpcs.Load())
+}
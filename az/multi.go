@@ -0,0 +1,91 @@
+package az
+
+import (
+	"context"
+
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// NewMultiTransport returns an AuthorizerClient that makes calls using grpcClient and retries them using httpClient
+// if they fail at the transport level (i.e. with codes.Unavailable), for example because a firewall blocks gRPC
+// traffic. Other errors, including authorization and validation errors, are returned as is.
+//
+// The first client is always attempted first. To prefer HTTP and fall back to gRPC, swap the arguments.
+func NewMultiTransport(grpcClient, httpClient authz.AuthorizerClient) authz.AuthorizerClient {
+	return &multiTransport{preferred: grpcClient, fallback: httpClient}
+}
+
+type multiTransport struct {
+	preferred authz.AuthorizerClient
+	fallback  authz.AuthorizerClient
+}
+
+var _ authz.AuthorizerClient = (*multiTransport)(nil)
+
+func (c *multiTransport) DecisionTree(
+	ctx context.Context,
+	in *authz.DecisionTreeRequest,
+	opts ...grpc.CallOption,
+) (*authz.DecisionTreeResponse, error) {
+	return withFallback(ctx, in, opts, c.preferred.DecisionTree, c.fallback.DecisionTree)
+}
+
+func (c *multiTransport) Is(ctx context.Context, in *authz.IsRequest, opts ...grpc.CallOption) (*authz.IsResponse, error) {
+	return withFallback(ctx, in, opts, c.preferred.Is, c.fallback.Is)
+}
+
+func (c *multiTransport) Query(
+	ctx context.Context,
+	in *authz.QueryRequest,
+	opts ...grpc.CallOption,
+) (*authz.QueryResponse, error) {
+	return withFallback(ctx, in, opts, c.preferred.Query, c.fallback.Query)
+}
+
+func (c *multiTransport) Compile(
+	ctx context.Context,
+	in *authz.CompileRequest,
+	opts ...grpc.CallOption,
+) (*authz.CompileResponse, error) {
+	return withFallback(ctx, in, opts, c.preferred.Compile, c.fallback.Compile)
+}
+
+func (c *multiTransport) ListPolicies(
+	ctx context.Context,
+	in *authz.ListPoliciesRequest,
+	opts ...grpc.CallOption,
+) (*authz.ListPoliciesResponse, error) {
+	return withFallback(ctx, in, opts, c.preferred.ListPolicies, c.fallback.ListPolicies)
+}
+
+func (c *multiTransport) GetPolicy(
+	ctx context.Context,
+	in *authz.GetPolicyRequest,
+	opts ...grpc.CallOption,
+) (*authz.GetPolicyResponse, error) {
+	return withFallback(ctx, in, opts, c.preferred.GetPolicy, c.fallback.GetPolicy)
+}
+
+func (c *multiTransport) Info(ctx context.Context, in *authz.InfoRequest, opts ...grpc.CallOption) (*authz.InfoResponse, error) {
+	return withFallback(ctx, in, opts, c.preferred.Info, c.fallback.Info)
+}
+
+type call[Req, Resp any] func(context.Context, Req, ...grpc.CallOption) (Resp, error)
+
+// withFallback makes a call using preferred and retries it using fallback if it fails with codes.Unavailable.
+func withFallback[Req, Resp any](
+	ctx context.Context,
+	in Req,
+	opts []grpc.CallOption,
+	preferred, fallback call[Req, Resp],
+) (Resp, error) {
+	resp, err := preferred(ctx, in, opts...)
+	if status.Code(err) != codes.Unavailable || ctx.Err() != nil {
+		return resp, err
+	}
+
+	return fallback(ctx, in, opts...)
+}
@@ -0,0 +1,60 @@
+package az_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/az"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	assrt "github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type transportAuthorizer struct {
+	authz.AuthorizerClient
+
+	err   error
+	calls int
+}
+
+func (f *transportAuthorizer) Is(_ context.Context, _ *authz.IsRequest, _ ...grpc.CallOption) (*authz.IsResponse, error) {
+	f.calls++
+
+	if f.err != nil {
+		return nil, f.err
+	}
+
+	return &authz.IsResponse{Decisions: []*authz.Decision{{Decision: "allowed", Is: true}}}, nil
+}
+
+func TestMultiTransport(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err           error
+		fallbackCalls int
+		code          codes.Code
+	}{
+		"preferred succeeds":        {},
+		"unavailable falls back":    {err: status.Error(codes.Unavailable, "blocked"), fallbackCalls: 1},
+		"other errors are returned": {err: status.Error(codes.InvalidArgument, "bad"), code: codes.InvalidArgument},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert := assrt.New(t)
+
+			preferred := &transportAuthorizer{err: tc.err}
+			fallback := &transportAuthorizer{}
+
+			client := az.NewMultiTransport(preferred, fallback)
+
+			resp, err := client.Is(context.Background(), &authz.IsRequest{})
+			assert.Equal(tc.code, status.Code(err))
+			assert.Equal(1, preferred.calls)
+			assert.Equal(tc.fallbackCalls, fallback.calls)
+
+			if tc.code == codes.OK {
+				assert.True(resp.GetDecisions()[0].GetIs())
+			}
+		})
+	}
+}
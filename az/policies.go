@@ -0,0 +1,81 @@
+package az
+
+import (
+	"context"
+	"strings"
+
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// Policy is a policy module loaded by the authorizer.
+type Policy struct {
+	// ID identifies the module, typically by the path of its source file.
+	ID string
+
+	// PackagePath is the module's Rego package without the "data." prefix (e.g. "todo.GET.todos").
+	PackagePath string
+
+	// PackageRoot is the root of the module's policy.
+	PackageRoot string
+
+	// Source is the module's Rego source. It is only set if PolicyFilter.IncludeSource is true.
+	Source string
+}
+
+// PolicyFilter selects the policies returned by Client.Policies.
+type PolicyFilter struct {
+	// PathPrefix selects policies whose package path is or is nested under the prefix. For example, "todo" matches
+	// "todo" and "todo.GET.todos" but not "todos". An empty prefix matches all policies.
+	PathPrefix string
+
+	// IncludeSource determines whether the policies' Rego source is returned.
+	IncludeSource bool
+
+	// Instance is the policy instance to list policies from. If nil, the authorizer's default instance is used.
+	Instance *api.PolicyInstance
+}
+
+// Policies returns the policy modules loaded by the authorizer that match the filter.
+//
+// Unlike ListPolicies, it takes care of the request's field mask and returns plain values. Modules' ASTs aren't
+// requested, and their source is only requested if filter.IncludeSource is true.
+func (c *Client) Policies(ctx context.Context, filter PolicyFilter) ([]*Policy, error) {
+	paths := []string{"id", "package_path", "package_root"}
+	if filter.IncludeSource {
+		paths = append(paths, "raw")
+	}
+
+	resp, err := c.ListPolicies(ctx, &authz.ListPoliciesRequest{
+		FieldMask:      &fieldmaskpb.FieldMask{Paths: paths},
+		PolicyInstance: filter.Instance,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := strings.Trim(filter.PathPrefix, ".")
+	policies := make([]*Policy, 0, len(resp.GetResult()))
+
+	for _, module := range resp.GetResult() {
+		path := module.GetPackagePath()
+		if prefix != "" && path != prefix && !strings.HasPrefix(path, prefix+".") {
+			continue
+		}
+
+		policy := &Policy{
+			ID:          module.GetId(),
+			PackagePath: path,
+			PackageRoot: module.GetPackageRoot(),
+		}
+
+		if filter.IncludeSource {
+			policy.Source = module.GetRaw()
+		}
+
+		policies = append(policies, policy)
+	}
+
+	return policies, nil
+}
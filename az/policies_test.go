@@ -0,0 +1,63 @@
+package az_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/az"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	assrt "github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+type policiesAuthorizer struct {
+	authz.AuthorizerClient
+
+	req *authz.ListPoliciesRequest
+}
+
+func (f *policiesAuthorizer) ListPolicies(
+	_ context.Context,
+	in *authz.ListPoliciesRequest,
+	_ ...grpc.CallOption,
+) (*authz.ListPoliciesResponse, error) {
+	f.req = in
+
+	module := func(id, path string) *api.Module {
+		return &api.Module{
+			Id:          proto.String(id),
+			PackagePath: proto.String(path),
+			PackageRoot: proto.String("todo"),
+			Raw:         proto.String("package " + path),
+		}
+	}
+
+	return &authz.ListPoliciesResponse{Result: []*api.Module{
+		module("todo.rego", "todo"),
+		module("todos.rego", "todos.GET"),
+		module("todo/get.rego", "todo.GET.todos"),
+	}}, nil
+}
+
+func TestPolicies(t *testing.T) {
+	assert := assrt.New(t)
+
+	fake := &policiesAuthorizer{}
+	client := &az.Client{AuthorizerClient: fake}
+
+	policies, err := client.Policies(context.Background(), az.PolicyFilter{PathPrefix: "todo"})
+	assert.NoError(err)
+	assert.Equal([]string{"id", "package_path", "package_root"}, fake.req.GetFieldMask().GetPaths())
+	assert.Equal([]*az.Policy{
+		{ID: "todo.rego", PackagePath: "todo", PackageRoot: "todo"},
+		{ID: "todo/get.rego", PackagePath: "todo.GET.todos", PackageRoot: "todo"},
+	}, policies)
+
+	policies, err = client.Policies(context.Background(), az.PolicyFilter{IncludeSource: true})
+	assert.NoError(err)
+	assert.Contains(fake.req.GetFieldMask().GetPaths(), "raw")
+	assert.Len(policies, 3)
+	assert.Equal("package todos.GET", policies[1].Source)
+}
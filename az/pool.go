@@ -0,0 +1,279 @@
+package az
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aserto-dev/go-aserto"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+// DefaultPoolIdleTTL is how long a ClientPool keeps a tenant's connection open after its last call.
+const DefaultPoolIdleTTL = 10 * time.Minute
+
+// ErrPoolClosed is returned by ClientPool.For and by calls made with the pool's clients after the pool is closed.
+var ErrPoolClosed = errors.New("client pool is closed")
+
+// ClientPool creates and caches an authorizer connection for each tenant, for services that call the authorizer on
+// behalf of many tenants.
+//
+// Connections are created on first use with the pool's connection options and the tenant's ID. Connections without
+// calls in flight that aren't used for the pool's idle TTL are closed. Clients returned by the pool remain usable
+// after their connection is closed; the next call opens a new connection.
+//
+// To serve multiple tenants over a single connection instead, pass a context created with WithTenant to client calls.
+type ClientPool struct {
+	opts    []aserto.ConnectionOption
+	idleTTL time.Duration
+
+	mu      sync.Mutex
+	clients map[string]*pooledClient
+	closed  bool
+	done    chan struct{}
+}
+
+type pooledClient struct {
+	client   *Client
+	lastUsed time.Time
+	inFlight int
+}
+
+// NewClientPool returns a pool that creates tenant connections with the specified connection options.
+// Idle connections are closed after idleTTL. If idleTTL is zero, DefaultPoolIdleTTL is used.
+//
+// The pool must be closed when it is no longer needed.
+func NewClientPool(idleTTL time.Duration, opts ...aserto.ConnectionOption) *ClientPool {
+	if idleTTL <= 0 {
+		idleTTL = DefaultPoolIdleTTL
+	}
+
+	p := &ClientPool{
+		opts:    opts,
+		idleTTL: idleTTL,
+		clients: map[string]*pooledClient{},
+		done:    make(chan struct{}),
+	}
+
+	go p.evictIdle()
+
+	return p
+}
+
+// For returns a client that makes calls on behalf of the given tenant. The tenant's connection is created if it
+// doesn't exist.
+func (p *ClientPool) For(ctx context.Context, tenantID string) (authz.AuthorizerClient, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, err := p.get(tenantID); err != nil {
+		return nil, err
+	}
+
+	return &tenantClient{pool: p, tenantID: tenantID}, nil
+}
+
+// Len returns the number of open connections in the pool.
+func (p *ClientPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return len(p.clients)
+}
+
+// Close closes all connections in the pool, including those with calls in flight. Subsequent calls to For and
+// calls made with the pool's clients fail with ErrPoolClosed. Calling Close more than once has no effect.
+func (p *ClientPool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil
+	}
+
+	p.closed = true
+	close(p.done)
+
+	var errs error
+
+	for tenantID, pc := range p.clients {
+		if err := pc.client.Close(); err != nil {
+			errs = multierror.Append(errs, err)
+		}
+
+		delete(p.clients, tenantID)
+	}
+
+	return errs
+}
+
+// get returns the tenant's connection, creating it if it doesn't exist. The caller must hold p.mu.
+func (p *ClientPool) get(tenantID string) (*pooledClient, error) {
+	if p.closed {
+		return nil, ErrPoolClosed
+	}
+
+	if pc, ok := p.clients[tenantID]; ok {
+		pc.lastUsed = time.Now()
+		return pc, nil
+	}
+
+	opts := append(append([]aserto.ConnectionOption{}, p.opts...), aserto.WithTenantID(tenantID))
+
+	client, err := New(opts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "create client for tenant %q", tenantID)
+	}
+
+	pc := &pooledClient{client: client, lastUsed: time.Now()}
+	p.clients[tenantID] = pc
+
+	return pc, nil
+}
+
+// acquire returns the tenant's connection and marks a call in flight on it, so it isn't evicted.
+func (p *ClientPool) acquire(tenantID string) (*pooledClient, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pc, err := p.get(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	pc.inFlight++
+
+	return pc, nil
+}
+
+// release marks the end of a call made with acquire.
+func (p *ClientPool) release(pc *pooledClient) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	pc.inFlight--
+	pc.lastUsed = time.Now()
+}
+
+func (p *ClientPool) evictIdle() {
+	ticker := time.NewTicker(p.idleTTL / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.done:
+			return
+		case now := <-ticker.C:
+			p.evict(now)
+		}
+	}
+}
+
+func (p *ClientPool) evict(now time.Time) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for tenantID, pc := range p.clients {
+		if pc.inFlight == 0 && now.Sub(pc.lastUsed) >= p.idleTTL {
+			_ = pc.client.Close()
+
+			delete(p.clients, tenantID)
+		}
+	}
+}
+
+// tenantClient makes calls using the pool's connection for a tenant.
+type tenantClient struct {
+	pool     *ClientPool
+	tenantID string
+}
+
+var _ authz.AuthorizerClient = (*tenantClient)(nil)
+
+func (c *tenantClient) DecisionTree(
+	ctx context.Context,
+	in *authz.DecisionTreeRequest,
+	opts ...grpc.CallOption,
+) (*authz.DecisionTreeResponse, error) {
+	return withPooled(ctx, c, in, opts, func(client *Client) call[*authz.DecisionTreeRequest, *authz.DecisionTreeResponse] {
+		return client.DecisionTree
+	})
+}
+
+func (c *tenantClient) Is(ctx context.Context, in *authz.IsRequest, opts ...grpc.CallOption) (*authz.IsResponse, error) {
+	return withPooled(ctx, c, in, opts, func(client *Client) call[*authz.IsRequest, *authz.IsResponse] {
+		return client.Is
+	})
+}
+
+func (c *tenantClient) Query(
+	ctx context.Context,
+	in *authz.QueryRequest,
+	opts ...grpc.CallOption,
+) (*authz.QueryResponse, error) {
+	return withPooled(ctx, c, in, opts, func(client *Client) call[*authz.QueryRequest, *authz.QueryResponse] {
+		return client.Query
+	})
+}
+
+func (c *tenantClient) Compile(
+	ctx context.Context,
+	in *authz.CompileRequest,
+	opts ...grpc.CallOption,
+) (*authz.CompileResponse, error) {
+	return withPooled(ctx, c, in, opts, func(client *Client) call[*authz.CompileRequest, *authz.CompileResponse] {
+		return client.Compile
+	})
+}
+
+func (c *tenantClient) ListPolicies(
+	ctx context.Context,
+	in *authz.ListPoliciesRequest,
+	opts ...grpc.CallOption,
+) (*authz.ListPoliciesResponse, error) {
+	return withPooled(ctx, c, in, opts, func(client *Client) call[*authz.ListPoliciesRequest, *authz.ListPoliciesResponse] {
+		return client.ListPolicies
+	})
+}
+
+func (c *tenantClient) GetPolicy(
+	ctx context.Context,
+	in *authz.GetPolicyRequest,
+	opts ...grpc.CallOption,
+) (*authz.GetPolicyResponse, error) {
+	return withPooled(ctx, c, in, opts, func(client *Client) call[*authz.GetPolicyRequest, *authz.GetPolicyResponse] {
+		return client.GetPolicy
+	})
+}
+
+func (c *tenantClient) Info(ctx context.Context, in *authz.InfoRequest, opts ...grpc.CallOption) (*authz.InfoResponse, error) {
+	return withPooled(ctx, c, in, opts, func(client *Client) call[*authz.InfoRequest, *authz.InfoResponse] {
+		return client.Info
+	})
+}
+
+// withPooled makes a call using the tenant's connection, which isn't evicted while the call is in flight.
+func withPooled[Req, Resp any](
+	ctx context.Context,
+	c *tenantClient,
+	in Req,
+	opts []grpc.CallOption,
+	method func(*Client) call[Req, Resp],
+) (Resp, error) {
+	pc, err := c.pool.acquire(c.tenantID)
+	if err != nil {
+		var zero Resp
+		return zero, err
+	}
+
+	defer c.pool.release(pc)
+
+	return method(pc.client)(ctx, in, opts...)
+}
@@ -0,0 +1,146 @@
+package az_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/aserto-dev/go-aserto"
+	"github.com/aserto-dev/go-aserto/az"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	assrt "github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestClientPoolTenants(t *testing.T) {
+	assert := assrt.New(t)
+
+	addr, calls := metadataServer(t)
+
+	pool := az.NewClientPool(0, aserto.WithAddr(addr), aserto.WithNoTLS(true))
+	defer pool.Close()
+
+	acme, err := pool.For(context.Background(), "acme")
+	assert.NoError(err)
+
+	_, err = pool.For(context.Background(), "acme")
+	assert.NoError(err)
+	assert.Equal(1, pool.Len())
+
+	other, err := pool.For(context.Background(), "other")
+	assert.NoError(err)
+	assert.Equal(2, pool.Len())
+
+	_, _ = acme.Is(context.Background(), &authz.IsRequest{})
+	assert.Equal([]string{"acme"}, (<-calls).Get("aserto-tenant-id"))
+
+	_, _ = other.Is(context.Background(), &authz.IsRequest{})
+	assert.Equal([]string{"other"}, (<-calls).Get("aserto-tenant-id"))
+}
+
+func TestClientPoolEvictsIdle(t *testing.T) {
+	assert := assrt.New(t)
+
+	pool := az.NewClientPool(20*time.Millisecond, aserto.WithAddr("localhost:8282"), aserto.WithNoTLS(true))
+	defer pool.Close()
+
+	_, err := pool.For(context.Background(), "acme")
+	assert.NoError(err)
+	assert.Equal(1, pool.Len())
+
+	assert.Eventually(func() bool { return pool.Len() == 0 }, time.Second, 5*time.Millisecond)
+}
+
+// blockingServer starts a gRPC server whose calls report that they started and then wait for release to be closed.
+func blockingServer(t *testing.T) (addr string, started <-chan struct{}, release chan<- struct{}) {
+	t.Helper()
+
+	startedCh := make(chan struct{}, 1)
+	releaseCh := make(chan struct{})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assrt.NoError(t, err)
+
+	server := grpc.NewServer(grpc.UnknownServiceHandler(func(_ interface{}, _ grpc.ServerStream) error {
+		startedCh <- struct{}{}
+		<-releaseCh
+
+		return status.Error(codes.Unimplemented, "not implemented")
+	}))
+	t.Cleanup(server.Stop)
+
+	go func() { _ = server.Serve(lis) }()
+
+	return lis.Addr().String(), startedCh, releaseCh
+}
+
+func TestClientPoolKeepsConnectionsInUse(t *testing.T) {
+	assert := assrt.New(t)
+
+	addr, started, release := blockingServer(t)
+
+	pool := az.NewClientPool(20*time.Millisecond, aserto.WithAddr(addr), aserto.WithNoTLS(true))
+	defer pool.Close()
+
+	client, err := pool.For(context.Background(), "acme")
+	assert.NoError(err)
+
+	errs := make(chan error, 1)
+
+	go func() {
+		_, err := client.Is(context.Background(), &authz.IsRequest{})
+		errs <- err
+	}()
+
+	<-started
+
+	// Eviction runs several times while the call is in flight.
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(1, pool.Len())
+
+	close(release)
+	assert.Equal(codes.Unimplemented, status.Code(<-errs))
+
+	assert.Eventually(func() bool { return pool.Len() == 0 }, time.Second, 5*time.Millisecond)
+
+	// The client remains usable after its connection is evicted.
+	_, err = client.Is(context.Background(), &authz.IsRequest{})
+	assert.Equal(codes.Unimplemented, status.Code(err))
+	assert.Equal(1, pool.Len())
+}
+
+func TestClientPoolClose(t *testing.T) {
+	assert := assrt.New(t)
+
+	pool := az.NewClientPool(0, aserto.WithAddr("localhost:8282"), aserto.WithNoTLS(true))
+
+	client, err := pool.For(context.Background(), "acme")
+	assert.NoError(err)
+
+	assert.NoError(pool.Close())
+	assert.Equal(0, pool.Len())
+	assert.NoError(pool.Close())
+
+	_, err = pool.For(context.Background(), "acme")
+	assert.ErrorIs(err, az.ErrPoolClosed)
+
+	_, err = client.Is(context.Background(), &authz.IsRequest{})
+	assert.ErrorIs(err, az.ErrPoolClosed)
+}
+
+func TestClientPoolCanceledContext(t *testing.T) {
+	assert := assrt.New(t)
+
+	pool := az.NewClientPool(0, aserto.WithAddr("localhost:8282"), aserto.WithNoTLS(true))
+	defer pool.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := pool.For(ctx, "acme")
+	assert.ErrorIs(err, context.Canceled)
+	assert.Equal(0, pool.Len())
+}
@@ -0,0 +1,56 @@
+package az
+
+import (
+	"context"
+	"encoding/json"
+
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// Query evaluates an ad-hoc Rego query against the policy instance and decodes the authorizer's response into
+// result.
+//
+// The input map is passed to the query as "input". The response is decoded using encoding/json. It is an object
+// whose "result" field holds the query's result set.
+// If instance is nil, the authorizer's default policy is used.
+func Query(
+	ctx context.Context,
+	client authz.AuthorizerClient,
+	instance *api.PolicyInstance,
+	query string,
+	input map[string]interface{},
+	result interface{},
+) error {
+	req := &authz.QueryRequest{
+		Query:          query,
+		PolicyInstance: instance,
+	}
+
+	if input != nil {
+		b, err := json.Marshal(input)
+		if err != nil {
+			return errors.Wrap(err, "invalid query input")
+		}
+
+		req.Input = string(b)
+	}
+
+	resp, err := client.Query(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	b, err := protojson.Marshal(resp.GetResponse())
+	if err != nil {
+		return errors.Wrap(err, "invalid query response")
+	}
+
+	if err := json.Unmarshal(b, result); err != nil {
+		return errors.Wrap(err, "failed to decode query response")
+	}
+
+	return nil
+}
@@ -0,0 +1,94 @@
+package az_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto"
+	"github.com/aserto-dev/go-aserto/az"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	assrt "github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type queryAuthorizer struct {
+	authz.AuthorizerClient
+
+	req *authz.QueryRequest
+}
+
+func (f *queryAuthorizer) Query(
+	_ context.Context,
+	in *authz.QueryRequest,
+	_ ...grpc.CallOption,
+) (*authz.QueryResponse, error) {
+	f.req = in
+
+	resp, err := structpb.NewStruct(map[string]interface{}{
+		"result": []interface{}{
+			map[string]interface{}{"bindings": map[string]interface{}{"x": true}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &authz.QueryResponse{Response: resp}, nil
+}
+
+func TestQuery(t *testing.T) {
+	assert := assrt.New(t)
+
+	client := &queryAuthorizer{}
+	instance := &api.PolicyInstance{Name: "myapp", InstanceLabel: "myapp"}
+
+	var result struct {
+		Result []struct {
+			Bindings map[string]bool `json:"bindings"`
+		} `json:"result"`
+	}
+
+	err := az.Query(context.Background(), client, instance, "x = data.myapp.allowed", map[string]interface{}{"user": "alice"}, &result)
+	assert.NoError(err)
+
+	assert.Equal("x = data.myapp.allowed", client.req.GetQuery())
+	assert.Equal(instance, client.req.GetPolicyInstance())
+	assert.JSONEq(`{"user": "alice"}`, client.req.GetInput())
+
+	assert.Len(result.Result, 1)
+	assert.True(result.Result[0].Bindings["x"])
+}
+
+func ExampleQuery() {
+	client, err := az.New(aserto.WithAddr("localhost:8282"))
+	if err != nil {
+		return
+	}
+	defer client.Close()
+
+	var result struct {
+		Result []struct {
+			Bindings map[string]interface{} `json:"bindings"`
+		} `json:"result"`
+	}
+
+	// Evaluate a rule of a loaded policy for a given input.
+	err = az.Query(
+		context.Background(),
+		client,
+		&api.PolicyInstance{Name: "todo", InstanceLabel: "todo"},
+		"allowed = data.todo.GET.todos.allowed",
+		map[string]interface{}{"user": map[string]interface{}{"id": "rick@the-citadel.com"}},
+		&result,
+	)
+	if err != nil {
+		return
+	}
+
+	b, _ := json.Marshal(result)
+	fmt.Println(string(b))
+}
@@ -0,0 +1,16 @@
+package az
+
+import (
+	"context"
+
+	"github.com/aserto-dev/go-aserto"
+)
+
+// WithTenant returns a copy of ctx that directs calls made with it to the given tenant, overriding the tenant ID of
+// the client's connection. It lets a single client serve multiple tenants.
+//
+// The context can be passed directly to client calls or to middleware, which use the request context when calling
+// the authorizer. An empty tenantID leaves ctx unchanged.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return aserto.SetTenantContext(ctx, tenantID)
+}
@@ -0,0 +1,83 @@
+package az
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var ErrMissingPolicyPath = errors.New("policy context has no path")
+
+// Trace is the evaluation trace of a policy decision.
+type Trace struct {
+	// Events are the trace events reported by the authorizer, in evaluation order.
+	Events []*structpb.Struct
+
+	// Summary is a human-readable rendering of the trace, one line per entry.
+	Summary []string
+}
+
+// IsWithTrace makes the same decisions as Is and also returns the authorizer's evaluation trace, which shows the
+// rules that were evaluated and their outcomes.
+//
+// The authorizer doesn't trace Is calls, so the decisions are evaluated using a Query call with full tracing
+// enabled. Decisions that are undefined in the policy are false.
+// Tracing is expensive and is meant for development and debugging.
+func (c *Client) IsWithTrace(ctx context.Context, req *authz.IsRequest) (*authz.IsResponse, *Trace, error) {
+	path := req.GetPolicyContext().GetPath()
+	if path == "" {
+		return nil, nil, ErrMissingPolicyPath
+	}
+
+	resp, err := c.Query(ctx, &authz.QueryRequest{
+		Query: "x = " + dataRef(path),
+		Options: &authz.QueryOptions{
+			Trace:        authz.TraceLevel_TRACE_LEVEL_FULL,
+			TraceSummary: true,
+		},
+		PolicyContext:   req.GetPolicyContext(),
+		IdentityContext: req.GetIdentityContext(),
+		ResourceContext: req.GetResourceContext(),
+		PolicyInstance:  req.GetPolicyInstance(),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rules := binding(resp.GetResponse(), "x").GetStructValue().GetFields()
+	decisions := make([]*authz.Decision, len(req.GetPolicyContext().GetDecisions()))
+
+	for i, name := range req.GetPolicyContext().GetDecisions() {
+		decisions[i] = &authz.Decision{Decision: name, Is: rules[name].GetBoolValue()}
+	}
+
+	return &authz.IsResponse{Decisions: decisions}, &Trace{Events: resp.GetTrace(), Summary: resp.GetTraceSummary()}, nil
+}
+
+// dataRef returns a Rego reference to the policy package with the given dot-separated path.
+// Segments are quoted so paths may include characters that aren't valid in Rego identifiers.
+func dataRef(path string) string {
+	var ref strings.Builder
+
+	ref.WriteString("data")
+
+	for _, segment := range strings.Split(path, ".") {
+		ref.WriteString("[" + strconv.Quote(segment) + "]")
+	}
+
+	return ref.String()
+}
+
+// binding returns the value bound to a variable in the first result of a query response.
+func binding(resp *structpb.Struct, name string) *structpb.Value {
+	results := resp.GetFields()["result"].GetListValue().GetValues()
+	if len(results) == 0 {
+		return nil
+	}
+
+	return results[0].GetStructValue().GetFields()["bindings"].GetStructValue().GetFields()[name]
+}
@@ -0,0 +1,84 @@
+package az_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/az"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	assrt "github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type traceAuthorizer struct {
+	authz.AuthorizerClient
+
+	req *authz.QueryRequest
+}
+
+func (f *traceAuthorizer) Query(
+	_ context.Context,
+	in *authz.QueryRequest,
+	_ ...grpc.CallOption,
+) (*authz.QueryResponse, error) {
+	f.req = in
+
+	resp, err := structpb.NewStruct(map[string]interface{}{
+		"result": []interface{}{
+			map[string]interface{}{
+				"bindings": map[string]interface{}{
+					"x": map[string]interface{}{"allowed": true, "enabled": false},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := structpb.NewStruct(map[string]interface{}{"op": "enter"})
+	if err != nil {
+		return nil, err
+	}
+
+	return &authz.QueryResponse{
+		Response:     resp,
+		Trace:        []*structpb.Struct{event},
+		TraceSummary: []string{"Enter data.documents.read = x"},
+	}, nil
+}
+
+func TestIsWithTrace(t *testing.T) {
+	assert := assrt.New(t)
+
+	fake := &traceAuthorizer{}
+	client := &az.Client{AuthorizerClient: fake}
+
+	resp, trace, err := client.IsWithTrace(context.Background(), &authz.IsRequest{
+		IdentityContext: identity,
+		PolicyContext:   &api.PolicyContext{Path: "documents.read", Decisions: []string{"allowed", "enabled", "visible"}},
+	})
+	assert.NoError(err)
+
+	assert.Equal(`x = data["documents"]["read"]`, fake.req.GetQuery())
+	assert.Equal(authz.TraceLevel_TRACE_LEVEL_FULL, fake.req.GetOptions().GetTrace())
+	assert.Equal(identity, fake.req.GetIdentityContext())
+
+	assert.Len(resp.GetDecisions(), 3)
+	assert.True(resp.GetDecisions()[0].GetIs())
+	assert.False(resp.GetDecisions()[1].GetIs())
+	assert.Equal("visible", resp.GetDecisions()[2].GetDecision())
+	assert.False(resp.GetDecisions()[2].GetIs())
+
+	assert.Len(trace.Events, 1)
+	assert.Equal([]string{"Enter data.documents.read = x"}, trace.Summary)
+}
+
+func TestIsWithTraceMissingPath(t *testing.T) {
+	client := &az.Client{AuthorizerClient: &traceAuthorizer{}}
+
+	_, _, err := client.IsWithTrace(context.Background(), &authz.IsRequest{})
+	assrt.ErrorIs(t, err, az.ErrMissingPolicyPath)
+}
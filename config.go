@@ -41,6 +41,18 @@ type Config struct {
 	// validate the server's certificate against.
 	CACertPath string `json:"ca_cert_path"`
 
+	// In TLS connections, CACertPaths holds paths of additional CA certificates to
+	// validate the server's certificate against.
+	CACertPaths []string `json:"ca_cert_paths"`
+
+	// In TLS connections, CACertDir is a directory of CA certificates to validate the
+	// server's certificate against. Files with a .pem, .crt, or .cer extension are loaded.
+	CACertDir string `json:"ca_cert_dir"`
+
+	// In TLS connections, NoSystemCertPool causes the configured CA certificates to replace
+	// the system's root CAs instead of being appended to them.
+	NoSystemCertPool bool `json:"no_system_cert_pool"`
+
 	// In TLS connections, skip verification of the server certificate.
 	Insecure bool `json:"insecure"`
 
@@ -53,6 +65,9 @@ type Config struct {
 	// Additional headers to include in requests to the service.
 	Headers map[string]string `json:"headers"`
 
+	// UserAgent identifies the client in requests to the service. Default: "go-aserto/<version>".
+	UserAgent string `json:"user_agent"`
+
 	// Deprecated: no longer used. Timeouts are controlled on a per-call basis
 	// by the provided context.
 	TimeoutInSeconds int `json:"timeout_in_seconds"`
@@ -104,6 +119,18 @@ func (cfg *Config) ToConnectionOptions() ([]ConnectionOption, error) {
 		options = append(options, WithCACertPath(cfg.CACertPath))
 	}
 
+	if len(cfg.CACertPaths) > 0 {
+		options = append(options, WithCACertPaths(cfg.CACertPaths...))
+	}
+
+	if cfg.CACertDir != "" {
+		options = append(options, WithCACertDir(cfg.CACertDir))
+	}
+
+	if cfg.NoSystemCertPool {
+		options = append(options, WithSystemCertPool(false))
+	}
+
 	if cfg.TenantID != "" {
 		options = append(options, WithTenantID(cfg.TenantID))
 	}
@@ -116,9 +143,19 @@ func (cfg *Config) ToConnectionOptions() ([]ConnectionOption, error) {
 		options = append(options, WithHeader(key, value))
 	}
 
+	if cfg.UserAgent != "" {
+		options = append(options, WithUserAgent(cfg.UserAgent))
+	}
+
 	return options, nil
 }
 
+// Validate returns an error if the configuration has conflicting settings, such as both an API key and a token, or
+// a client certificate without a private key.
+func (cfg *Config) Validate() error {
+	return cfg.validate()
+}
+
 func (cfg *Config) validate() error {
 	if cfg.APIKey != "" && cfg.Token != "" {
 		return errors.Wrap(ErrInvalidConfig, "api_key and token are mutually exclusive")
@@ -12,6 +12,7 @@ package aserto
 import (
 	"context"
 	"strings"
+	"sync"
 
 	"github.com/aserto-dev/go-aserto/internal/hosted"
 	"github.com/aserto-dev/header"
@@ -21,6 +22,11 @@ import (
 )
 
 // NewConnection creates a gRPC connection with the given options.
+//
+// If no address is specified, NewConnection connects to DefaultAuthorizer().
+//
+// NewConnection doesn't block. The connection is established on first use. Use WithWaitForReady to have calls made
+// before the connection is ready wait for it instead of failing.
 func NewConnection(opts ...ConnectionOption) (*grpc.ClientConn, error) {
 	options, err := NewConnectionOptions(opts...)
 	if err != nil {
@@ -29,12 +35,40 @@ func NewConnection(opts ...ConnectionOption) (*grpc.ClientConn, error) {
 
 	if options.Address == "" {
 		// Backward compatibility: default to authorizer service.
-		options.Address = hosted.HostedAuthorizerHostname + hosted.HostedAuthorizerGRPCPort
+		options.Address = DefaultAuthorizer()
 	}
 
 	return Connect(options)
 }
 
+var defaultAuthorizer struct {
+	sync.RWMutex
+	addr string
+}
+
+// SetDefaultAuthorizer sets the address NewConnection connects to when no address is specified, for deployments that
+// use a self-hosted authorizer. An empty address restores the default, Aserto's hosted authorizer.
+//
+// SetDefaultAuthorizer is meant to be called once during initialization, before connections are created.
+func SetDefaultAuthorizer(addr string) {
+	defaultAuthorizer.Lock()
+	defer defaultAuthorizer.Unlock()
+
+	defaultAuthorizer.addr = addr
+}
+
+// DefaultAuthorizer returns the address NewConnection connects to when no address is specified.
+func DefaultAuthorizer() string {
+	defaultAuthorizer.RLock()
+	defer defaultAuthorizer.RUnlock()
+
+	if defaultAuthorizer.addr == "" {
+		return hosted.HostedAuthorizerHostname + hosted.HostedAuthorizerGRPCPort
+	}
+
+	return defaultAuthorizer.addr
+}
+
 // Connect creates a gRPC connection with the given options.
 func Connect(options *ConnectionOptions) (*grpc.ClientConn, error) {
 	if options.Address == "" {
@@ -0,0 +1,31 @@
+package aserto_test
+
+import (
+	"testing"
+
+	"github.com/aserto-dev/go-aserto"
+	assrt "github.com/stretchr/testify/assert"
+)
+
+func TestSetDefaultAuthorizer(t *testing.T) {
+	assert := assrt.New(t)
+
+	hosted := aserto.DefaultAuthorizer()
+	assert.Equal("authorizer.prod.aserto.com:8443", hosted)
+
+	aserto.SetDefaultAuthorizer("authorizer.internal:8282")
+	t.Cleanup(func() { aserto.SetDefaultAuthorizer("") })
+
+	conn, err := aserto.NewConnection(aserto.WithInsecure(true))
+	assert.NoError(err)
+	assert.Equal("authorizer.internal:8282", conn.Target())
+	assert.NoError(conn.Close())
+
+	conn, err = aserto.NewConnection(aserto.WithAddr("localhost:8282"), aserto.WithInsecure(true))
+	assert.NoError(err)
+	assert.Equal("localhost:8282", conn.Target())
+	assert.NoError(conn.Close())
+
+	aserto.SetDefaultAuthorizer("")
+	assert.Equal(hosted, aserto.DefaultAuthorizer())
+}
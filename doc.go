@@ -10,11 +10,8 @@ middleware.
 The AuthorizerClient interface, defined in "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2",
 describes the operations exposed by the Aserto authorizer service.
 
-Two implementation of AuthorizerClient are available:
-
-1. `authorizer/grpc` provides a client that communicates with the authorizer using gRPC.
-
-2. `authorizer/http` provides a client that communicates with the authorizer over its REST HTTP endpoints.
+The `az` package provides an implementation of AuthorizerClient that communicates with the authorizer using gRPC.
+There is no REST HTTP implementation of AuthorizerClient.
 
 # Middleware
 
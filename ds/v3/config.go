@@ -1,6 +1,7 @@
 package ds
 
 import (
+	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
 	"github.com/samber/lo"
 	"google.golang.org/grpc"
@@ -46,17 +47,42 @@ func (c *Config) Connect() (*Client, error) {
 }
 
 // Validate returns an error if the configuration is invalid.
+//
+// At least one client configuration must be specified and each specified configuration must be valid.
+// Errors in client configurations are prefixed with the name of the client (e.g. "reader: ...").
 func (c *Config) Validate() error {
 	if c == nil {
 		return ErrInvalidConfig
 	}
 
 	// At least one client config must be non-nil.
-	if allNil([]*aserto.Config{c.Config, c.Reader, c.Writer, c.Importer, c.Exporter}) {
+	if allNil([]*aserto.Config{c.Config, c.Reader, c.Writer, c.Importer, c.Exporter, c.Model}) {
 		return ErrInvalidConfig
 	}
 
-	return nil
+	var errs error
+
+	for _, client := range []struct {
+		name string
+		cfg  *aserto.Config
+	}{
+		{"base", c.Config},
+		{"reader", c.Reader},
+		{"writer", c.Writer},
+		{"importer", c.Importer},
+		{"exporter", c.Exporter},
+		{"model", c.Model},
+	} {
+		if client.cfg == nil {
+			continue
+		}
+
+		if err := client.cfg.Validate(); err != nil {
+			errs = multierror.Append(errs, errors.Wrap(err, client.name))
+		}
+	}
+
+	return errs
 }
 
 func connect(conns *internal.Connections, cfg *Config) (*Client, error) {
@@ -127,6 +127,59 @@ func TestConnect(t *testing.T) {
 	})
 }
 
+func TestValidate(t *testing.T) {
+	assert := asserts.New(t)
+
+	cfg := &Config{
+		Config:   &aserto.Config{Address: "localhost:8282"},
+		Reader:   &aserto.Config{Address: "localhost:9292", APIKey: "key", Token: "token"},
+		Exporter: &aserto.Config{Address: "localhost:9393", ClientCertPath: "client.crt"},
+	}
+
+	err := cfg.Validate()
+	assert.ErrorIs(err, aserto.ErrInvalidConfig)
+	assert.ErrorContains(err, "reader: api_key and token are mutually exclusive")
+	assert.ErrorContains(err, "exporter: client_cert_path and client_key_path must be specified together")
+	assert.NotContains(err.Error(), "base")
+
+	_, err = connect(internal.NewConnections(), cfg)
+	assert.ErrorIs(err, aserto.ErrInvalidConfig)
+
+	assert.ErrorIs((&Config{}).Validate(), ErrInvalidConfig)
+}
+
+func TestConnection(t *testing.T) {
+	assert := asserts.New(t)
+
+	conns, _ := mockConns()
+
+	dir, err := connect(conns, &Config{Config: &aserto.Config{Address: "localhost:8282"}})
+	assert.NoError(err)
+	assert.NotNil(dir.Connection())
+
+	conns, _ = mockConns()
+
+	dir, err = connect(conns, &Config{
+		Config: &aserto.Config{Address: "localhost:8282"},
+		Reader: &aserto.Config{Address: "localhost:9292"},
+	})
+	assert.NoError(err)
+	assert.Nil(dir.Connection())
+}
+
+func TestClose(t *testing.T) {
+	assert := asserts.New(t)
+
+	dir, err := (&Config{
+		Config: &aserto.Config{Address: "localhost:8282", NoTLS: true},
+		Writer: &aserto.Config{Address: "localhost:9292", NoTLS: true},
+	}).Connect()
+	assert.NoError(err)
+
+	assert.NoError(dir.Close())
+	assert.NoError(dir.Close())
+}
+
 func mockConns() (*internal.Connections, *internal.ConnectCounter) {
 	counter := &internal.ConnectCounter{}
 	conns := internal.NewConnections()
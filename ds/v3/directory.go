@@ -10,6 +10,7 @@ import (
 	dws "github.com/aserto-dev/go-directory/aserto/directory/writer/v3"
 	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
+	"github.com/samber/lo"
 	"google.golang.org/grpc"
 )
 
@@ -71,15 +72,28 @@ func FromConnection(conn *grpc.ClientConn) *Client {
 	}
 }
 
-// Close closes the underlying connections.
+// Connection returns the gRPC connection shared by all the client's services.
+// It returns nil if the services use different connections.
+func (c *Client) Connection() *grpc.ClientConn {
+	if len(c.conns) != 1 {
+		return nil
+	}
+
+	return c.conns[0]
+}
+
+// Close closes the underlying connections. Connections shared by multiple services are closed once.
+// Calling Close more than once has no effect.
 func (c *Client) Close() error {
 	var errs error
 
-	for _, conn := range c.conns {
+	for _, conn := range lo.Uniq(c.conns) {
 		if err := conn.Close(); err != nil {
 			errs = multierror.Append(errs, err)
 		}
 	}
 
+	c.conns = nil
+
 	return errs
 }
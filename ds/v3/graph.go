@@ -0,0 +1,39 @@
+package ds
+
+import (
+	"context"
+
+	drs "github.com/aserto-dev/go-directory/aserto/directory/reader/v3"
+	"github.com/pkg/errors"
+	"github.com/samber/lo"
+)
+
+// ObjectsForSubject returns the IDs of all objects of the given type on which the subject has the given relation or
+// permission (e.g. all folders that alice can read).
+//
+// It uses the directory's graph API, which follows relations transitively and evaluates permissions, and returns
+// all results in a single response. Duplicate IDs are removed.
+func (c *Client) ObjectsForSubject(
+	ctx context.Context,
+	subjectType, subjectID, relation, objectType string,
+) ([]string, error) {
+	resp, err := c.Reader.GetGraph(ctx, &drs.GetGraphRequest{
+		ObjectType:  objectType,
+		Relation:    relation,
+		SubjectType: subjectType,
+		SubjectId:   subjectID,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "get graph failed")
+	}
+
+	ids := make([]string, 0, len(resp.GetResults()))
+
+	for _, obj := range resp.GetResults() {
+		if obj.GetObjectType() == objectType {
+			ids = append(ids, obj.GetObjectId())
+		}
+	}
+
+	return lo.Uniq(ids), nil
+}
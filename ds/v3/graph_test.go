@@ -0,0 +1,67 @@
+package ds //nolint:testpackage
+
+import (
+	"context"
+	"testing"
+
+	dsc "github.com/aserto-dev/go-directory/aserto/directory/common/v3"
+	drs "github.com/aserto-dev/go-directory/aserto/directory/reader/v3"
+	asserts "github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+type graphReader struct {
+	drs.ReaderClient
+
+	req *drs.GetGraphRequest
+	err error
+}
+
+func (r *graphReader) GetGraph(
+	_ context.Context,
+	in *drs.GetGraphRequest,
+	_ ...grpc.CallOption,
+) (*drs.GetGraphResponse, error) {
+	r.req = in
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	return &drs.GetGraphResponse{
+		Results: []*dsc.ObjectIdentifier{
+			{ObjectType: "folder", ObjectId: "f1"},
+			{ObjectType: "folder", ObjectId: "f2"},
+			{ObjectType: "folder", ObjectId: "f1"},
+			{ObjectType: "document", ObjectId: "d1"},
+		},
+	}, nil
+}
+
+func TestObjectsForSubject(t *testing.T) {
+	assert := asserts.New(t)
+
+	reader := &graphReader{}
+	client := &Client{Reader: reader}
+
+	ids, err := client.ObjectsForSubject(context.Background(), "user", "alice", "can_read", "folder")
+	assert.NoError(err)
+	assert.Equal([]string{"f1", "f2"}, ids)
+
+	assert.Equal("folder", reader.req.GetObjectType())
+	assert.Empty(reader.req.GetObjectId())
+	assert.Equal("can_read", reader.req.GetRelation())
+	assert.Equal("user", reader.req.GetSubjectType())
+	assert.Equal("alice", reader.req.GetSubjectId())
+}
+
+func TestObjectsForSubjectError(t *testing.T) {
+	assert := asserts.New(t)
+
+	client := &Client{Reader: &graphReader{err: status.Error(codes.Unavailable, "unavailable")}}
+
+	_, err := client.ObjectsForSubject(context.Background(), "user", "alice", "can_read", "folder")
+	assert.Error(err)
+	assert.Equal(codes.Unavailable, status.Code(err))
+}
@@ -0,0 +1,93 @@
+package ds
+
+import (
+	"context"
+
+	dsc "github.com/aserto-dev/go-directory/aserto/directory/common/v3"
+	drs "github.com/aserto-dev/go-directory/aserto/directory/reader/v3"
+	"google.golang.org/protobuf/proto"
+)
+
+// IterObjects returns an iterator over the objects matched by req. Pages are fetched as the iterator advances.
+//
+// The returned function has the signature of iter.Seq2[*dsc.Object, error] and can be used in range loops in Go 1.23
+// and later:
+//
+//	for obj, err := range dsClient.IterObjects(ctx, &reader.GetObjectsRequest{ObjectType: "user"}) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// If a page fails to load or ctx is done, the error is yielded and iteration stops. The page size and initial token
+// are taken from req.Page, if set. req isn't modified.
+func (c *Client) IterObjects(
+	ctx context.Context,
+	req *drs.GetObjectsRequest,
+) func(yield func(*dsc.Object, error) bool) {
+	req = proto.Clone(req).(*drs.GetObjectsRequest)
+
+	return paginate(ctx, req.GetPage(), func(page *dsc.PaginationRequest) ([]*dsc.Object, *dsc.PaginationResponse, error) {
+		req.Page = page
+
+		resp, err := c.Reader.GetObjects(ctx, req)
+
+		return resp.GetResults(), resp.GetPage(), err
+	})
+}
+
+// IterRelations returns an iterator over the relations matched by req. Pages are fetched as the iterator advances.
+//
+// The returned function has the signature of iter.Seq2[*dsc.Relation, error]. See IterObjects for details.
+func (c *Client) IterRelations(
+	ctx context.Context,
+	req *drs.GetRelationsRequest,
+) func(yield func(*dsc.Relation, error) bool) {
+	req = proto.Clone(req).(*drs.GetRelationsRequest)
+
+	return paginate(ctx, req.GetPage(), func(page *dsc.PaginationRequest) ([]*dsc.Relation, *dsc.PaginationResponse, error) {
+		req.Page = page
+
+		resp, err := c.Reader.GetRelations(ctx, req)
+
+		return resp.GetResults(), resp.GetPage(), err
+	})
+}
+
+// paginate calls fetch with successive pagination tokens and yields the results until the last page is reached,
+// yield returns false, or an error occurs.
+func paginate[T any](
+	ctx context.Context,
+	start *dsc.PaginationRequest,
+	fetch func(*dsc.PaginationRequest) ([]T, *dsc.PaginationResponse, error),
+) func(yield func(T, error) bool) {
+	return func(yield func(T, error) bool) {
+		var zero T
+
+		token := start.GetToken()
+
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(zero, err)
+				return
+			}
+
+			results, page, err := fetch(&dsc.PaginationRequest{Size: start.GetSize(), Token: token})
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+
+			for _, result := range results {
+				if !yield(result, nil) {
+					return
+				}
+			}
+
+			if token = page.GetNextToken(); token == "" {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package ds //nolint:testpackage
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	dsc "github.com/aserto-dev/go-directory/aserto/directory/common/v3"
+	drs "github.com/aserto-dev/go-directory/aserto/directory/reader/v3"
+	asserts "github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// pagedReader serves pages of two objects and relations. Tokens are page numbers.
+type pagedReader struct {
+	drs.ReaderClient
+
+	pages  int
+	failAt int
+	tokens []string
+}
+
+func (r *pagedReader) page(token string) (int, *dsc.PaginationResponse, error) {
+	r.tokens = append(r.tokens, token)
+
+	page := 0
+	if token != "" {
+		page, _ = strconv.Atoi(token)
+	}
+
+	if r.failAt > 0 && page == r.failAt {
+		return 0, nil, status.Error(codes.Unavailable, "unavailable")
+	}
+
+	next := ""
+	if page < r.pages-1 {
+		next = strconv.Itoa(page + 1)
+	}
+
+	return page, &dsc.PaginationResponse{NextToken: next}, nil
+}
+
+func (r *pagedReader) GetObjects(
+	_ context.Context,
+	in *drs.GetObjectsRequest,
+	_ ...grpc.CallOption,
+) (*drs.GetObjectsResponse, error) {
+	page, resp, err := r.page(in.GetPage().GetToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return &drs.GetObjectsResponse{
+		Results: []*dsc.Object{
+			{Type: in.GetObjectType(), Id: strconv.Itoa(2 * page)},
+			{Type: in.GetObjectType(), Id: strconv.Itoa(2*page + 1)},
+		},
+		Page: resp,
+	}, nil
+}
+
+func (r *pagedReader) GetRelations(
+	_ context.Context,
+	in *drs.GetRelationsRequest,
+	_ ...grpc.CallOption,
+) (*drs.GetRelationsResponse, error) {
+	page, resp, err := r.page(in.GetPage().GetToken())
+	if err != nil {
+		return nil, err
+	}
+
+	return &drs.GetRelationsResponse{
+		Results: []*dsc.Relation{
+			{Relation: in.GetRelation(), ObjectId: strconv.Itoa(2 * page)},
+			{Relation: in.GetRelation(), ObjectId: strconv.Itoa(2*page + 1)},
+		},
+		Page: resp,
+	}, nil
+}
+
+func TestIterObjects(t *testing.T) {
+	assert := asserts.New(t)
+
+	reader := &pagedReader{pages: 3}
+	client := &Client{Reader: reader}
+	req := &drs.GetObjectsRequest{ObjectType: "user"}
+
+	var ids []string
+
+	client.IterObjects(context.Background(), req)(func(obj *dsc.Object, err error) bool {
+		assert.NoError(err)
+		assert.Equal("user", obj.GetType())
+
+		ids = append(ids, obj.GetId())
+
+		return true
+	})
+
+	assert.Equal([]string{"0", "1", "2", "3", "4", "5"}, ids)
+	assert.Equal([]string{"", "1", "2"}, reader.tokens)
+	assert.Nil(req.GetPage())
+}
+
+func TestIterRelationsStop(t *testing.T) {
+	assert := asserts.New(t)
+
+	reader := &pagedReader{pages: 3}
+	client := &Client{Reader: reader}
+
+	var ids []string
+
+	client.IterRelations(context.Background(), &drs.GetRelationsRequest{Relation: "member"})(
+		func(rel *dsc.Relation, err error) bool {
+			assert.NoError(err)
+			assert.Equal("member", rel.GetRelation())
+
+			ids = append(ids, rel.GetObjectId())
+
+			return len(ids) < 3
+		},
+	)
+
+	assert.Equal([]string{"0", "1", "2"}, ids)
+	assert.Equal([]string{"", "1"}, reader.tokens)
+}
+
+func TestIterError(t *testing.T) {
+	assert := asserts.New(t)
+
+	client := &Client{Reader: &pagedReader{pages: 3, failAt: 1}}
+
+	var (
+		count int
+		errs  []error
+	)
+
+	client.IterObjects(context.Background(), &drs.GetObjectsRequest{})(func(_ *dsc.Object, err error) bool {
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			count++
+		}
+
+		return true
+	})
+
+	assert.Equal(2, count)
+	assert.Len(errs, 1)
+	assert.Equal(codes.Unavailable, status.Code(errs[0]))
+}
+
+func TestIterCanceled(t *testing.T) {
+	assert := asserts.New(t)
+
+	reader := &pagedReader{pages: 3}
+	client := &Client{Reader: reader}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var errs []error
+
+	client.IterObjects(ctx, &drs.GetObjectsRequest{})(func(_ *dsc.Object, err error) bool {
+		errs = append(errs, err)
+		return true
+	})
+
+	assert.Equal([]error{context.Canceled}, errs)
+	assert.Empty(reader.tokens)
+}
@@ -0,0 +1,112 @@
+package aserto
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultEnvPrefix is the prefix of environment variables read by Config.FromEnv when no prefix is specified.
+const DefaultEnvPrefix = "ASERTO"
+
+// LoadConfigFromEnv returns a Config populated from environment variables.
+// See Config.FromEnv for details.
+func LoadConfigFromEnv(prefix string) (*Config, error) {
+	cfg := &Config{}
+	if err := cfg.FromEnv(prefix); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// FromEnv populates the Config from environment variables and validates the result.
+//
+// Variable names are formed by joining the prefix (default: "ASERTO") and the upper-cased JSON name of each field
+// with an underscore. For example, ASERTO_ADDRESS, ASERTO_API_KEY, ASERTO_TENANT_ID, and ASERTO_INSECURE.
+// Boolean variables accept the values understood by strconv.ParseBool. CA_CERT_PATHS is a comma-separated list.
+// Headers and the deprecated TimeoutInSeconds can't be set from the environment.
+//
+// Fields are only overwritten by variables that are set. Fields with no corresponding variable keep their values.
+func (cfg *Config) FromEnv(prefix string) error {
+	if prefix == "" {
+		prefix = DefaultEnvPrefix
+	}
+
+	env := envReader{prefix: prefix}
+
+	env.string("ADDRESS", &cfg.Address)
+	env.string("TOKEN", &cfg.Token)
+	env.string("API_KEY", &cfg.APIKey)
+	env.string("TENANT_ID", &cfg.TenantID)
+	env.string("ACCOUNT_ID", &cfg.AccountID)
+	env.string("CLIENT_CERT_PATH", &cfg.ClientCertPath)
+	env.string("CLIENT_KEY_PATH", &cfg.ClientKeyPath)
+	env.string("CA_CERT_PATH", &cfg.CACertPath)
+	env.list("CA_CERT_PATHS", &cfg.CACertPaths)
+	env.string("CA_CERT_DIR", &cfg.CACertDir)
+	env.bool("NO_SYSTEM_CERT_POOL", &cfg.NoSystemCertPool)
+	env.bool("INSECURE", &cfg.Insecure)
+	env.bool("NO_TLS", &cfg.NoTLS)
+	env.bool("NO_PROXY", &cfg.NoProxy)
+	env.string("USER_AGENT", &cfg.UserAgent)
+
+	if env.err != nil {
+		return env.err
+	}
+
+	return cfg.validate()
+}
+
+// envReader reads prefixed environment variables. It records the first parsing error.
+type envReader struct {
+	prefix string
+	err    error
+}
+
+func (e *envReader) lookup(name string) (string, string, bool) {
+	key := e.prefix + "_" + name
+	value, ok := os.LookupEnv(key)
+
+	return key, value, ok
+}
+
+func (e *envReader) string(name string, dst *string) {
+	if _, value, ok := e.lookup(name); ok {
+		*dst = value
+	}
+}
+
+func (e *envReader) list(name string, dst *[]string) {
+	_, value, ok := e.lookup(name)
+	if !ok {
+		return
+	}
+
+	var items []string
+
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	*dst = items
+}
+
+func (e *envReader) bool(name string, dst *bool) {
+	key, value, ok := e.lookup(name)
+	if !ok || e.err != nil {
+		return
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		e.err = errors.Wrapf(ErrInvalidConfig, "%s: invalid boolean %q", key, value)
+		return
+	}
+
+	*dst = b
+}
@@ -0,0 +1,57 @@
+package aserto_test
+
+import (
+	"testing"
+
+	"github.com/aserto-dev/go-aserto"
+	assrt "github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	assert := assrt.New(t)
+
+	t.Setenv("ASERTO_ADDRESS", "localhost:8282")
+	t.Setenv("ASERTO_API_KEY", "key")
+	t.Setenv("ASERTO_TENANT_ID", "tenant")
+	t.Setenv("ASERTO_INSECURE", "true")
+	t.Setenv("ASERTO_CA_CERT_PATHS", "a.pem, b.pem")
+
+	cfg, err := aserto.LoadConfigFromEnv("")
+	assert.NoError(err)
+
+	assert.Equal(&aserto.Config{
+		Address:     "localhost:8282",
+		APIKey:      "key",
+		TenantID:    "tenant",
+		Insecure:    true,
+		CACertPaths: []string{"a.pem", "b.pem"},
+	}, cfg)
+}
+
+func TestConfigFromEnvPrecedence(t *testing.T) {
+	assert := assrt.New(t)
+
+	t.Setenv("MYAPP_ADDRESS", "from-env:8282")
+
+	cfg := &aserto.Config{Address: "from-config:8282", TenantID: "tenant"}
+	assert.NoError(cfg.FromEnv("MYAPP"))
+
+	assert.Equal("from-env:8282", cfg.Address)
+	assert.Equal("tenant", cfg.TenantID)
+}
+
+func TestConfigFromEnvErrors(t *testing.T) {
+	assert := assrt.New(t)
+
+	t.Setenv("ASERTO_INSECURE", "maybe")
+
+	_, err := aserto.LoadConfigFromEnv("")
+	assert.ErrorIs(err, aserto.ErrInvalidConfig)
+
+	t.Setenv("ASERTO_INSECURE", "1")
+	t.Setenv("ASERTO_NO_TLS", "1")
+
+	_, err = aserto.LoadConfigFromEnv("")
+	assert.ErrorIs(err, aserto.ErrInvalidConfig)
+	assert.ErrorContains(err, "insecure and no_tls are mutually exclusive")
+}
@@ -3,6 +3,8 @@ package client
 import (
 	"context"
 	"strings"
+
+	"github.com/pkg/errors"
 )
 
 // TokenAuth bearer token based authentication.
@@ -35,6 +37,32 @@ func (TokenAuth) RequireTransportSecurity() bool {
 	return false
 }
 
+// TokenProviderAuth bearer token based authentication with tokens obtained on each call.
+//
+// It implements the interface credentials.PerRPCCredentials.
+type TokenProviderAuth struct {
+	provider func(context.Context) (string, error)
+}
+
+func NewTokenProviderAuth(provider func(context.Context) (string, error)) *TokenProviderAuth {
+	return &TokenProviderAuth{
+		provider: provider,
+	}
+}
+
+func (t *TokenProviderAuth) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	token, err := t.provider(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get token")
+	}
+
+	return NewTokenAuth(token).GetRequestMetadata(ctx, uri...)
+}
+
+func (*TokenProviderAuth) RequireTransportSecurity() bool {
+	return false
+}
+
 // APIKeyAuth API key based authentication.
 //
 // It implements the interface credentials.PerRPCCredentials.
@@ -4,8 +4,10 @@ import (
 	"crypto/x509"
 )
 
-func CertPool(caCertPath string) (*x509.CertPool, error) {
-	if caCertPath == "" {
+// CertPool returns the pool that trusted CA certificates are added to.
+// If hasCA is false or systemRoots is true, the pool holds the system roots.
+func CertPool(hasCA, systemRoots bool) (*x509.CertPool, error) {
+	if !hasCA || systemRoots {
 		return x509.SystemCertPool()
 	}
 
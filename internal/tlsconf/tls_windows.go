@@ -4,12 +4,19 @@ import (
 	"crypto/x509"
 )
 
-func CertPool(caCertPath string) (*x509.CertPool, error) {
+// CertPool returns the pool that trusted CA certificates are added to.
+// If hasCA is false, the returned pool is nil and the system roots are used.
+// Otherwise, if systemRoots is true, the returned pool holds the system roots.
+func CertPool(hasCA, systemRoots bool) (*x509.CertPool, error) {
 	var certPool *x509.CertPool
 
-	if caCertPath == "" {
+	if !hasCA {
 		return certPool, nil
 	}
 
+	if systemRoots {
+		return x509.SystemCertPool()
+	}
+
 	return x509.NewCertPool(), nil
 }
@@ -0,0 +1,77 @@
+package aserto
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// LoadConfig reads a Config from a JSON, YAML, or TOML file and validates it.
+//
+// The format is determined by the file's extension: .json, .yaml, .yml, or .toml. In all formats, keys are the JSON
+// names of the Config fields (e.g. "api_key").
+// References to environment variables in string values, such as "${ASERTO_API_KEY}", are replaced with the variables'
+// values. References to undefined variables are replaced with empty strings.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read config file")
+	}
+
+	var values map[string]interface{}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(data, &values)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return nil, errors.Wrapf(ErrInvalidConfig, "unsupported config file extension %q", ext)
+	}
+
+	if err != nil {
+		return nil, errors.Wrapf(ErrInvalidConfig, "failed to parse %s: %s", path, err)
+	}
+
+	// Round-trip through JSON so that all formats use the same field names.
+	data, err = json.Marshal(expandEnv(values))
+	if err != nil {
+		return nil, errors.Wrapf(ErrInvalidConfig, "failed to parse %s: %s", path, err)
+	}
+
+	cfg := &Config{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, errors.Wrapf(ErrInvalidConfig, "failed to parse %s: %s", path, err)
+	}
+
+	if err := cfg.validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// expandEnv replaces references to environment variables in all string values.
+func expandEnv(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return os.ExpandEnv(v)
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = expandEnv(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = expandEnv(item)
+		}
+	}
+
+	return value
+}
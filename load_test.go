@@ -0,0 +1,81 @@
+package aserto_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto"
+	assrt "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("TEST_API_KEY", "secret")
+
+	expected := &aserto.Config{
+		Address:     "localhost:8282",
+		APIKey:      "secret",
+		Insecure:    true,
+		CACertPaths: []string{"a.pem", "b.pem"},
+		Headers:     map[string]string{"x-env": "secret"},
+	}
+
+	for name, content := range map[string]string{
+		"config.json": `{
+			"address": "localhost:8282",
+			"api_key": "${TEST_API_KEY}",
+			"insecure": true,
+			"ca_cert_paths": ["a.pem", "b.pem"],
+			"headers": {"x-env": "$TEST_API_KEY"}
+		}`,
+		"config.yaml": `
+address: localhost:8282
+api_key: ${TEST_API_KEY}
+insecure: true
+ca_cert_paths: [a.pem, b.pem]
+headers:
+  x-env: $TEST_API_KEY
+`,
+		"config.toml": `
+address = "localhost:8282"
+api_key = "${TEST_API_KEY}"
+insecure = true
+ca_cert_paths = ["a.pem", "b.pem"]
+
+[headers]
+x-env = "$TEST_API_KEY"
+`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := aserto.LoadConfig(writeConfig(t, name, content))
+			require.NoError(t, err)
+			assrt.Equal(t, expected, cfg)
+		})
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	for name, content := range map[string]string{
+		"config.ini":  `address = localhost:8282`,
+		"config.json": `{"address": `,
+		"config.yml":  "api_key: key\ntoken: token\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := aserto.LoadConfig(writeConfig(t, name, content))
+			assrt.ErrorIs(t, err, aserto.ErrInvalidConfig)
+		})
+	}
+
+	_, err := aserto.LoadConfig(filepath.Join(t.TempDir(), "missing.json"))
+	assrt.Error(t, err)
+}
@@ -0,0 +1,131 @@
+package aserto
+
+import (
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// WithRPCLogging logs every call made over the connection with its method, duration, and status code.
+//
+// Successful calls are logged at info level and failed calls at error level. Streaming calls are logged when the
+// stream fails to be established, when the response of a client-streaming call is received, when a server stream
+// ends, or when the caller cancels the call's context before the stream ends.
+func WithRPCLogging(logger *zerolog.Logger) ConnectionOption {
+	return func(options *ConnectionOptions) error {
+		if logger == nil {
+			return errors.Wrap(ErrInvalidOptions, "rpc logger must not be nil")
+		}
+
+		l := &rpcLogger{logger: logger}
+
+		options.UnaryClientInterceptors = append(options.UnaryClientInterceptors, l.unaryInterceptor)
+		options.StreamClientInterceptors = append(options.StreamClientInterceptors, l.streamInterceptor)
+
+		return nil
+	}
+}
+
+type rpcLogger struct {
+	logger *zerolog.Logger
+}
+
+func (l *rpcLogger) unaryInterceptor(
+	ctx context.Context,
+	method string,
+	req, reply interface{},
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	l.log(method, start, err)
+
+	return err
+}
+
+func (l *rpcLogger) streamInterceptor(
+	ctx context.Context,
+	desc *grpc.StreamDesc,
+	cc *grpc.ClientConn,
+	method string,
+	streamer grpc.Streamer,
+	opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	start := time.Now()
+
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		l.log(method, start, err)
+		return nil, err
+	}
+
+	logged := &loggedStream{
+		ClientStream:  stream,
+		serverStreams: desc.ServerStreams,
+		done:          func(err error) { l.log(method, start, err) },
+	}
+
+	go logged.watch(ctx)
+
+	return logged, nil
+}
+
+func (l *rpcLogger) log(method string, start time.Time, err error) {
+	event := l.logger.Info()
+	if err != nil {
+		event = l.logger.Error().Err(err)
+	}
+
+	event.
+		Str("method", method).
+		Dur("duration", time.Since(start)).
+		Str("code", status.Code(err).String()).
+		Msg("grpc call")
+}
+
+// loggedStream reports the outcome of a client stream once it ends.
+type loggedStream struct {
+	grpc.ClientStream
+
+	serverStreams bool
+	done          func(error)
+	once          sync.Once
+}
+
+func (s *loggedStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+
+	switch {
+	case errors.Is(err, io.EOF):
+		s.end(nil)
+	case err != nil:
+		s.end(err)
+	case !s.serverStreams:
+		// The single response of a client-streaming call has been received.
+		s.end(nil)
+	}
+
+	return err
+}
+
+// watch reports streams that are abandoned by canceling the caller's context before they end.
+// It returns once the stream is done.
+func (s *loggedStream) watch(ctx context.Context) {
+	<-s.ClientStream.Context().Done()
+
+	if err := ctx.Err(); err != nil {
+		s.end(status.FromContextError(err).Err())
+	}
+}
+
+func (s *loggedStream) end(err error) {
+	s.once.Do(func() { s.done(err) })
+}
@@ -0,0 +1,204 @@
+package aserto_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aserto-dev/go-aserto"
+	"github.com/rs/zerolog"
+	assrt "github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// logBuffer collects log entries. Streams can be logged from other goroutines, so access is synchronized.
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+// entries returns the entries logged since the last call.
+func (b *logBuffer) entries(t *testing.T) []map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var entries []map[string]interface{}
+
+	dec := json.NewDecoder(&b.buf)
+	for dec.More() {
+		entry := map[string]interface{}{}
+		assrt.NoError(t, dec.Decode(&entry))
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+func loggingInterceptors(t *testing.T, buf *logBuffer) (grpc.UnaryClientInterceptor, grpc.StreamClientInterceptor) {
+	logger := zerolog.New(buf)
+
+	options, err := aserto.NewConnectionOptions(aserto.WithRPCLogging(&logger))
+	assrt.NoError(t, err)
+	assrt.Len(t, options.UnaryClientInterceptors, 1)
+	assrt.Len(t, options.StreamClientInterceptors, 1)
+
+	return options.UnaryClientInterceptors[0], options.StreamClientInterceptors[0]
+}
+
+func TestRPCLoggingNilLogger(t *testing.T) {
+	_, err := aserto.NewConnectionOptions(aserto.WithRPCLogging(nil))
+	assrt.ErrorIs(t, err, aserto.ErrInvalidOptions)
+}
+
+func TestRPCLoggingUnary(t *testing.T) {
+	assert := assrt.New(t)
+
+	var buf logBuffer
+
+	unary, _ := loggingInterceptors(t, &buf)
+
+	calls := 0
+	assert.NoError(unary(context.Background(), "/svc/Is", nil, nil, nil, failingInvoker(&calls, 0, codes.OK)))
+	assert.Error(unary(context.Background(), "/svc/Query", nil, nil, nil, failingInvoker(&calls, 5, codes.Unavailable)))
+
+	entries := buf.entries(t)
+	assert.Len(entries, 2)
+
+	assert.Equal("info", entries[0]["level"])
+	assert.Equal("/svc/Is", entries[0]["method"])
+	assert.Equal("OK", entries[0]["code"])
+	assert.Contains(entries[0], "duration")
+
+	assert.Equal("error", entries[1]["level"])
+	assert.Equal("/svc/Query", entries[1]["method"])
+	assert.Equal("Unavailable", entries[1]["code"])
+	assert.Contains(entries[1], "error")
+}
+
+// scriptedStream is a client stream whose RecvMsg returns the given errors in order.
+// Its context is done when the test ends.
+type scriptedStream struct {
+	grpc.ClientStream
+
+	ctx  context.Context
+	recv []error
+}
+
+func (s *scriptedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *scriptedStream) RecvMsg(interface{}) error {
+	err := s.recv[0]
+	s.recv = s.recv[1:]
+
+	return err
+}
+
+func scriptedStreamer(t *testing.T, recv ...error) grpc.Streamer {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	return func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &scriptedStream{ctx: ctx, recv: recv}, nil
+	}
+}
+
+func TestRPCLoggingServerStream(t *testing.T) {
+	assert := assrt.New(t)
+
+	var buf logBuffer
+
+	_, stream := loggingInterceptors(t, &buf)
+
+	desc := &grpc.StreamDesc{ServerStreams: true}
+
+	cs, err := stream(context.Background(), desc, nil, "/svc/List", scriptedStreamer(t, nil, io.EOF, io.EOF))
+	assert.NoError(err)
+
+	assert.NoError(cs.RecvMsg(nil))
+	assert.Empty(buf.entries(t))
+
+	assert.ErrorIs(cs.RecvMsg(nil), io.EOF)
+	assert.ErrorIs(cs.RecvMsg(nil), io.EOF)
+
+	entries := buf.entries(t)
+	assert.Len(entries, 1)
+	assert.Equal("/svc/List", entries[0]["method"])
+	assert.Equal("OK", entries[0]["code"])
+
+	failing := func(context.Context, *grpc.StreamDesc, *grpc.ClientConn, string, ...grpc.CallOption) (grpc.ClientStream, error) {
+		return nil, status.Error(codes.Unauthenticated, "no token")
+	}
+
+	_, err = stream(context.Background(), desc, nil, "/svc/List", failing)
+	assert.Error(err)
+
+	entries = buf.entries(t)
+	assert.Len(entries, 1)
+	assert.Equal("error", entries[0]["level"])
+	assert.Equal("Unauthenticated", entries[0]["code"])
+}
+
+func TestRPCLoggingClientStream(t *testing.T) {
+	assert := assrt.New(t)
+
+	var buf logBuffer
+
+	_, stream := loggingInterceptors(t, &buf)
+
+	cs, err := stream(context.Background(), &grpc.StreamDesc{ClientStreams: true}, nil, "/svc/Upload", scriptedStreamer(t, nil))
+	assert.NoError(err)
+
+	assert.NoError(cs.RecvMsg(nil))
+
+	entries := buf.entries(t)
+	assert.Len(entries, 1)
+	assert.Equal("/svc/Upload", entries[0]["method"])
+	assert.Equal("OK", entries[0]["code"])
+}
+
+func TestRPCLoggingAbandonedStream(t *testing.T) {
+	assert := assrt.New(t)
+
+	var buf logBuffer
+
+	_, stream := loggingInterceptors(t, &buf)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	streamer := func(ctx context.Context, _ *grpc.StreamDesc, _ *grpc.ClientConn, _ string, _ ...grpc.CallOption) (grpc.ClientStream, error) {
+		return &scriptedStream{ctx: ctx, recv: []error{nil}}, nil
+	}
+
+	cs, err := stream(ctx, &grpc.StreamDesc{ServerStreams: true}, nil, "/svc/List", streamer)
+	assert.NoError(err)
+	assert.NoError(cs.RecvMsg(nil))
+
+	cancel()
+
+	var entries []map[string]interface{}
+
+	assert.Eventually(func() bool {
+		entries = append(entries, buf.entries(t)...)
+		return len(entries) > 0
+	}, time.Second, 5*time.Millisecond)
+
+	assert.Len(entries, 1)
+	assert.Equal("/svc/List", entries[0]["method"])
+	assert.Equal("Canceled", entries[0]["code"])
+}
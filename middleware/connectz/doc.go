@@ -0,0 +1,4 @@
+/*
+Package connectz provides authorization components for servers built with connect-go (connectrpc.com/connect).
+*/
+package connectz
@@ -0,0 +1,75 @@
+package connectz_test
+
+import (
+	"context"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/aserto-dev/go-aserto/middleware/connectz"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const username = "george"
+
+type user struct{}
+
+func request(headers map[string]string) connect.AnyRequest {
+	req := connect.NewRequest(&emptypb.Empty{})
+	for k, v := range headers {
+		req.Header().Set(k, v)
+	}
+
+	return req
+}
+
+func TestIdentityFromHeader(t *testing.T) {
+	builder := (&connectz.IdentityBuilder{}).JWT().FromHeader("X-Identity", "Authorization")
+
+	identity := builder.Build(context.TODO(), request(map[string]string{"Authorization": "Bearer " + username}))
+
+	assert.Equal(t, api.IdentityType_IDENTITY_TYPE_JWT, identity.GetType())
+	assert.Equal(t, username, identity.GetIdentity())
+}
+
+func TestIdentityFromMissingHeader(t *testing.T) {
+	builder := (&connectz.IdentityBuilder{}).Subject().FromHeader("Authorization")
+
+	identity := builder.Build(context.TODO(), request(nil))
+
+	assert.Equal(t, api.IdentityType_IDENTITY_TYPE_NONE, identity.GetType())
+}
+
+func TestSubjectFromHeaderToken(t *testing.T) {
+	token, err := jwt.NewBuilder().Subject(username).Build()
+	assert.NoError(t, err)
+
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.HS256, []byte("secret")))
+	assert.NoError(t, err)
+
+	builder := (&connectz.IdentityBuilder{}).Subject().FromHeader("Authorization")
+
+	identity := builder.Build(context.TODO(), request(map[string]string{"Authorization": "Bearer " + string(signed)}))
+
+	assert.Equal(t, api.IdentityType_IDENTITY_TYPE_SUB, identity.GetType())
+	assert.Equal(t, username, identity.GetIdentity())
+}
+
+func TestIdentityFromContextValue(t *testing.T) {
+	builder := (&connectz.IdentityBuilder{}).Subject().FromContextValue(user{})
+
+	identity := builder.Build(context.WithValue(context.TODO(), user{}, username), request(nil))
+
+	assert.Equal(t, api.IdentityType_IDENTITY_TYPE_SUB, identity.GetType())
+	assert.Equal(t, username, identity.GetIdentity())
+}
+
+func TestManualIdentity(t *testing.T) {
+	identity := (&connectz.IdentityBuilder{}).Manual().ID("object_id").Build(context.TODO(), request(nil))
+
+	assert.Equal(t, api.IdentityType_IDENTITY_TYPE_MANUAL, identity.GetType())
+	assert.Equal(t, "object_id", identity.GetIdentity())
+}
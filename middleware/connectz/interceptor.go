@@ -0,0 +1,385 @@
+package connectz
+
+import (
+	"context"
+	"fmt"
+
+	"connectrpc.com/connect"
+	"github.com/aserto-dev/go-aserto/middleware"
+	"github.com/aserto-dev/go-aserto/middleware/internal"
+	"github.com/aserto-dev/go-aserto/middleware/internal/pbutil"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/aserto-dev/go-authorizer/pkg/aerr"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type (
+	Policy           = middleware.Policy
+	AuthorizerClient = authz.AuthorizerClient
+)
+
+/*
+Middleware implements a connect.Interceptor that authorizes incoming unary and streaming calls.
+
+To authorize incoming calls, the middleware needs information about:
+
+1. The user making the request.
+
+2. The Aserto authorization policy to evaluate.
+
+3. Optional, additional input data to the authorization policy.
+
+The values for these parameters can be set globally or extracted dynamically from incoming requests.
+
+Streaming calls are authorized once, when the stream is opened, before any messages are received. Mappers called
+for streaming calls receive a request that carries the stream's headers and has no message.
+*/
+type Middleware struct {
+	// Identity determines the caller identity used in authorization calls.
+	Identity *IdentityBuilder
+
+	client            AuthorizerClient
+	policy            *Policy
+	policyMapper      StringMapper
+	resourceMappers   []ResourceMapper
+	omitEmptyResource bool
+	manualIdentity    string
+	allowedProcedures internal.Lookup[string]
+	decision          string
+}
+
+var _ connect.Interceptor = (*Middleware)(nil)
+
+type (
+	// StringMapper functions are used to extract string values from incoming requests.
+	// They are used to define policy path mappers.
+	StringMapper func(context.Context, connect.AnyRequest) string
+
+	// ResourceMapper functions are used to extract structured data from incoming requests.
+	ResourceMapper func(context.Context, connect.AnyRequest, map[string]interface{})
+)
+
+type procedureKey struct{}
+
+// New creates middleware for the specified policy.
+//
+// The new middleware is created with default identity and policy path mapper.
+// Those can be overridden using `Middleware.Identity` to specify the caller's identity, or using
+// the middleware's ".With...()" functions to set policy path and resource mappers.
+func New(authzClient AuthorizerClient, policy *Policy) *Middleware {
+	policyMapper := procedurePolicyMapper("")
+	if policy.Path != "" {
+		policyMapper = nil
+	}
+
+	return &Middleware{
+		Identity:        (&IdentityBuilder{}).FromHeader("Authorization").byDefault(),
+		client:          authzClient,
+		policy:          policy,
+		policyMapper:    policyMapper,
+		resourceMappers: []ResourceMapper{},
+	}
+}
+
+// WithAllowedProcedures takes a list of procedures that are allowed to proceed without authorization.
+// Procedures are in the format "/package.Service/Method".
+func (m *Middleware) WithAllowedProcedures(procedures ...string) *Middleware {
+	m.allowedProcedures = internal.NewLookup(procedures...)
+	return m
+}
+
+// WithDecision selects the decision used to authorize requests by name, overriding the policy's Decision.
+//
+// The authorizer's response may include additional decisions. The outcome is that of the decision whose name
+// matches. Responses without a matching decision fail.
+func (m *Middleware) WithDecision(name string) *Middleware {
+	m.decision = name
+	return m
+}
+
+// WithPolicyPathMapper takes a custom StringMapper for extracting the authorization policy path from
+// incoming requests.
+func (m *Middleware) WithPolicyPathMapper(mapper StringMapper) *Middleware {
+	m.policyMapper = mapper
+	return m
+}
+
+// WithPolicyFromProcedure derives the policy path from the called procedure, prefixed with the given policy root.
+// For example, with the root "myapp", calls to "/example.v1.UserService/GetUser" are authorized using the policy
+// path "myapp.example.v1.UserService.GetUser".
+func (m *Middleware) WithPolicyFromProcedure(root string) *Middleware {
+	m.policyMapper = procedurePolicyMapper(root)
+	return m
+}
+
+/*
+WithResourceFromFields instructs the middleware to select the specified fields from incoming messages and
+use them as the resource in authorization calls. Fields are expressed as a field mask.
+
+Note: Protobuf message fields are identified using their JSON names.
+
+Example:
+
+	middleware.WithResourceFromFields("product.type", "address")
+
+This call would result in an authorization resource with the following structure:
+
+	  {
+		  "product": {
+			  "type": <value from message>
+		  },
+		  "address": <value from message>
+	  }
+
+If the value of "address" is itself a message, all of its fields are included.
+*/
+func (m *Middleware) WithResourceFromFields(fields ...string) *Middleware {
+	m.resourceMappers = append(m.resourceMappers, messageResourceMapper(map[string][]string{}, fields...))
+	return m
+}
+
+// WithResourceFromMessageByProcedure behaves similarly to WithResourceFromFields but allows specifying different
+// sets of fields for different procedures. Procedures that aren't in fieldsByProcedure use the default fields.
+func (m *Middleware) WithResourceFromMessageByProcedure(
+	fieldsByProcedure map[string][]string,
+	defaults ...string,
+) *Middleware {
+	m.resourceMappers = append(m.resourceMappers, messageResourceMapper(fieldsByProcedure, defaults...))
+	return m
+}
+
+// WithResourceFromContextValue instructs the middleware to read the specified value from the incoming request
+// context and add it to the authorization resource context under the given field.
+func (m *Middleware) WithResourceFromContextValue(ctxKey interface{}, field string) *Middleware {
+	m.resourceMappers = append(m.resourceMappers, func(ctx context.Context, _ connect.AnyRequest, res map[string]interface{}) {
+		if v := ctx.Value(ctxKey); v != nil {
+			res[field] = v
+		}
+	})
+
+	return m
+}
+
+// WithResourceMapper takes a custom ResourceMapper for extracting the authorization resource context from
+// incoming requests.
+func (m *Middleware) WithResourceMapper(mapper ResourceMapper) *Middleware {
+	m.resourceMappers = append(m.resourceMappers, mapper)
+	return m
+}
+
+// WithoutEmptyResource sends authorization calls without a resource context when resource mappers don't produce any
+// fields, instead of sending an empty resource. This lets policies distinguish "no resource" from "empty resource".
+func (m *Middleware) WithoutEmptyResource() *Middleware {
+	m.omitEmptyResource = true
+	return m
+}
+
+// WithResourceFallback adds the fields produced by the primary ResourceMapper to the resource context. If primary
+// produces no fields, the fields produced by fallback are added instead.
+func (m *Middleware) WithResourceFallback(primary, fallback ResourceMapper) *Middleware {
+	return m.WithResourceMapper(func(ctx context.Context, req connect.AnyRequest, resource map[string]interface{}) {
+		fields := map[string]interface{}{}
+
+		primary(ctx, req, fields)
+
+		if len(fields) == 0 {
+			fallback(ctx, req, fields)
+		}
+
+		for k, v := range fields {
+			resource[k] = v
+		}
+	})
+}
+
+// WithManualIdentityInResource adds the caller's identity to the resource context under the given field when the
+// identity type is manual. Manual identities are passed to policies as-is, so this lets policies that don't read
+// input.identity access them.
+func (m *Middleware) WithManualIdentityInResource(field string) *Middleware {
+	m.manualIdentity = field
+
+	return m
+}
+
+// Unary returns a connect.UnaryInterceptorFunc that authorizes incoming unary calls.
+// Use the middleware itself as a connect.Interceptor to authorize streaming calls too.
+func (m *Middleware) Unary() connect.UnaryInterceptorFunc {
+	return m.WrapUnary
+}
+
+// WrapUnary implements connect.Interceptor. Client calls pass through unchanged.
+func (m *Middleware) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if req.Spec().IsClient {
+			return next(ctx, req)
+		}
+
+		ctx, err := m.authorize(ctx, req.Spec().Procedure, req)
+		if err != nil {
+			return nil, err
+		}
+
+		return next(ctx, req)
+	}
+}
+
+// WrapStreamingClient implements connect.Interceptor. Client streams pass through unchanged.
+func (m *Middleware) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler implements connect.Interceptor. Streams are authorized when they are opened.
+func (m *Middleware) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		ctx, err := m.authorize(ctx, conn.Spec().Procedure, streamRequest(conn))
+		if err != nil {
+			return err
+		}
+
+		return next(ctx, conn)
+	}
+}
+
+// authorize returns a copy of ctx that carries the caller's identity if the request is allowed.
+func (m *Middleware) authorize(ctx context.Context, procedure string, req connect.AnyRequest) (context.Context, error) {
+	if m.allowedProcedures.Contains(procedure) {
+		return ctx, nil
+	}
+
+	ctx = context.WithValue(ctx, procedureKey{}, procedure)
+
+	policyContext := internal.DefaultPolicyContext(m.policy)
+	if m.decision != "" {
+		policyContext.Decisions = []string{m.decision}
+	}
+
+	if m.policyMapper != nil {
+		policyContext.Path = m.policyMapper(ctx, req)
+	}
+
+	identity, err := m.Identity.resolve(ctx, req)
+	if err != nil {
+		return ctx, connect.NewError(connect.CodeInternal, fmt.Errorf("failed to resolve identity: %w", err))
+	}
+
+	resource, err := m.resourceContext(ctx, req, identity)
+	if err != nil {
+		return ctx, connect.NewError(connect.CodeInvalidArgument, fmt.Errorf("failed to apply resource mapper: %w", err))
+	}
+
+	resp, err := m.client.Is(ctx, &authz.IsRequest{
+		IdentityContext: identity,
+		PolicyContext:   policyContext,
+		ResourceContext: resource,
+		PolicyInstance:  internal.DefaultPolicyInstance(m.policy),
+	})
+	if err != nil {
+		return ctx, connect.NewError(connect.Code(status.Code(err)), fmt.Errorf("authorization call failed: %w", err))
+	}
+
+	allowed, err := m.outcome(resp)
+	if err != nil {
+		return ctx, connect.NewError(connect.CodeInternal, err)
+	}
+
+	if !allowed {
+		return ctx, connect.NewError(connect.CodePermissionDenied, aerr.ErrAuthorizationFailed)
+	}
+
+	return middleware.WithIdentity(ctx, identity), nil
+}
+
+// outcome returns the outcome of the authorizer's response. Unless a decision is selected with WithDecision, the
+// first decision is used.
+func (m *Middleware) outcome(resp *authz.IsResponse) (bool, error) {
+	if m.decision != "" {
+		return internal.Outcome(resp, m.decision)
+	}
+
+	if len(resp.GetDecisions()) == 0 {
+		return false, aerr.ErrInvalidDecision
+	}
+
+	return resp.GetDecisions()[0].GetIs(), nil
+}
+
+func (m *Middleware) resourceContext(ctx context.Context, req connect.AnyRequest, identity *api.IdentityContext) (*structpb.Struct, error) {
+	res := map[string]interface{}{}
+	for _, mapper := range m.resourceMappers {
+		mapper(ctx, req, res)
+	}
+
+	if m.manualIdentity != "" {
+		internal.AddManualIdentity(identity, m.manualIdentity, res)
+	}
+
+	return internal.NewResource(res, m.omitEmptyResource)
+}
+
+// PolicyPathFor returns the policy path the middleware's default policy mapper derives from a procedure name
+// (e.g. "/package.Service/Method"), optionally prefixed with a policy root.
+func PolicyPathFor(procedure, root string) string {
+	path := internal.ToPolicyPath(procedure)
+
+	if root == "" {
+		return path
+	}
+
+	return fmt.Sprintf("%s.%s", root, path)
+}
+
+func procedurePolicyMapper(root string) StringMapper {
+	return func(ctx context.Context, _ connect.AnyRequest) string {
+		return PolicyPathFor(procedureFromContext(ctx), root)
+	}
+}
+
+func messageResourceMapper(fieldsByProcedure map[string][]string, defaults ...string) ResourceMapper {
+	return func(ctx context.Context, req connect.AnyRequest, res map[string]interface{}) {
+		fields, ok := fieldsByProcedure[procedureFromContext(ctx)]
+		if !ok || len(fields) == 0 {
+			fields = defaults
+		}
+
+		msg := message(req)
+		if len(fields) == 0 || msg == nil {
+			return
+		}
+
+		resource, _ := pbutil.Select(msg, fields...)
+		for k, v := range resource.AsMap() {
+			res[k] = v
+		}
+	}
+}
+
+// procedureFromContext returns the procedure being authorized. Unlike req.Spec(), it's also set for streaming calls.
+func procedureFromContext(ctx context.Context) string {
+	procedure, _ := ctx.Value(procedureKey{}).(string)
+	return procedure
+}
+
+// message returns the protobuf message of a unary request or nil if the request has none.
+func message(req connect.AnyRequest) proto.Message {
+	msg, ok := req.Any().(proto.Message)
+	if !ok || !msg.ProtoReflect().IsValid() {
+		return nil
+	}
+
+	return msg
+}
+
+// streamRequest returns a request without a message that carries the headers of a stream, for use by identity
+// builders and mappers.
+func streamRequest(conn connect.StreamingHandlerConn) connect.AnyRequest {
+	req := connect.NewRequest[emptypb.Empty](nil)
+	for k, v := range conn.RequestHeader() {
+		req.Header()[k] = v
+	}
+
+	return req
+}
@@ -0,0 +1,134 @@
+package connectz_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/aserto-dev/go-aserto/middleware"
+	"github.com/aserto-dev/go-aserto/middleware/connectz"
+	"github.com/aserto-dev/go-aserto/middleware/internal/test"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	unaryProcedure  = "/example.v1.Service/Get"
+	streamProcedure = "/example.v1.Service/List"
+)
+
+// serve starts a server with a unary and a server-streaming procedure that use the given interceptor.
+// Both procedures echo the identity added to the context by the middleware.
+func serve(t *testing.T, mw *connectz.Middleware) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+
+	mux.Handle(unaryProcedure, connect.NewUnaryHandler(
+		unaryProcedure,
+		func(ctx context.Context, _ *connect.Request[api.IdentityContext]) (*connect.Response[api.IdentityContext], error) {
+			return connect.NewResponse(middleware.IdentityFromContext(ctx)), nil
+		},
+		connect.WithInterceptors(mw),
+	))
+
+	mux.Handle(streamProcedure, connect.NewServerStreamHandler(
+		streamProcedure,
+		func(ctx context.Context, _ *connect.Request[emptypb.Empty], stream *connect.ServerStream[api.IdentityContext]) error {
+			return stream.Send(middleware.IdentityFromContext(ctx))
+		},
+		connect.WithInterceptors(mw),
+	))
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestUnary(t *testing.T) {
+	resource, err := structpb.NewStruct(map[string]interface{}{"identity": "alice"})
+	assert.NoError(t, err)
+
+	for _, tc := range []struct {
+		name   string
+		reject bool
+		code   connect.Code
+	}{
+		{"allowed", false, 0},
+		{"denied", true, connect.CodePermissionDenied},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			base := test.NewTest(t, tc.name, &test.Options{
+				ExpectedRequest: test.Request(
+					test.PolicyPath("myapp.example.v1.Service.Get"),
+					test.Resource(resource),
+				),
+				Reject: tc.reject,
+			})
+
+			mw := connectz.New(base.Client, test.Policy("")).
+				WithPolicyFromProcedure("myapp").
+				WithResourceFromFields("identity")
+			mw.Identity.Subject()
+
+			server := serve(t, mw)
+
+			client := connect.NewClient[api.IdentityContext, api.IdentityContext](server.Client(), server.URL+unaryProcedure)
+
+			req := connect.NewRequest(&api.IdentityContext{Identity: "alice"})
+			req.Header().Set("Authorization", test.DefaultUsername)
+
+			resp, err := client.CallUnary(context.Background(), req)
+			if tc.reject {
+				assert.Equal(t, tc.code, connect.CodeOf(err))
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.DefaultUsername, resp.Msg.GetIdentity())
+		})
+	}
+}
+
+func TestStream(t *testing.T) {
+	base := test.NewTest(t, "stream", &test.Options{
+		ExpectedRequest: test.Request(test.PolicyPath("example.v1.Service.List")),
+	})
+
+	mw := connectz.New(base.Client, test.Policy("")).WithResourceFromFields("identity")
+	mw.Identity.Subject()
+
+	server := serve(t, mw)
+
+	client := connect.NewClient[emptypb.Empty, api.IdentityContext](server.Client(), server.URL+streamProcedure)
+
+	req := connect.NewRequest(&emptypb.Empty{})
+	req.Header().Set("Authorization", test.DefaultUsername)
+
+	stream, err := client.CallServerStream(context.Background(), req)
+	assert.NoError(t, err)
+
+	defer stream.Close()
+
+	assert.True(t, stream.Receive())
+	assert.NoError(t, stream.Err())
+	assert.Equal(t, test.DefaultUsername, stream.Msg().GetIdentity())
+}
+
+func TestAllowedProcedures(t *testing.T) {
+	base := test.NewTest(t, "allowed", &test.Options{Reject: true})
+
+	mw := connectz.New(base.Client, test.Policy("")).WithAllowedProcedures(unaryProcedure)
+
+	server := serve(t, mw)
+
+	client := connect.NewClient[api.IdentityContext, api.IdentityContext](server.Client(), server.URL+unaryProcedure)
+
+	_, err := client.CallUnary(context.Background(), connect.NewRequest(&api.IdentityContext{}))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+)
+
+type identityKey struct{}
+
+// WithIdentity returns a copy of ctx that carries the caller's identity.
+//
+// Authorization middleware call WithIdentity on the contexts of incoming requests before passing them on to
+// downstream handlers.
+func WithIdentity(ctx context.Context, identity *api.IdentityContext) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the caller's identity stored in ctx by WithIdentity, or nil if there is none.
+func IdentityFromContext(ctx context.Context) *api.IdentityContext {
+	identity, _ := ctx.Value(identityKey{}).(*api.IdentityContext)
+	return identity
+}
@@ -0,0 +1,21 @@
+package middleware_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentityContext(t *testing.T) {
+	identity := &api.IdentityContext{Type: api.IdentityType_IDENTITY_TYPE_SUB, Identity: "alice"}
+
+	ctx := middleware.WithIdentity(context.Background(), identity)
+	assert.Same(t, identity, middleware.IdentityFromContext(ctx))
+}
+
+func TestNoIdentityContext(t *testing.T) {
+	assert.Nil(t, middleware.IdentityFromContext(context.Background()))
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+)
+
+// DecisionPostProcessor functions are called after the authorizer responds to an authorization call.
+// They receive the authorizer's decision and response and return the final decision.
+//
+// Returning an error fails the request.
+type DecisionPostProcessor func(ctx context.Context, allowed bool, resp *authz.IsResponse) (bool, error)
+
+// DecisionEvent describes an authorization decision made by middleware.
+type DecisionEvent struct {
+	// Identity is the caller's identity.
+	Identity *api.IdentityContext
+
+	// PolicyPath is the path of the evaluated policy module.
+	PolicyPath string
+
+	// Decision is the name of the evaluated decision.
+	Decision string
+
+	// Resource is the resource context sent to the authorizer.
+	Resource map[string]interface{}
+
+	// Allowed is true if the request is authorized.
+	Allowed bool
+
+	// Duration is the time it took to make the decision.
+	Duration time.Duration
+
+	// Err is the error that failed the authorization, if any. It is nil for denied requests.
+	Err error
+
+	// RequestID is the ID assigned to the request by the request ID middleware of httpz or grpcz, if any.
+	RequestID string
+}
+
+// DecisionLogger functions are called after every authorization call, regardless of its outcome.
+//
+// They can be used to send decisions to an audit pipeline. Decision loggers are called synchronously and should
+// not block.
+type DecisionLogger func(ctx context.Context, event DecisionEvent)
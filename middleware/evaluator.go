@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/pkg/errors"
+)
+
+// ErrNoEvaluators is returned by evaluators created with AllOf or AnyOf without any evaluators to combine.
+var ErrNoEvaluators = errors.New("no evaluators to combine")
+
+// Evaluator makes authorization decisions for incoming requests of type R (e.g. *http.Request).
+//
+// Middleware and check middleware in the httpz package implement Evaluator so they can be combined using AllOf and
+// AnyOf. Middleware for other frameworks don't implement it yet.
+type Evaluator[R any] interface {
+	// Evaluate returns true if the request is allowed.
+	Evaluate(ctx context.Context, req R) (bool, error)
+}
+
+// EvaluatorFunc adapts a function to the Evaluator interface.
+type EvaluatorFunc[R any] func(ctx context.Context, req R) (bool, error)
+
+// Evaluate calls f.
+func (f EvaluatorFunc[R]) Evaluate(ctx context.Context, req R) (bool, error) {
+	return f(ctx, req)
+}
+
+// AllOf returns an evaluator that allows a request only if all the given evaluators allow it.
+//
+// Evaluators run concurrently. As soon as one of them denies the request, the others are canceled and the request is
+// denied. If no evaluator denies the request and some of them fail, their errors are returned.
+// If no evaluators are given, evaluation fails with ErrNoEvaluators so an empty list never allows requests.
+//
+// The type parameter can't be inferred from the evaluators and must be specified. For example:
+//
+//	middleware.AllOf[*http.Request](mw, mw.Check(httpz.WithRelation("can_read")))
+func AllOf[R any](evaluators ...Evaluator[R]) Evaluator[R] {
+	return EvaluatorFunc[R](func(ctx context.Context, req R) (bool, error) {
+		return quantify(ctx, req, evaluators, false)
+	})
+}
+
+// AnyOf returns an evaluator that allows a request if any of the given evaluators allow it.
+//
+// Evaluators run concurrently. As soon as one of them allows the request, the others are canceled and the request is
+// allowed. If no evaluator allows the request and some of them fail, their errors are returned.
+// If no evaluators are given, evaluation fails with ErrNoEvaluators so an empty list never allows requests.
+func AnyOf[R any](evaluators ...Evaluator[R]) Evaluator[R] {
+	return EvaluatorFunc[R](func(ctx context.Context, req R) (bool, error) {
+		return quantify(ctx, req, evaluators, true)
+	})
+}
+
+// quantify evaluates the request concurrently using all the evaluators. It returns decisive as soon as an evaluator
+// returns it. Otherwise, it returns !decisive if all evaluators complete without errors.
+func quantify[R any](ctx context.Context, req R, evaluators []Evaluator[R], decisive bool) (bool, error) {
+	if len(evaluators) == 0 {
+		return false, ErrNoEvaluators
+	}
+
+	type result struct {
+		allowed bool
+		err     error
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// The channel is buffered so evaluators that complete after the outcome is decided don't block.
+	results := make(chan result, len(evaluators))
+
+	for _, evaluator := range evaluators {
+		go func() {
+			allowed, err := evaluator.Evaluate(ctx, req)
+			results <- result{allowed: allowed, err: err}
+		}()
+	}
+
+	var errs error
+
+	for range evaluators {
+		res := <-results
+
+		switch {
+		case res.err != nil:
+			errs = multierror.Append(errs, res.err)
+		case res.allowed == decisive:
+			return decisive, nil
+		}
+	}
+
+	if errs != nil {
+		return false, errs
+	}
+
+	return !decisive, nil
+}
@@ -0,0 +1,64 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+var errEvaluate = errors.New("evaluation failed")
+
+func outcome(allowed bool, err error) middleware.Evaluator[string] {
+	return middleware.EvaluatorFunc[string](func(context.Context, string) (bool, error) {
+		return allowed, err
+	})
+}
+
+// blocked doesn't return until its context is canceled.
+var blocked = middleware.EvaluatorFunc[string](func(ctx context.Context, _ string) (bool, error) {
+	<-ctx.Done()
+	return false, ctx.Err()
+})
+
+func TestAllOf(t *testing.T) {
+	for name, tc := range map[string]struct {
+		evaluators []middleware.Evaluator[string]
+		expected   bool
+		err        error
+	}{
+		"all allowed":           {evaluators: []middleware.Evaluator[string]{outcome(true, nil), outcome(true, nil)}, expected: true},
+		"one denied":            {evaluators: []middleware.Evaluator[string]{outcome(true, nil), outcome(false, nil)}},
+		"denial short-circuits": {evaluators: []middleware.Evaluator[string]{blocked, outcome(false, nil)}},
+		"errors fail":           {evaluators: []middleware.Evaluator[string]{outcome(true, nil), outcome(false, errEvaluate)}, err: errEvaluate},
+		"no evaluators":         {err: middleware.ErrNoEvaluators},
+	} {
+		t.Run(name, func(t *testing.T) {
+			allowed, err := middleware.AllOf(tc.evaluators...).Evaluate(context.Background(), "req")
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.expected, allowed)
+		})
+	}
+}
+
+func TestAnyOf(t *testing.T) {
+	for name, tc := range map[string]struct {
+		evaluators []middleware.Evaluator[string]
+		expected   bool
+		err        error
+	}{
+		"one allowed":            {evaluators: []middleware.Evaluator[string]{outcome(false, nil), outcome(true, nil)}, expected: true},
+		"all denied":             {evaluators: []middleware.Evaluator[string]{outcome(false, nil), outcome(false, nil)}},
+		"allowed short-circuits": {evaluators: []middleware.Evaluator[string]{blocked, outcome(true, nil)}, expected: true},
+		"errors fail":            {evaluators: []middleware.Evaluator[string]{outcome(false, nil), outcome(false, errEvaluate)}, err: errEvaluate},
+		"no evaluators":          {err: middleware.ErrNoEvaluators},
+	} {
+		t.Run(name, func(t *testing.T) {
+			allowed, err := middleware.AnyOf(tc.evaluators...).Evaluate(context.Background(), "req")
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.expected, allowed)
+		})
+	}
+}
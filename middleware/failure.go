@@ -0,0 +1,30 @@
+package middleware
+
+// FailureMode determines whether middleware allow or reject requests when the authorization call fails with an error
+// that isn't mapped to an outcome using WithErrorCodeMapping, for example because the authorizer can't be reached.
+// Denied authorization decisions are never affected.
+//
+// Middleware have a failure mode, which can be overridden for specific policy paths. For example, to let a
+// non-critical endpoint proceed when the authorizer is down:
+//
+//	mw.WithFailureMode(middleware.FailOpen, "myapp.GET.recommendations")
+type FailureMode int
+
+const (
+	// FailClosed fails requests when the authorization call fails. This is the default.
+	FailClosed FailureMode = iota
+
+	// FailOpen allows requests when the authorization call fails and logs a warning.
+	FailOpen
+)
+
+func (m FailureMode) String() string {
+	switch m {
+	case FailClosed:
+		return "closed"
+	case FailOpen:
+		return "open"
+	default:
+		return "unknown"
+	}
+}
@@ -2,8 +2,8 @@ package ginz
 
 import (
 	"fmt"
-	"net/http"
 
+	"github.com/aserto-dev/go-aserto/middleware"
 	"github.com/aserto-dev/go-aserto/middleware/internal"
 	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
 	"github.com/gin-gonic/gin"
@@ -23,6 +23,30 @@ func WithIdentityMapper(mapper IdentityMapper) CheckOption {
 	}
 }
 
+// WithSubjectIDFromHeader takes the name of a request header whose value is used as the subject id for the check call.
+// If the header is absent, the caller's identity is used.
+func WithSubjectIDFromHeader(name string) CheckOption {
+	return func(o *CheckOptions) {
+		o.subj.mapper = func(g *gin.Context, identity middleware.Identity) {
+			if id := g.GetHeader(name); id != "" {
+				identity.Subject().ID(id)
+			}
+		}
+	}
+}
+
+// WithSubjectIDFromQuery takes the name of a query parameter whose value is used as the subject id for the check call.
+// If the parameter is absent, the caller's identity is used.
+func WithSubjectIDFromQuery(name string) CheckOption {
+	return func(o *CheckOptions) {
+		o.subj.mapper = func(g *gin.Context, identity middleware.Identity) {
+			if id := g.Query(name); id != "" {
+				identity.Subject().ID(id)
+			}
+		}
+	}
+}
+
 // WithRelation sets the relation/permission to check.
 func WithRelation(name string) CheckOption {
 	return func(o *CheckOptions) {
@@ -151,22 +175,27 @@ func newCheck(mw *Middleware, options ...CheckOption) *Check {
 // Handler returns a middleware handler that checks incoming requests.
 func (c *Check) Handler(g *gin.Context) {
 	policyContext := c.policyContext(g)
-	identityContext := c.identityContext(g)
+
+	identityContext, err := c.identityContext(g)
+	if err != nil {
+		_ = g.AbortWithError(c.mw.errorStatus, err)
+		return
+	}
 
 	resourceContext, err := c.resourceContext(g)
 	if err != nil {
-		_ = g.AbortWithError(http.StatusInternalServerError, err)
+		_ = g.AbortWithError(c.mw.errorStatus, err)
 		return
 	}
 
 	allowed, err := c.mw.is(g.Request.Context(), identityContext, policyContext, resourceContext)
 	if err != nil {
-		_ = g.AbortWithError(http.StatusInternalServerError, err)
+		_ = g.AbortWithError(c.mw.errorStatus, err)
 		return
 	}
 
 	if !allowed {
-		g.AbortWithStatus(http.StatusForbidden)
+		c.mw.deny(g)
 		return
 	}
 
@@ -198,8 +227,11 @@ func (c *Check) policyContext(g *gin.Context) *api.PolicyContext {
 	return policyContext
 }
 
-func (c *Check) identityContext(g *gin.Context) *api.IdentityContext {
-	idc := c.mw.Identity.Build(g)
+func (c *Check) identityContext(g *gin.Context) (*api.IdentityContext, error) {
+	idc, err := c.mw.Identity.resolve(g)
+	if err != nil {
+		return nil, err
+	}
 
 	if c.opts.subj.mapper != nil {
 		identity := internal.NewIdentity(idc.Type, idc.Identity)
@@ -207,7 +239,7 @@ func (c *Check) identityContext(g *gin.Context) *api.IdentityContext {
 		idc = identity.Context()
 	}
 
-	return idc
+	return idc, nil
 }
 
 func (c *Check) resourceContext(g *gin.Context) (*structpb.Struct, error) {
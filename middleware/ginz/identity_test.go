@@ -0,0 +1,30 @@
+package ginz_test
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aserto-dev/go-aserto/middleware/ginz"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/gin-gonic/gin"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestRejectExpiredFromContextValue(t *testing.T) {
+	token, err := jwt.NewBuilder().Subject("alice").Expiration(time.Now().Add(-time.Hour)).Build()
+	assert.NoError(t, err)
+
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.HS256, []byte("secret")))
+	assert.NoError(t, err)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("token", string(signed))
+
+	identity := (&ginz.IdentityBuilder{}).JWT().FromContextValue("token").RejectExpired(0).Build(c)
+
+	assert.Equal(t, api.IdentityType_IDENTITY_TYPE_NONE, identity.GetType())
+	assert.Empty(t, identity.GetIdentity())
+}
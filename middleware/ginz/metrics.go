@@ -0,0 +1,22 @@
+package ginz
+
+import (
+	"github.com/aserto-dev/go-aserto/middleware/internal"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// WithMetrics registers Prometheus collectors with the specified registerer and instructs the middleware to
+// record the outcome and latency of each authorization call.
+//
+// The following metrics are collected:
+//
+//	aserto_authz_decisions_total{decision,allowed} - number of authorization decisions.
+//	aserto_authz_errors_total{decision}            - number of failed authorization calls.
+//	aserto_authz_duration_seconds{decision}        - latency of authorization calls.
+//
+// Collectors that are already registered (e.g. by another middleware) are reused.
+// WithMetrics panics if the collectors can't be registered for any other reason.
+func (m *Middleware) WithMetrics(registerer prometheus.Registerer) *Middleware {
+	m.metrics = internal.NewMetrics(registerer)
+	return m
+}
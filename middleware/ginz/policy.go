@@ -4,15 +4,16 @@ import (
 	"context"
 	"net/http"
 	"strings"
+	"time"
 
 	cerr "github.com/aserto-dev/errors"
 	"github.com/aserto-dev/go-aserto/middleware"
 	"github.com/aserto-dev/go-aserto/middleware/internal"
 	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
 	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
-	"github.com/aserto-dev/go-authorizer/pkg/aerr"
 	"github.com/gin-gonic/gin"
 	"github.com/rs/zerolog"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
@@ -38,10 +39,26 @@ type Middleware struct {
 	// Identity determines the caller identity used in authorization calls.
 	Identity *IdentityBuilder
 
-	client          AuthorizerClient
-	policy          *Policy
-	policyMapper    StringMapper
-	resourceMappers []ResourceMapper
+	client            AuthorizerClient
+	policy            *Policy
+	policyMapper      StringMapper
+	pathFormat        middleware.PathFormat
+	resourceMappers   []ResourceMapper
+	omitEmptyResource bool
+	manualIdentity    string
+	errorOutcomes     map[codes.Code]middleware.Outcome
+	failureModes      internal.FailureModes
+	denialStatus      int
+	errorStatus       int
+	denialHandler     gin.HandlerFunc
+	decision          string
+	allowedPaths      *internal.PathMatcher
+	allowedMethods    internal.Lookup[string]
+	trusted           internal.TrustedPeers
+	postProcessor     middleware.DecisionPostProcessor
+	logLevel          *zerolog.Level
+	logRedactions     []string
+	metrics           *internal.Metrics
 }
 
 type (
@@ -60,45 +77,66 @@ type (
 // Those can be overridden using `Middleware.Identity` to specify the caller's identity, or using
 // the middleware's ".With...()" functions to set policy path and resource mappers.
 func New(client AuthorizerClient, policy *Policy) *Middleware {
-	policyMapper := urlPolicyPathMapper("")
-	if policy.Path != "" {
-		policyMapper = nil
-	}
-
-	return &Middleware{
+	mw := &Middleware{
 		client:          client,
-		Identity:        (&IdentityBuilder{}).FromHeader("Authorization"),
+		Identity:        (&IdentityBuilder{}).FromHeader("Authorization").byDefault(),
 		policy:          policy,
 		resourceMappers: []ResourceMapper{defaultResourceMapper},
-		policyMapper:    policyMapper,
+		pathFormat:      middleware.DefaultPathFormat(),
+		denialStatus:    http.StatusForbidden,
+		errorStatus:     http.StatusInternalServerError,
+	}
+
+	if policy.Path == "" {
+		mw.policyMapper = urlPolicyPathMapper(policy.Root, &mw.pathFormat)
 	}
+
+	return mw
 }
 
 // Handler is the middleware implementation. It is how an Authorizer is wired to a Gin router.
 func (m *Middleware) Handler(c *gin.Context) {
+	if err := m.trusted.Err(); err != nil {
+		_ = c.AbortWithError(m.errorStatus, err)
+		return
+	}
+
+	if m.isAllowed(c.Request) {
+		c.Next()
+		return
+	}
+
 	policyContext := m.policyContext()
 
 	if m.policyMapper != nil {
 		policyContext.Path = m.policyMapper(c)
 	}
 
-	resource, err := m.resourceContext(c)
+	identity, err := m.Identity.resolve(c)
+	if err != nil {
+		_ = c.AbortWithError(m.errorStatus, err)
+		return
+	}
+
+	resource, err := m.resourceContext(c, identity)
 	if err != nil {
-		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		_ = c.AbortWithError(m.errorStatus, err)
 		return
 	}
 
-	allowed, err := m.is(c.Request.Context(), m.Identity.Build(c), policyContext, resource)
+	allowed, err := m.is(c.Request.Context(), identity, policyContext, resource)
 	if err != nil {
-		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		_ = c.AbortWithError(m.errorStatus, err)
 		return
 	}
 
 	if !allowed {
-		c.AbortWithStatus(http.StatusForbidden)
+		m.deny(c)
 		return
 	}
 
+	c.Request = c.Request.WithContext(middleware.WithIdentity(c.Request.Context(), identity))
+
 	c.Next()
 }
 
@@ -111,17 +149,41 @@ func (m *Middleware) Check(options ...CheckOption) gin.HandlerFunc {
 	}
 }
 
+func (m *Middleware) deny(c *gin.Context) {
+	if m.denialHandler != nil {
+		m.denialHandler(c)
+		c.Abort()
+
+		return
+	}
+
+	c.AbortWithStatus(m.denialStatus)
+}
+
+func (m *Middleware) isAllowed(r *http.Request) bool {
+	return m.allowedMethods.Contains(r.Method) || m.allowedPaths.Matches(r.URL.Path) || m.trusted.TrustsRequest(r)
+}
+
 func (m *Middleware) policyContext() *api.PolicyContext {
-	return internal.DefaultPolicyContext(m.policy)
+	policyContext := internal.DefaultPolicyContext(m.policy)
+	if m.decision != "" {
+		policyContext.Decisions = []string{m.decision}
+	}
+
+	return policyContext
 }
 
-func (m *Middleware) resourceContext(c *gin.Context) (*structpb.Struct, error) {
+func (m *Middleware) resourceContext(c *gin.Context, identity *api.IdentityContext) (*structpb.Struct, error) {
 	res := map[string]interface{}{}
 	for _, mapper := range m.resourceMappers {
 		mapper(c, res)
 	}
 
-	return structpb.NewStruct(res)
+	if m.manualIdentity != "" {
+		internal.AddManualIdentity(identity, m.manualIdentity, res)
+	}
+
+	return internal.NewResource(res, m.omitEmptyResource)
 }
 
 func (m *Middleware) is(
@@ -129,6 +191,19 @@ func (m *Middleware) is(
 	identityContext *api.IdentityContext,
 	policyContext *api.PolicyContext,
 	resourceContext *structpb.Struct,
+) (bool, error) {
+	start := time.Now()
+	allowed, err := m.callAuthorizer(ctx, identityContext, policyContext, resourceContext)
+	m.metrics.Observe(policyContext, time.Since(start), allowed, err)
+
+	return allowed, err
+}
+
+func (m *Middleware) callAuthorizer(
+	ctx context.Context,
+	identityContext *api.IdentityContext,
+	policyContext *api.PolicyContext,
+	resourceContext *structpb.Struct,
 ) (bool, error) {
 	isRequest := &authz.IsRequest{
 		IdentityContext: identityContext,
@@ -137,24 +212,40 @@ func (m *Middleware) is(
 		PolicyInstance:  internal.DefaultPolicyInstance(m.policy),
 	}
 
-	logger := zerolog.Ctx(ctx).With().Interface("is_request", isRequest).Logger()
-	logger.Debug().Msg("authorizing request")
-	ctx = logger.WithContext(ctx)
+	log := internal.NewRequestLog(ctx, m.logLevel, "is_request", isRequest, m.logRedactions)
+
+	log.Debug().Msg("authorizing request")
+	ctx = log.WithContext(ctx)
 
 	resp, err := m.client.Is(ctx, isRequest)
 
-	switch {
-	case err != nil:
-		return false, cerr.WithContext(err, ctx)
-	case len(resp.Decisions) != 1:
-		return false, cerr.WithContext(aerr.ErrInvalidDecision, ctx)
+	if err != nil {
+		ctx = log.WithRequestContext(ctx)
+
+		allowed, err := internal.ResolveError(ctx, m.errorOutcomes, &m.failureModes, policyContext.GetPath(), err)
+		if err != nil {
+			return false, cerr.WithContext(err, ctx)
+		}
+
+		return allowed, nil
+	}
+
+	allowed, err := internal.Outcome(resp, m.decision)
+	if err != nil {
+		return false, cerr.WithContext(err, log.WithRequestContext(ctx))
+	}
+
+	if m.postProcessor != nil {
+		if allowed, err = m.postProcessor(ctx, allowed, resp); err != nil {
+			return false, cerr.WrapContext(err, log.WithRequestContext(ctx), "decision post-processor failed")
+		}
 	}
 
-	if !resp.Decisions[0].Is {
-		logger.Info().Msg("authorization failed")
+	if !allowed {
+		log.Info().Msg("authorization failed")
 	}
 
-	return resp.Decisions[0].Is, nil
+	return allowed, nil
 }
 
 // WithPolicyFromURL instructs the middleware to construct the policy path from the path segment
@@ -162,7 +253,7 @@ func (m *Middleware) is(
 //
 // Path separators ('/') are replaced with dots ('.'). If the request uses gorilla/mux to define path
 // parameters, those are added to the path with two leading underscores.
-// An optional prefix can be specified to be included in all paths.
+// An optional prefix can be specified to be included in all paths. If the prefix is empty, the policy's Root is used.
 //
 // # Example
 //
@@ -174,7 +265,32 @@ func (m *Middleware) is(
 //
 //	"myapp.POST.products.__id"
 func (m *Middleware) WithPolicyFromURL(prefix string) *Middleware {
-	m.policyMapper = urlPolicyPathMapper(prefix)
+	if prefix == "" {
+		prefix = m.policy.Root
+	}
+
+	m.policyMapper = urlPolicyPathMapper(prefix, &m.pathFormat)
+	return m
+}
+
+// WithPathSeparator sets the separator that joins the segments of policy paths derived from requests by the
+// middleware's URL policy mapper. Default: ".".
+func (m *Middleware) WithPathSeparator(sep string) *Middleware {
+	m.pathFormat.Separator = sep
+	return m
+}
+
+// WithPathCase sets the case of policy paths derived from requests by the middleware's URL policy mapper.
+// The policy prefix is left unchanged. Default: middleware.PathCasePreserve.
+func (m *Middleware) WithPathCase(pathCase middleware.PathCase) *Middleware {
+	m.pathFormat.Case = pathCase
+	return m
+}
+
+// WithPathParamPrefix sets the prefix of route parameter names in policy paths derived from route templates.
+// Pass an empty string to use bare parameter names. Default: "__".
+func (m *Middleware) WithPathParamPrefix(prefix string) *Middleware {
+	m.pathFormat.ParamPrefix = prefix
 	return m
 }
 
@@ -192,6 +308,113 @@ func (m *Middleware) WithNoResourceContext() *Middleware {
 	return m
 }
 
+// WithErrorCodeMapping determines how the middleware responds when the authorizer fails with specific gRPC codes.
+// Errors with a code that isn't in the mapping fail the request.
+//
+// For example, to treat a missing policy instance as a denied request:
+//
+//	mw.WithErrorCodeMapping(map[codes.Code]middleware.Outcome{codes.NotFound: middleware.OutcomeDeny})
+func (m *Middleware) WithErrorCodeMapping(mapping map[codes.Code]middleware.Outcome) *Middleware {
+	m.errorOutcomes = mapping
+	return m
+}
+
+// WithFailureMode sets the middleware.FailureMode of the middleware or, if policy paths are given, of requests
+// authorized using those paths. Default: middleware.FailClosed.
+func (m *Middleware) WithFailureMode(mode middleware.FailureMode, policyPaths ...string) *Middleware {
+	m.failureModes.Set(mode, policyPaths...)
+	return m
+}
+
+// WithDenialStatus sets the HTTP status code returned when authorization is denied. Default: 403 Forbidden.
+//
+// For example, use http.StatusNotFound to avoid revealing the existence of resources the caller can't access.
+func (m *Middleware) WithDenialStatus(code int) *Middleware {
+	m.denialStatus = code
+	return m
+}
+
+// WithErrorStatus sets the HTTP status code returned when authorization fails with an error.
+// Default: 500 Internal Server Error.
+func (m *Middleware) WithErrorStatus(code int) *Middleware {
+	m.errorStatus = code
+	return m
+}
+
+// WithDenialHandler sets a handler that writes the response when authorization is denied.
+// It takes precedence over WithDenialStatus. The request is aborted after the handler returns.
+func (m *Middleware) WithDenialHandler(handler gin.HandlerFunc) *Middleware {
+	m.denialHandler = handler
+	return m
+}
+
+// WithDecision selects the decision used to authorize requests by name, overriding the policy's Decision.
+//
+// By default, the middleware requires the authorizer to respond with exactly one decision. With WithDecision,
+// responses may include additional decisions (e.g. to be inspected by a decision post-processor) and the outcome
+// is that of the decision whose name matches. Responses without a matching decision fail.
+func (m *Middleware) WithDecision(name string) *Middleware {
+	m.decision = name
+	return m
+}
+
+// WithLogLevel sets the minimum level of the middleware's own log messages, such as the debug-level "authorizing
+// request" message, independently of the level of the logger in the request context.
+// For example, WithLogLevel(zerolog.DebugLevel) enables authorization tracing while other debug logs stay disabled.
+// Note that the zerolog global level still applies.
+func (m *Middleware) WithLogLevel(level zerolog.Level) *Middleware {
+	m.logLevel = &level
+	return m
+}
+
+// WithLogRedaction replaces the values of the named fields with "***" in authorization requests written to the
+// middleware's debug logs. The field "identity" is the caller's identity. Other fields are in the resource context and
+// nested fields use dot notation (e.g. "user.email").
+//
+// Redaction only applies to logs. Authorization calls receive the original values.
+func (m *Middleware) WithLogRedaction(fields ...string) *Middleware {
+	m.logRedactions = append(m.logRedactions, fields...)
+	return m
+}
+
+// WithAllowedPaths lets requests to the specified URL paths proceed without authorization, regardless of their
+// HTTP method. Paths are matched exactly against the request's URL path (e.g. "/healthz"). Paths that end with '*'
+// match all request paths that start with the rest of the path (e.g. "/static/*").
+func (m *Middleware) WithAllowedPaths(paths ...string) *Middleware {
+	m.allowedPaths = internal.NewPathMatcher(paths...)
+	return m
+}
+
+// WithAllowedMethods lets requests with the specified HTTP methods (e.g. "OPTIONS") proceed without authorization.
+func (m *Middleware) WithAllowedMethods(methods ...string) *Middleware {
+	m.allowedMethods = internal.NewMethodLookup(methods...)
+	return m
+}
+
+// WithTrustedPeers lets requests from the specified networks proceed without authorization, before the authorizer
+// is called. Entries are CIDR blocks (e.g. "10.0.0.0/8") or single IP addresses, matched against the request's
+// remote address. Forwarding headers such as X-Forwarded-For aren't considered.
+// If an entry is invalid, requests fail with middleware.ErrInvalidTrustedPeer.
+func (m *Middleware) WithTrustedPeers(cidrs ...string) *Middleware {
+	m.trusted.WithCIDRs(cidrs...)
+	return m
+}
+
+// WithTrustedSPIFFEIDs lets requests from clients that present a verified TLS certificate with one of the specified
+// SPIFFE IDs (e.g. "spiffe://example.org/ns/default/sa/api") proceed without authorization.
+func (m *Middleware) WithTrustedSPIFFEIDs(ids ...string) *Middleware {
+	m.trusted.WithSPIFFEIDs(ids...)
+	return m
+}
+
+// WithDecisionPostProcessor sets a function that is called after the authorizer responds and can override its
+// decision. This allows applying additional business rules (e.g. a feature-flag controlled freeze) to
+// authorization decisions.
+func (m *Middleware) WithDecisionPostProcessor(processor middleware.DecisionPostProcessor) *Middleware {
+	m.postProcessor = processor
+	return m
+}
+
 // WithResourceMapper sets a custom resource mapper, a function that takes an incoming request
 // and returns the resource object to include with the authorization request as a `structpb.Struct`.
 func (m *Middleware) WithResourceMapper(mapper ResourceMapper) *Middleware {
@@ -199,13 +422,70 @@ func (m *Middleware) WithResourceMapper(mapper ResourceMapper) *Middleware {
 	return m
 }
 
+// WithoutEmptyResource sends authorization calls without a resource context when resource mappers don't produce any
+// fields, instead of sending an empty resource. This lets policies distinguish "no resource" from "empty resource".
+func (m *Middleware) WithoutEmptyResource() *Middleware {
+	m.omitEmptyResource = true
+	return m
+}
+
+// WithResourceFallback adds the fields produced by the primary ResourceMapper to the resource context. If primary
+// produces no fields, the fields produced by fallback are added instead.
+func (m *Middleware) WithResourceFallback(primary, fallback ResourceMapper) *Middleware {
+	return m.WithResourceMapper(func(c *gin.Context, resource map[string]interface{}) {
+		fields := map[string]interface{}{}
+
+		primary(c, fields)
+
+		if len(fields) == 0 {
+			fallback(c, fields)
+		}
+
+		for k, v := range fields {
+			resource[k] = v
+		}
+	})
+}
+
+// WithManualIdentityInResource adds the caller's identity to the resource context under the given field when the
+// identity type is manual. Manual identities are passed to policies as-is, so this lets policies that don't read
+// input.identity access them.
+func (m *Middleware) WithManualIdentityInResource(field string) *Middleware {
+	m.manualIdentity = field
+
+	return m
+}
+
+// WithResourceFromQuery adds the specified query string parameters to the resource context.
+// Single values are added as strings and repeated parameters as lists of strings.
+// Pass "*" to include all query parameters.
+func (m *Middleware) WithResourceFromQuery(params ...string) *Middleware {
+	return m.WithResourceMapper(func(c *gin.Context, resource map[string]interface{}) {
+		internal.QueryResource(c.Request.URL.Query(), params, resource)
+	})
+}
+
+// WithResourceFromGinKey adds the value stored in the gin context under key (using c.Set) to the resource context as
+// field. This lets values produced by earlier gin middleware, such as a resolved organization, be used in
+// authorization calls. If the key isn't set, the field is omitted.
+//
+// Values must be representable as a structpb.Value (e.g. strings, numbers, booleans, and maps or slices of those).
+// Other values cause authorization to fail.
+func (m *Middleware) WithResourceFromGinKey(key, field string) *Middleware {
+	return m.WithResourceMapper(func(c *gin.Context, resource map[string]interface{}) {
+		if value, ok := c.Get(key); ok {
+			resource[field] = value
+		}
+	})
+}
+
 func defaultResourceMapper(c *gin.Context, resource map[string]interface{}) {
 	for _, param := range c.Params {
 		resource[param.Key] = param.Value
 	}
 }
 
-func urlPolicyPathMapper(prefix string) StringMapper {
+func urlPolicyPathMapper(prefix string, format *middleware.PathFormat) StringMapper {
 	return func(c *gin.Context) string {
 		policyPath := []string{c.Request.Method}
 
@@ -214,18 +494,12 @@ func urlPolicyPathMapper(prefix string) StringMapper {
 		if len(c.Params) > 0 {
 			for i, segment := range segments {
 				if strings.HasPrefix(segment, ":") {
-					segments[i] = "__" + segment[1:]
+					segments[i] = format.Param(segment[1:])
 				}
 			}
 		}
 
-		policyPath = append(policyPath, segments...)
-
-		if prefix != "" {
-			policyPath = append([]string{strings.Trim(prefix, ".")}, policyPath...)
-		}
-
-		return strings.Join(policyPath, ".")
+		return format.Join(prefix, append(policyPath, segments...)...)
 	}
 }
 
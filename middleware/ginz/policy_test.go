@@ -0,0 +1,119 @@
+package ginz_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware/ginz"
+	"github.com/aserto-dev/go-aserto/middleware/internal/test"
+	"github.com/gin-gonic/gin"
+	assert "github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const DefaultPolicyPath = "GET.foo"
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// serve sends a request to GET /foo on a router that runs the given handlers before the middleware. It returns the
+// response and whether the route's handler was called.
+func serve(mw *ginz.Middleware, handlers ...gin.HandlerFunc) (*httptest.ResponseRecorder, bool) {
+	router := gin.New()
+	router.Use(handlers...)
+	router.Use(mw.Handler)
+
+	called := false
+
+	router.GET("/foo", func(c *gin.Context) {
+		called = true
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/foo", http.NoBody)
+	req.Header.Add("Authorization", test.DefaultUsername)
+
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	return w, called
+}
+
+func TestResourceFromGinKey(t *testing.T) {
+	resource, err := structpb.NewStruct(map[string]interface{}{"org_id": "acme"})
+	assert.NoError(t, err)
+
+	for name, tc := range map[string]struct {
+		handlers []gin.HandlerFunc
+		resource *structpb.Struct
+	}{
+		"key set": {
+			handlers: []gin.HandlerFunc{func(c *gin.Context) { c.Set("org", "acme") }},
+			resource: resource,
+		},
+		"key not set": {
+			resource: &structpb.Struct{Fields: map[string]*structpb.Value{}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			base := test.NewTest(t, name, &test.Options{
+				ExpectedRequest: test.Request(test.PolicyPath(DefaultPolicyPath), test.Resource(tc.resource)),
+			})
+
+			mw := ginz.New(base.Client, test.Policy("")).WithResourceFromGinKey("org", "org_id")
+			mw.Identity.Subject().ID(test.DefaultUsername)
+
+			w, called := serve(mw, tc.handlers...)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.True(t, called)
+		})
+	}
+}
+
+func TestDenial(t *testing.T) {
+	for name, tc := range map[string]struct {
+		handler  gin.HandlerFunc
+		expected int
+		body     string
+	}{
+		"default": {expected: http.StatusForbidden},
+		"denial handler": {
+			handler:  func(c *gin.Context) { c.String(http.StatusUnauthorized, "denied") },
+			expected: http.StatusUnauthorized,
+			body:     "denied",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			base := test.NewTest(t, name, &test.Options{PolicyPath: DefaultPolicyPath, Reject: true})
+
+			mw := ginz.New(base.Client, test.Policy(""))
+			mw.Identity.Subject().ID(test.DefaultUsername)
+
+			if tc.handler != nil {
+				mw.WithDenialHandler(tc.handler)
+			}
+
+			w, called := serve(mw)
+
+			assert.Equal(t, tc.expected, w.Code)
+			assert.Equal(t, tc.body, w.Body.String())
+			assert.False(t, called, "denied requests must not reach the route handler")
+		})
+	}
+}
+
+func TestInvalidTrustedPeer(t *testing.T) {
+	base := test.NewTest(t, "invalid trusted peer", &test.Options{})
+
+	mw := ginz.New(base.Client, test.Policy(DefaultPolicyPath)).WithTrustedPeers("10.0.0.0/33")
+	mw.Identity.Subject().ID(test.DefaultUsername)
+
+	w, called := serve(mw)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.False(t, called)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 
+	"github.com/aserto-dev/go-aserto/middleware"
 	"github.com/aserto-dev/go-aserto/middleware/internal"
 	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
 	"github.com/gorilla/mux"
@@ -23,6 +24,30 @@ func WithIdentityMapper(mapper IdentityMapper) CheckOption {
 	}
 }
 
+// WithSubjectIDFromHeader takes the name of a request header whose value is used as the subject id for the check call.
+// If the header is absent, the caller's identity is used.
+func WithSubjectIDFromHeader(name string) CheckOption {
+	return func(o *CheckOptions) {
+		o.subj.mapper = func(r *http.Request, identity middleware.Identity) {
+			if id := r.Header.Get(name); id != "" {
+				identity.Subject().ID(id)
+			}
+		}
+	}
+}
+
+// WithSubjectIDFromQuery takes the name of a query parameter whose value is used as the subject id for the check call.
+// If the parameter is absent, the caller's identity is used.
+func WithSubjectIDFromQuery(name string) CheckOption {
+	return func(o *CheckOptions) {
+		o.subj.mapper = func(r *http.Request, identity middleware.Identity) {
+			if id := r.URL.Query().Get(name); id != "" {
+				identity.Subject().ID(id)
+			}
+		}
+	}
+}
+
 // WithRelation sets the relation/permission to check.
 func WithRelation(name string) CheckOption {
 	return func(o *CheckOptions) {
@@ -152,22 +177,27 @@ func newCheck(mw *Middleware, options ...CheckOption) *Check {
 func (c *Check) Handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		policyContext := c.policyContext(r)
-		identityContext := c.identityContext(r)
-		resourceContext, err := c.resourceContext(r)
 
+		identityContext, err := c.identityContext(r)
+		if err != nil {
+			http.Error(w, err.Error(), c.mw.errorStatus)
+			return
+		}
+
+		resourceContext, err := c.resourceContext(r)
 		if err != nil {
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			http.Error(w, http.StatusText(c.mw.errorStatus), c.mw.errorStatus)
 			return
 		}
 
 		allowed, err := c.mw.is(r.Context(), identityContext, policyContext, resourceContext)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), c.mw.errorStatus)
 			return
 		}
 
 		if !allowed {
-			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			c.mw.deny(w, r)
 			return
 		}
 
@@ -205,8 +235,11 @@ func (c *Check) policyContext(r *http.Request) *api.PolicyContext {
 	return policyContext
 }
 
-func (c *Check) identityContext(r *http.Request) *api.IdentityContext {
-	idc := c.mw.Identity.Build(r)
+func (c *Check) identityContext(r *http.Request) (*api.IdentityContext, error) {
+	idc, err := c.mw.Identity.resolve(r)
+	if err != nil {
+		return nil, err
+	}
 
 	if c.opts.subj.mapper != nil {
 		identity := internal.NewIdentity(idc.Type, idc.Identity)
@@ -214,7 +247,7 @@ func (c *Check) identityContext(r *http.Request) *api.IdentityContext {
 		idc = identity.Context()
 	}
 
-	return idc
+	return idc, nil
 }
 
 func (c *Check) resourceContext(r *http.Request) (*structpb.Struct, error) {
@@ -5,9 +5,12 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/aserto-dev/go-aserto/middleware"
 	httpmw "github.com/aserto-dev/go-aserto/middleware/gorillaz"
 	"github.com/aserto-dev/go-aserto/middleware/internal/test"
 	assert "github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type TestCase struct {
@@ -83,6 +86,112 @@ func TestAuthorizer(t *testing.T) {
 				},
 			},
 		),
+		NewTest(
+			t,
+			"unmapped authorizer errors should fail",
+			&testOptions{
+				Options: test.Options{
+					Err: status.Error(codes.NotFound, "policy not found"),
+				},
+				expectedStatusCode: http.StatusInternalServerError,
+			},
+		),
+		NewTest(
+			t,
+			"authorizer errors mapped to deny should be forbidden",
+			&testOptions{
+				Options: test.Options{
+					Err: status.Error(codes.NotFound, "policy not found"),
+				},
+				expectedStatusCode: http.StatusForbidden,
+				callback: func(mw *httpmw.Middleware) {
+					mw.WithErrorCodeMapping(
+						map[codes.Code]middleware.Outcome{codes.NotFound: middleware.OutcomeDeny},
+					).Identity.Subject().ID(test.DefaultUsername)
+				},
+			},
+		),
+		NewTest(
+			t,
+			"authorizer errors mapped to allow should succeed",
+			&testOptions{
+				Options: test.Options{
+					Err: status.Error(codes.Unavailable, "unavailable"),
+				},
+				callback: func(mw *httpmw.Middleware) {
+					mw.WithErrorCodeMapping(
+						map[codes.Code]middleware.Outcome{codes.Unavailable: middleware.OutcomeAllow},
+					).Identity.Subject().ID(test.DefaultUsername)
+				},
+			},
+		),
+		NewTest(
+			t,
+			"denials should use the configured status",
+			&testOptions{
+				Options: test.Options{
+					Reject: true,
+				},
+				expectedStatusCode: http.StatusNotFound,
+				callback: func(mw *httpmw.Middleware) {
+					mw.WithDenialStatus(http.StatusNotFound).Identity.Subject().ID(test.DefaultUsername)
+				},
+			},
+		),
+		NewTest(
+			t,
+			"errors should use the configured status",
+			&testOptions{
+				Options: test.Options{
+					Err: status.Error(codes.Unavailable, "unavailable"),
+				},
+				expectedStatusCode: http.StatusServiceUnavailable,
+				callback: func(mw *httpmw.Middleware) {
+					mw.WithErrorStatus(http.StatusServiceUnavailable).Identity.Subject().ID(test.DefaultUsername)
+				},
+			},
+		),
+		NewTest(
+			t,
+			"denial handler should write the response",
+			&testOptions{
+				Options: test.Options{
+					Reject: true,
+				},
+				expectedStatusCode: http.StatusUnauthorized,
+				callback: func(mw *httpmw.Middleware) {
+					mw.WithDenialStatus(http.StatusNotFound).
+						WithDenialHandler(func(w http.ResponseWriter, _ *http.Request) {
+							w.WriteHeader(http.StatusUnauthorized)
+						}).
+						Identity.Subject().ID(test.DefaultUsername)
+				},
+			},
+		),
+		NewTest(
+			t,
+			"allowed paths should skip authorization",
+			&testOptions{
+				Options: test.Options{
+					Reject: true,
+				},
+				callback: func(mw *httpmw.Middleware) {
+					mw.WithAllowedPaths("/f*").Identity.Subject().ID(test.DefaultUsername)
+				},
+			},
+		),
+		NewTest(
+			t,
+			"allowed methods should skip authorization",
+			&testOptions{
+				Options: test.Options{
+					Reject: true,
+				},
+				callback: func(mw *httpmw.Middleware) {
+					mw.WithAllowedMethods("get").Identity.Subject().ID(test.DefaultUsername)
+				},
+			},
+		),
 	}
 
 	for _, test := range tests {
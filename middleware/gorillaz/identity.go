@@ -1,8 +1,12 @@
 package gorillaz
 
 import (
+	"crypto/x509"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/aserto-dev/go-aserto/middleware"
 	"github.com/aserto-dev/go-aserto/middleware/internal"
@@ -18,7 +22,12 @@ type IdentityMapper func(*http.Request, middleware.Identity)
 type IdentityBuilder struct {
 	identityType    api.IdentityType
 	defaultIdentity string
-	mapper          IdentityMapper
+	anonymous       string
+	sources         []internal.IdentitySource[IdentityMapper]
+	precedence      middleware.IdentityPrecedence
+	replaceSources  bool
+	rejectExpired   bool
+	expirySkew      time.Duration
 }
 
 // Static values
@@ -61,6 +70,31 @@ func (b *IdentityBuilder) None() *IdentityBuilder {
 	return b
 }
 
+// AnonymousAs sets a well-known subject used when no caller identity is resolved.
+//
+// Instead of being sent as anonymous (NONE) identities, unauthenticated requests are authorized using the given
+// subject. This lets policies grant limited access to a concrete anonymous principal.
+// For example:
+//
+//	idBuilder.FromHeader("Authorization").AnonymousAs("anonymous")
+func (b *IdentityBuilder) AnonymousAs(subject string) *IdentityBuilder {
+	b.anonymous = subject
+	return b
+}
+
+// RejectExpired treats requests with expired JWTs as anonymous. A token is expired if its "exp" claim is more
+// than skew in the past. Tokens without an "exp" claim are accepted. Identities from all sources, including
+// custom mappers, are checked.
+//
+// The token's signature isn't verified. RejectExpired only catches stale credentials and doesn't replace
+// token validation.
+func (b *IdentityBuilder) RejectExpired(skew time.Duration) *IdentityBuilder {
+	b.rejectExpired = true
+	b.expirySkew = skew
+
+	return b
+}
+
 // Call ID(...) to set the user's identity. If neither JWT() or Subject() are called too, IdentityMapper
 // tries to infer whether the specified identity is a JWT or not.
 // Passing an empty string is the same as calling .None() and results in an authorization check for anonymous access.
@@ -74,7 +108,7 @@ func (b *IdentityBuilder) ID(identity string) *IdentityBuilder {
 // Headers are attempted in order. The first non-empty header is used.
 // If none of the specified headers have a value, the request is considered anonymous.
 func (b *IdentityBuilder) FromHeader(header ...string) *IdentityBuilder {
-	b.mapper = func(r *http.Request, identity middleware.Identity) {
+	return b.addSource("header:"+strings.Join(header, ","), func(r *http.Request, identity middleware.Identity) {
 		for _, h := range header {
 			id := r.Header.Get(h)
 			if id == "" {
@@ -93,20 +127,54 @@ func (b *IdentityBuilder) FromHeader(header ...string) *IdentityBuilder {
 
 		// None of the specified headers are present in the request.
 		identity.None()
-	}
-
-	return b
+	})
 }
 
 // FromContextValue extracts caller identity from a value in the incoming request context.
 //
 // If the value is not present, not a string, or an empty string then the request is considered anonymous.
 func (b *IdentityBuilder) FromContextValue(key interface{}) *IdentityBuilder {
-	b.mapper = func(r *http.Request, identity middleware.Identity) {
+	return b.addSource(fmt.Sprintf("context:%v", key), func(r *http.Request, identity middleware.Identity) {
 		identity.ID(internal.ValueOrEmpty(r.Context(), key))
+	})
+}
+
+// FromCookie retrieves caller identity from the value of the named cookie.
+//
+// If the cookie is missing or empty, the request is considered anonymous.
+// When combined with Subject(), a cookie that holds a JWT is resolved to the token's subject.
+func (b *IdentityBuilder) FromCookie(name string) *IdentityBuilder {
+	return b.addSource("cookie:"+name, func(r *http.Request, identity middleware.Identity) {
+		cookie, err := r.Cookie(name)
+		if err != nil || cookie.Value == "" {
+			identity.None()
+			return
+		}
+
+		identity.ID(cookie.Value)
+	})
+}
+
+// FromClientCert retrieves caller identity from the verified client certificate of mTLS connections.
+//
+// The extract function receives the leaf certificate and returns the identity. If extract is nil, the
+// certificate's subject common name is used.
+// If the connection doesn't have a verified client certificate, the request is considered anonymous.
+func (b *IdentityBuilder) FromClientCert(extract func(*x509.Certificate) string) *IdentityBuilder {
+	if extract == nil {
+		extract = func(cert *x509.Certificate) string {
+			return cert.Subject.CommonName
+		}
 	}
 
-	return b
+	return b.addSource("client_cert", func(r *http.Request, identity middleware.Identity) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			identity.None()
+			return
+		}
+
+		identity.ID(extract(r.TLS.VerifiedChains[0][0]))
+	})
 }
 
 // FromHostname extracts caller identity from the incoming request's host name.
@@ -117,40 +185,82 @@ func (b *IdentityBuilder) FromContextValue(key interface{}) *IdentityBuilder {
 // For Example, if the hostname is "service.user.company.com" then both FromHostname(1) and
 // FromHostname(-3) return the value "user".
 func (b *IdentityBuilder) FromHostname(segment int) *IdentityBuilder {
-	b.mapper = func(r *http.Request, identity middleware.Identity) {
+	return b.addSource(fmt.Sprintf("hostname:%d", segment), func(r *http.Request, identity middleware.Identity) {
 		identity.ID(internal.HostnameSegment(r.URL, segment))
-	}
+	})
+}
 
+// Precedence determines how the caller's identity is resolved when multiple identity sources are configured.
+// Each call to a From...() method or Mapper() adds a source. The default source set by the middleware is replaced
+// by the first source added. Default: middleware.IdentityLastSource.
+//
+// For example, to use the identity from a context value and fall back to the Authorization header:
+//
+//	idBuilder.FromContextValue("user").FromHeader("Authorization").Precedence(middleware.IdentityFirstSource)
+func (b *IdentityBuilder) Precedence(precedence middleware.IdentityPrecedence) *IdentityBuilder {
+	b.precedence = precedence
 	return b
 }
 
 // Mapper takes a custom IdentityMapper to be used for extracting identity information from incoming requests.
 func (b *IdentityBuilder) Mapper(mapper IdentityMapper) *IdentityBuilder {
-	b.mapper = mapper
-	return b
+	return b.addSource("mapper", mapper)
 }
 
 // Build constructs an IdentityContext that can be used in authorization requests.
 func (b *IdentityBuilder) Build(r *http.Request) *api.IdentityContext {
-	identity := internal.NewIdentity(b.identityType, b.defaultIdentity)
+	identity, _ := b.resolve(r)
+	return identity
+}
 
-	if b.mapper != nil {
-		b.mapper(r, identity)
-	}
+// resolve returns the caller's identity. It fails if identity sources conflict and IdentityStrict precedence is used.
+func (b *IdentityBuilder) resolve(r *http.Request) (*api.IdentityContext, error) {
+	identity, err := internal.ResolveIdentity(b.identityType, b.defaultIdentity, b.precedence, b.sources, b.fromToken,
+		func(mapper IdentityMapper, identity middleware.Identity) { mapper(r, identity) })
 
-	return identity.Context()
+	return identity.ContextOrAnonymous(b.anonymous), err
 }
 
 func (b *IdentityBuilder) fromAuthzHeader(value string) string {
 	// Authorization header is special. Need to remove "Bearer" auth scheme.
-	value = strings.TrimSpace(strings.TrimPrefix(value, "Bearer"))
-	if b.identityType == api.IdentityType_IDENTITY_TYPE_SUB {
-		// Try to parse subject out of token
-		token, err := jwt.ParseString(value)
-		if err == nil {
-			value = token.Subject()
-		}
+	return strings.TrimSpace(strings.TrimPrefix(value, "Bearer"))
+}
+
+// fromToken returns the subject of a JWT if identityType is subject and value is a well-formed token.
+// It is applied to the identity resolved by each source.
+// If RejectExpired is set, expired tokens are replaced with an empty string. Otherwise, value is returned as is.
+//
+// The token's signature isn't verified. Callers are expected to be authenticated before reaching the middleware.
+func (b *IdentityBuilder) fromToken(identityType api.IdentityType, value string) string {
+	if identityType != api.IdentityType_IDENTITY_TYPE_SUB && !b.rejectExpired {
+		return value
+	}
+
+	token, err := jwt.ParseString(value, jwt.WithVerify(false), jwt.WithAcceptableSkew(b.expirySkew))
+	if b.rejectExpired && errors.Is(err, jwt.ErrTokenExpired()) {
+		return ""
+	}
+
+	if err == nil && identityType == api.IdentityType_IDENTITY_TYPE_SUB {
+		// Use the token's subject
+		return token.Subject()
 	}
 
 	return value
 }
+
+// byDefault marks the configured identity sources as defaults that are replaced by the next source added.
+func (b *IdentityBuilder) byDefault() *IdentityBuilder {
+	b.replaceSources = true
+	return b
+}
+
+func (b *IdentityBuilder) addSource(name string, mapper IdentityMapper) *IdentityBuilder {
+	if b.replaceSources {
+		b.sources = nil
+		b.replaceSources = false
+	}
+
+	b.sources = append(b.sources, internal.IdentitySource[IdentityMapper]{Name: name, Mapper: mapper})
+	return b
+}
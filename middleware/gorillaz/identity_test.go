@@ -0,0 +1,33 @@
+package gorillaz_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aserto-dev/go-aserto/middleware/gorillaz"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	assert "github.com/stretchr/testify/require"
+)
+
+type tokenKey struct{}
+
+func TestRejectExpiredFromContextValue(t *testing.T) {
+	token, err := jwt.NewBuilder().Subject("alice").Expiration(time.Now().Add(-time.Hour)).Build()
+	assert.NoError(t, err)
+
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.HS256, []byte("secret")))
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/foo", http.NoBody)
+	req = req.WithContext(context.WithValue(req.Context(), tokenKey{}, string(signed)))
+
+	identity := (&gorillaz.IdentityBuilder{}).JWT().FromContextValue(tokenKey{}).RejectExpired(0).Build(req)
+
+	assert.Equal(t, api.IdentityType_IDENTITY_TYPE_NONE, identity.GetType())
+	assert.Empty(t, identity.GetIdentity())
+}
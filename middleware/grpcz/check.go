@@ -2,14 +2,18 @@ package grpcz
 
 import (
 	"context"
+	"fmt"
 
 	cerr "github.com/aserto-dev/errors"
 	"github.com/aserto-dev/go-aserto/middleware/internal"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
 	"github.com/aserto-dev/go-authorizer/pkg/aerr"
 	ds3 "github.com/aserto-dev/go-directory/aserto/directory/reader/v3"
 	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 type ObjectMapper func(ctx context.Context, req any) (objType, id string)
@@ -47,10 +51,17 @@ type CheckOptions struct {
 	obj  objectSpecifier
 	subj objectSpecifier
 	rel  struct {
-		name   string
-		mapper StringMapper
+		name     string
+		mapper   StringMapper
+		byMethod map[string]string
+		names    []string
+		all      bool
 	}
 	filters []Filter
+	policy  struct {
+		client AuthorizerClient
+		policy *Policy
+	}
 }
 
 func (o *CheckOptions) object(ctx context.Context, req any) (string, string) {
@@ -67,6 +78,12 @@ func (o *CheckOptions) subject(ctx context.Context, req any) (string, string) {
 }
 
 func (o *CheckOptions) relation(ctx context.Context, req any) string {
+	if method, ok := grpc.Method(ctx); ok {
+		if relation, ok := o.rel.byMethod[method]; ok {
+			return relation
+		}
+	}
+
 	relation := o.rel.name
 	if o.rel.mapper != nil {
 		relation = o.rel.mapper(ctx, req)
@@ -82,6 +99,24 @@ func WithRelation(name string) CheckOption {
 	}
 }
 
+// WithAnyRelation checks the specified relations/permissions concurrently and allows the request if any of them
+// exist. It takes precedence over WithRelation, WithRelationMapper, and WithRelationByMethod.
+func WithAnyRelation(relations ...string) CheckOption {
+	return func(o *CheckOptions) {
+		o.rel.names = relations
+		o.rel.all = false
+	}
+}
+
+// WithAllRelations checks the specified relations/permissions concurrently and allows the request only if all of
+// them exist. It takes precedence over WithRelation, WithRelationMapper, and WithRelationByMethod.
+func WithAllRelations(relations ...string) CheckOption {
+	return func(o *CheckOptions) {
+		o.rel.names = relations
+		o.rel.all = true
+	}
+}
+
 // WithRelation takes a function that is used to determine the relation/permission to check from the incoming request.
 func WithRelationMapper(mapper StringMapper) CheckOption {
 	return func(o *CheckOptions) {
@@ -89,6 +124,29 @@ func WithRelationMapper(mapper StringMapper) CheckOption {
 	}
 }
 
+// WithRelationByMethod maps gRPC methods (e.g. "/myapp.Docs/Get") to the relation/permission to check when they are
+// called. Methods that aren't in the map use the relation from WithRelation or WithRelationMapper or, if neither is
+// set, the relation determined from the method name.
+func WithRelationByMethod(relations map[string]string) CheckOption {
+	return func(o *CheckOptions) {
+		o.rel.byMethod = relations
+	}
+}
+
+// WithPolicyCheck evaluates checks by calling the authorizer with the specified policy instead of the directory's
+// Check API.
+//
+// The relation, object type, object ID, and subject type are sent in the resource context and the subject ID is sent
+// as the caller's identity.
+// If the policy path is empty, the "check" module under the policy root is evaluated. If the decision is empty,
+// "allowed" is used.
+func WithPolicyCheck(client AuthorizerClient, policy *Policy) CheckOption {
+	return func(o *CheckOptions) {
+		o.policy.client = client
+		o.policy.policy = policy
+	}
+}
+
 // WithObjectType sets the object type to check.
 func WithObjectType(objType string) CheckOption {
 	return func(o *CheckOptions) {
@@ -272,30 +330,102 @@ func (c *CheckMiddleware) authorize(ctx context.Context, req interface{}) error
 		return errors.New("subject type is empty")
 	}
 
-	check := &ds3.CheckRequest{
-		ObjectType:  objType,
-		ObjectId:    objID,
-		Relation:    c.opts.relation(ctx, req),
-		SubjectType: subjType,
-		SubjectId:   subjID,
+	check := func(ctx context.Context, relation string) (bool, error) {
+		check := &ds3.CheckRequest{
+			ObjectType:  objType,
+			ObjectId:    objID,
+			Relation:    relation,
+			SubjectType: subjType,
+			SubjectId:   subjID,
+		}
+
+		logger := zerolog.Ctx(ctx).With().Interface("check_request", check).Logger()
+		logger.Debug().Msg("authorizing request")
+		ctx = logger.WithContext(ctx)
+
+		allowed, err := c.check(ctx, check)
+		if err != nil {
+			return false, cerr.WrapContext(err, ctx, "check call failed")
+		}
+
+		return allowed, nil
 	}
 
-	logger := zerolog.Ctx(ctx).With().Interface("check_request", check).Logger()
-	logger.Debug().Msg("authorizing request")
-	ctx = logger.WithContext(ctx)
+	var (
+		allowed bool
+		err     error
+	)
+
+	switch {
+	case len(c.opts.rel.names) == 0:
+		allowed, err = check(ctx, c.opts.relation(ctx, req))
+	case c.opts.rel.all:
+		allowed, err = internal.CheckAll(ctx, c.opts.rel.names, check)
+	default:
+		allowed, err = internal.CheckAny(ctx, c.opts.rel.names, check)
+	}
 
-	allowed, err := c.dsClient.Check(ctx, check)
 	if err != nil {
-		return cerr.WrapContext(err, ctx, "check call failed")
+		return err
 	}
 
-	if !allowed.Check {
+	if !allowed {
 		return cerr.WithContext(aerr.ErrAuthorizationFailed, ctx)
 	}
 
 	return nil
 }
 
+func (c *CheckMiddleware) check(ctx context.Context, check *ds3.CheckRequest) (bool, error) {
+	if c.opts.policy.client == nil {
+		resp, err := c.dsClient.Check(ctx, check)
+		return resp.GetCheck(), err
+	}
+
+	policy := c.opts.policy.policy
+	policyContext := internal.DefaultPolicyContext(policy)
+
+	if policyContext.Path == "" {
+		policyContext.Path = "check"
+		if policy.Root != "" {
+			policyContext.Path = fmt.Sprintf("%s.%s", policy.Root, policyContext.Path)
+		}
+	}
+
+	if policy.Decision == "" {
+		policyContext.Decisions = []string{"allowed"}
+	}
+
+	resource, err := structpb.NewStruct(map[string]interface{}{
+		"relation":     check.GetRelation(),
+		"object_type":  check.GetObjectType(),
+		"object_id":    check.GetObjectId(),
+		"subject_type": check.GetSubjectType(),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	resp, err := c.opts.policy.client.Is(ctx, &authz.IsRequest{
+		PolicyContext: policyContext,
+		IdentityContext: &api.IdentityContext{
+			Type:     api.IdentityType_IDENTITY_TYPE_SUB,
+			Identity: check.GetSubjectId(),
+		},
+		ResourceContext: resource,
+		PolicyInstance:  internal.DefaultPolicyInstance(policy),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if len(resp.GetDecisions()) == 0 {
+		return false, aerr.ErrInvalidDecision
+	}
+
+	return resp.GetDecisions()[0].GetIs(), nil
+}
+
 func relationFromMethod(ctx context.Context, _ any) string {
 	return permissionFromMethod(ctx)
 }
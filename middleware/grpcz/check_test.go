@@ -0,0 +1,143 @@
+package grpcz_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware/grpcz"
+	"github.com/aserto-dev/go-aserto/middleware/internal/test"
+	"github.com/aserto-dev/go-authorizer/pkg/aerr"
+	ds3 "github.com/aserto-dev/go-directory/aserto/directory/reader/v3"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type checkClient struct {
+	relation string
+}
+
+func (c *checkClient) Check(_ context.Context, in *ds3.CheckRequest, _ ...grpc.CallOption) (*ds3.CheckResponse, error) {
+	c.relation = in.GetRelation()
+	return &ds3.CheckResponse{Check: true}, nil
+}
+
+// transportStream sets the method returned by grpc.Method.
+type transportStream struct {
+	method string
+}
+
+func (s *transportStream) Method() string                 { return s.method }
+func (s *transportStream) SetHeader(_ metadata.MD) error  { return nil }
+func (s *transportStream) SendHeader(_ metadata.MD) error { return nil }
+func (s *transportStream) SetTrailer(_ metadata.MD) error { return nil }
+
+func TestRelationByMethod(t *testing.T) {
+	for method, expected := range map[string]string{
+		"/myapp.Docs/Get":    "can_read",
+		"/myapp.Docs/Delete": "myapp.docs.delete",
+	} {
+		t.Run(method, func(t *testing.T) {
+			client := &checkClient{}
+			mw := grpcz.NewCheckMiddleware(
+				client,
+				grpcz.WithObjectType("document"),
+				grpcz.WithObjectID("doc1"),
+				grpcz.WithSubjectID("george"),
+				grpcz.WithRelationByMethod(map[string]string{"/myapp.Docs/Get": "can_read"}),
+			)
+
+			ctx := grpc.NewContextWithServerTransportStream(context.Background(), &transportStream{method: method})
+
+			_, err := mw.Unary()(ctx, nil, &grpc.UnaryServerInfo{}, func(_ context.Context, _ interface{}) (interface{}, error) {
+				return nil, nil //nolint: nilnil
+			})
+			assert.NoError(t, err)
+			assert.Equal(t, expected, client.relation)
+		})
+	}
+}
+
+func TestPolicyCheck(t *testing.T) {
+	resource, err := structpb.NewStruct(map[string]interface{}{
+		"relation":     "can_read",
+		"object_type":  "document",
+		"object_id":    "doc1",
+		"subject_type": "user",
+	})
+	assert.NoError(t, err)
+
+	for name, reject := range map[string]bool{"allowed": false, "denied": true} {
+		t.Run(name, func(t *testing.T) {
+			base := test.NewTest(t, name, &test.Options{
+				ExpectedRequest: test.Request(test.PolicyPath("myapp.check"), test.Resource(resource)),
+				Reject:          reject,
+			})
+
+			policy := test.Policy("")
+			policy.Root = "myapp"
+
+			mw := grpcz.NewCheckMiddleware(
+				nil,
+				grpcz.WithObjectType("document"),
+				grpcz.WithObjectID("doc1"),
+				grpcz.WithSubjectID(test.DefaultUsername),
+				grpcz.WithRelation("can_read"),
+				grpcz.WithPolicyCheck(base.Client, policy),
+			)
+
+			_, err := mw.Unary()(context.Background(), nil, &grpc.UnaryServerInfo{}, func(_ context.Context, _ interface{}) (interface{}, error) {
+				return nil, nil //nolint: nilnil
+			})
+
+			if reject {
+				assert.ErrorIs(t, err, aerr.ErrAuthorizationFailed)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// relationsClient allows checks on a fixed set of relations.
+type relationsClient struct {
+	allowed map[string]bool
+}
+
+func (c *relationsClient) Check(_ context.Context, in *ds3.CheckRequest, _ ...grpc.CallOption) (*ds3.CheckResponse, error) {
+	return &ds3.CheckResponse{Check: c.allowed[in.GetRelation()]}, nil
+}
+
+func TestCheckMultipleRelations(t *testing.T) {
+	client := &relationsClient{allowed: map[string]bool{"can_read": true}}
+
+	for name, tc := range map[string]struct {
+		option      grpcz.CheckOption
+		expectedErr error
+	}{
+		"any relation allowed":  {option: grpcz.WithAnyRelation("can_write", "can_read")},
+		"any relation denied":   {option: grpcz.WithAnyRelation("can_write", "can_delete"), expectedErr: aerr.ErrAuthorizationFailed},
+		"all relations allowed": {option: grpcz.WithAllRelations("can_read")},
+		"all relations denied":  {option: grpcz.WithAllRelations("can_read", "can_write"), expectedErr: aerr.ErrAuthorizationFailed},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mw := grpcz.NewCheckMiddleware(
+				client,
+				grpcz.WithObjectType("document"),
+				grpcz.WithObjectID("doc1"),
+				grpcz.WithSubjectID("george"),
+				tc.option,
+			)
+
+			_, err := mw.Unary()(context.Background(), nil, &grpc.UnaryServerInfo{}, func(_ context.Context, _ interface{}) (interface{}, error) {
+				return nil, nil //nolint: nilnil
+			})
+			if tc.expectedErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
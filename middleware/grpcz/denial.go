@@ -0,0 +1,57 @@
+package grpcz
+
+import (
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// DenialReason is the reason of the errdetails.ErrorInfo attached to denied requests by WithDenialReasonInError.
+const DenialReason = "AUTHORIZATION_DENIED"
+
+// WithDenialReasonInError adds the details of denied authorization decisions to the returned gRPC status.
+//
+// The status details include an errdetails.ErrorInfo with the evaluated policy path and decision in its metadata,
+// followed by the authorizer's IsResponse. Clients can inspect them to explain why access was denied.
+func (m *Middleware) WithDenialReasonInError() *Middleware {
+	m.denialDetails = true
+	return m
+}
+
+// denialError is returned when authorization is denied and carries the decision details in its gRPC status.
+type denialError struct {
+	err  error
+	req  *authz.IsRequest
+	resp *authz.IsResponse
+}
+
+func (e *denialError) Error() string {
+	return e.err.Error()
+}
+
+func (e *denialError) Unwrap() error {
+	return e.err
+}
+
+func (e *denialError) GRPCStatus() *status.Status {
+	st := status.New(codes.PermissionDenied, e.err.Error())
+
+	info := &errdetails.ErrorInfo{
+		Reason: DenialReason,
+		Metadata: map[string]string{
+			"policy_path": e.req.GetPolicyContext().GetPath(),
+		},
+	}
+
+	if decisions := e.resp.GetDecisions(); len(decisions) > 0 {
+		info.Metadata["decision"] = decisions[0].GetDecision()
+	}
+
+	detailed, err := st.WithDetails(info, e.resp)
+	if err != nil {
+		return st
+	}
+
+	return detailed
+}
@@ -7,5 +7,6 @@ import (
 )
 
 func (b *IdentityBuilder) InternalBuild(ctx context.Context, req interface{}) *api.IdentityContext {
-	return b.build(ctx, req)
+	identity, _ := b.build(ctx, req)
+	return identity
 }
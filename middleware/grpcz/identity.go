@@ -2,7 +2,10 @@ package grpcz
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"strings"
+	"time"
 
 	"github.com/aserto-dev/go-aserto/middleware"
 	"github.com/aserto-dev/go-aserto/middleware/internal"
@@ -18,7 +21,12 @@ type IdentityMapper func(context.Context, interface{}, middleware.Identity)
 type IdentityBuilder struct {
 	identityType    api.IdentityType
 	defaultIdentity string
-	mapper          IdentityMapper
+	anonymous       string
+	sources         []internal.IdentitySource[IdentityMapper]
+	precedence      middleware.IdentityPrecedence
+	replaceSources  bool
+	rejectExpired   bool
+	expirySkew      time.Duration
 }
 
 // Static values
@@ -61,6 +69,31 @@ func (b *IdentityBuilder) None() *IdentityBuilder {
 	return b
 }
 
+// AnonymousAs sets a well-known subject used when no caller identity is resolved.
+//
+// Instead of being sent as anonymous (NONE) identities, unauthenticated requests are authorized using the given
+// subject. This lets policies grant limited access to a concrete anonymous principal.
+// For example:
+//
+//	idBuilder.FromHeader("Authorization").AnonymousAs("anonymous")
+func (b *IdentityBuilder) AnonymousAs(subject string) *IdentityBuilder {
+	b.anonymous = subject
+	return b
+}
+
+// RejectExpired treats requests with expired JWTs as anonymous. A token is expired if its "exp" claim is more
+// than skew in the past. Tokens without an "exp" claim are accepted. Identities from all sources, including
+// custom mappers, are checked.
+//
+// The token's signature isn't verified. RejectExpired only catches stale credentials and doesn't replace
+// token validation.
+func (b *IdentityBuilder) RejectExpired(skew time.Duration) *IdentityBuilder {
+	b.rejectExpired = true
+	b.expirySkew = skew
+
+	return b
+}
+
 // Call ID(...) to set the user's identity. If neither JWT() or Subject() are called too, IdentityMapper
 // tries to infer whether the specified identity is a JWT or not.
 // Passing an empty string is the same as calling .None() and results in an authorization check for anonymous access.
@@ -71,53 +104,104 @@ func (b *IdentityBuilder) ID(identity string) *IdentityBuilder {
 
 // FromMetadata extracts caller identity from a grpc/metadata field in the incoming message.
 func (b *IdentityBuilder) FromMetadata(field string) *IdentityBuilder {
-	b.mapper = func(ctx context.Context, _ interface{}, identity middleware.Identity) {
+	return b.addSource("metadata:"+field, func(ctx context.Context, _ interface{}, identity middleware.Identity) {
 		if md, ok := metadata.FromIncomingContext(ctx); ok {
 			id := md.Get(field)
 			if len(id) > 0 {
 				identity.ID(b.fromAuthzHeader(id[0]))
 			}
 		}
-	}
-
-	return b
+	})
 }
 
 // WithIdentityFromContextValue extracts caller identity from a context value in the incoming message.
 func (b *IdentityBuilder) FromContextValue(key interface{}) *IdentityBuilder {
-	b.mapper = func(ctx context.Context, _ interface{}, identity middleware.Identity) {
+	return b.addSource(fmt.Sprintf("context:%v", key), func(ctx context.Context, _ interface{}, identity middleware.Identity) {
 		identity.ID(internal.ValueOrEmpty(ctx, key))
-	}
+	})
+}
+
+// FromContextValues extracts caller identity from values in the incoming request context.
+//
+// Keys are attempted in order. The first non-empty string value is used.
+// If none of the keys have a value, the request is considered anonymous.
+// When combined with Subject(), a value that holds a JWT is resolved to the token's subject.
+func (b *IdentityBuilder) FromContextValues(keys ...any) *IdentityBuilder {
+	return b.addSource(fmt.Sprintf("context:%v", keys), func(ctx context.Context, _ interface{}, identity middleware.Identity) {
+		for _, key := range keys {
+			if id := internal.ValueOrEmpty(ctx, key); id != "" {
+				identity.ID(id)
+				return
+			}
+		}
+
+		identity.None()
+	})
+}
 
+// Precedence determines how the caller's identity is resolved when multiple identity sources are configured.
+// Each call to a From...() method or Mapper() adds a source. The default source set by the middleware is replaced
+// by the first source added. Default: middleware.IdentityLastSource.
+//
+// For example, to use the identity from a context value and fall back to the Authorization header:
+//
+//	idBuilder.FromContextValue("user").FromHeader("Authorization").Precedence(middleware.IdentityFirstSource)
+func (b *IdentityBuilder) Precedence(precedence middleware.IdentityPrecedence) *IdentityBuilder {
+	b.precedence = precedence
 	return b
 }
 
 // Mapper takes a custom IdentityMapper to be used for extracting identity information from incoming RPCs.
 func (b *IdentityBuilder) Mapper(mapper IdentityMapper) *IdentityBuilder {
-	b.mapper = mapper
-	return b
+	return b.addSource("mapper", mapper)
 }
 
-func (b *IdentityBuilder) build(ctx context.Context, req interface{}) *api.IdentityContext {
-	identity := internal.NewIdentity(b.identityType, b.defaultIdentity)
-
-	if b.mapper != nil {
-		b.mapper(ctx, req, identity)
-	}
+// build returns the caller's identity. It fails if identity sources conflict and IdentityStrict precedence is used.
+func (b *IdentityBuilder) build(ctx context.Context, req interface{}) (*api.IdentityContext, error) {
+	identity, err := internal.ResolveIdentity(b.identityType, b.defaultIdentity, b.precedence, b.sources, b.fromToken,
+		func(mapper IdentityMapper, identity middleware.Identity) { mapper(ctx, req, identity) })
 
-	return identity.Context()
+	return identity.ContextOrAnonymous(b.anonymous), err
 }
 
 func (b *IdentityBuilder) fromAuthzHeader(value string) string {
 	// Authorization header is special. Need to remove "Bearer" auth scheme.
-	value = strings.TrimSpace(strings.TrimPrefix(value, "Bearer"))
-	if b.identityType == api.IdentityType_IDENTITY_TYPE_SUB {
-		// Try to parse subject out of token
-		token, err := jwt.ParseString(value, jwt.WithVerify(false))
-		if err == nil {
-			value = token.Subject()
-		}
+	return strings.TrimSpace(strings.TrimPrefix(value, "Bearer"))
+}
+
+// fromToken returns the subject of a JWT if identityType is subject and value is a well-formed token.
+// It is applied to the identity resolved by each source.
+// If RejectExpired is set, expired tokens are replaced with an empty string. Otherwise, value is returned as is.
+func (b *IdentityBuilder) fromToken(identityType api.IdentityType, value string) string {
+	if identityType != api.IdentityType_IDENTITY_TYPE_SUB && !b.rejectExpired {
+		return value
+	}
+
+	token, err := jwt.ParseString(value, jwt.WithVerify(false), jwt.WithAcceptableSkew(b.expirySkew))
+	if b.rejectExpired && errors.Is(err, jwt.ErrTokenExpired()) {
+		return ""
+	}
+
+	if err == nil && identityType == api.IdentityType_IDENTITY_TYPE_SUB {
+		// Use the token's subject
+		return token.Subject()
 	}
 
 	return value
 }
+
+// byDefault marks the configured identity sources as defaults that are replaced by the next source added.
+func (b *IdentityBuilder) byDefault() *IdentityBuilder {
+	b.replaceSources = true
+	return b
+}
+
+func (b *IdentityBuilder) addSource(name string, mapper IdentityMapper) *IdentityBuilder {
+	if b.replaceSources {
+		b.sources = nil
+		b.replaceSources = false
+	}
+
+	b.sources = append(b.sources, internal.IdentitySource[IdentityMapper]{Name: name, Mapper: mapper})
+	return b
+}
@@ -3,7 +3,10 @@ package grpcz_test
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwt"
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc/metadata"
 
@@ -133,3 +136,67 @@ func TestMissingContextValue(t *testing.T) {
 		"Missing context value should result in anonymous identity",
 	)
 }
+
+type session struct{}
+
+func TestIdentityFromContextValues(t *testing.T) {
+	builder := &grpcz.IdentityBuilder{}
+	builder.Subject().FromContextValues(user{}, session{})
+
+	ctx := context.WithValue(context.TODO(), user{}, "")
+	ctx = context.WithValue(ctx, session{}, username)
+
+	assert.Equal(
+		t,
+		SUB(),
+		builder.InternalBuild(ctx, nil),
+		"First non-empty context value should be used",
+	)
+
+	assert.Equal(
+		t,
+		Anon(),
+		builder.InternalBuild(context.TODO(), nil),
+		"Missing context values should result in anonymous identity",
+	)
+}
+
+func TestSubjectFromContextValuesToken(t *testing.T) {
+	token, err := jwt.NewBuilder().Subject(username).Build()
+	assert.NoError(t, err)
+
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.HS256, []byte("secret")))
+	assert.NoError(t, err)
+
+	builder := &grpcz.IdentityBuilder{}
+	builder.Subject().FromContextValues(user{}, session{})
+
+	ctx := context.WithValue(context.TODO(), session{}, string(signed))
+
+	assert.Equal(
+		t,
+		SUB(),
+		builder.InternalBuild(ctx, nil),
+		"Subject should be parsed from JWT context value",
+	)
+}
+
+func TestRejectExpiredFromContextValue(t *testing.T) {
+	token, err := jwt.NewBuilder().Subject(username).Expiration(time.Now().Add(-time.Hour)).Build()
+	assert.NoError(t, err)
+
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.HS256, []byte("secret")))
+	assert.NoError(t, err)
+
+	builder := &grpcz.IdentityBuilder{}
+	builder.JWT().FromContextValue(user{}).RejectExpired(0)
+
+	ctx := context.WithValue(context.TODO(), user{}, string(signed))
+
+	assert.Equal(
+		t,
+		Anon(),
+		builder.InternalBuild(ctx, nil),
+		"Expired tokens from context values should result in anonymous identity",
+	)
+}
@@ -8,16 +8,27 @@ package grpcz
 
 import (
 	"context"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	cerr "github.com/aserto-dev/errors"
 	"github.com/aserto-dev/go-aserto/middleware"
-	"github.com/aserto-dev/go-aserto/middleware/grpcz/internal/pbutil"
 	"github.com/aserto-dev/go-aserto/middleware/internal"
+	"github.com/aserto-dev/go-aserto/middleware/internal/pbutil"
 	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
 	"github.com/aserto-dev/go-authorizer/pkg/aerr"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/protobuf/reflect/protoreflect"
 	"google.golang.org/protobuf/types/known/structpb"
 )
@@ -44,12 +55,31 @@ type Middleware struct {
 	// Identity determines the caller identity used in authorization calls.
 	Identity *IdentityBuilder
 
-	client          AuthorizerClient
-	policy          *Policy
-	policyMapper    StringMapper
-	resourceMappers []ResourceMapper
-	ignoredPaths    internal.Lookup[string]
-	allowedMethods  internal.Lookup[string]
+	client            AuthorizerClient
+	policy            *Policy
+	policyMapper      StringMapper
+	pathFormat        middleware.PathFormat
+	resourceMappers   []ResourceMapper
+	omitEmptyResource bool
+	manualIdentity    string
+	ignoredPaths      internal.Lookup[string]
+	allowedMethods    internal.Lookup[string]
+	trusted           internal.TrustedPeers
+	errorOutcomes     map[codes.Code]middleware.Outcome
+	failureModes      internal.FailureModes
+	decision          string
+	postProcessor     middleware.DecisionPostProcessor
+	decisionLogger    middleware.DecisionLogger
+	logLevel          *zerolog.Level
+	logRedactions     []string
+	tracer            trace.Tracer
+	metrics           *internal.Metrics
+	treeFormat        middleware.DecisionTreeFormat
+
+	skipMessage   bool
+	denialDetails bool
+	propagateRID  bool
+	perMessage    bool
 }
 
 type (
@@ -67,18 +97,20 @@ type (
 // Those can be overridden using `Middleware.Identity` to specify the caller's identity, or using
 // the middleware's ".With...()" functions to set policy path and resource mappers.
 func New(authzClient AuthorizerClient, policy *Policy) *Middleware {
-	policyMapper := methodPolicyMapper("")
-	if policy.Path != "" {
-		policyMapper = nil
-	}
-
-	return &Middleware{
-		Identity:        (&IdentityBuilder{}).FromMetadata("authorization"),
+	mw := &Middleware{
+		Identity:        (&IdentityBuilder{}).FromMetadata("authorization").byDefault(),
 		client:          authzClient,
 		policy:          policy,
-		policyMapper:    policyMapper,
+		pathFormat:      middleware.DefaultPathFormat(),
 		resourceMappers: []ResourceMapper{},
+		tracer:          noop.NewTracerProvider().Tracer(""),
 	}
+
+	if policy.Path == "" {
+		mw.policyMapper = methodPolicyMapper("", &mw.pathFormat)
+	}
+
+	return mw
 }
 
 // Deprecated: Use WithAllowedMethods instead.
@@ -98,6 +130,96 @@ func (m *Middleware) WithAllowedMethods(methods ...string) *Middleware {
 	return m
 }
 
+// WithTrustedPeers lets calls from the specified networks proceed without authorization, before the authorizer
+// is called. Entries are CIDR blocks (e.g. "10.0.0.0/8") or single IP addresses, matched against the peer's address.
+// If an entry is invalid, calls fail with middleware.ErrInvalidTrustedPeer.
+func (m *Middleware) WithTrustedPeers(cidrs ...string) *Middleware {
+	m.trusted.WithCIDRs(cidrs...)
+	return m
+}
+
+// WithTrustedSPIFFEIDs lets calls from clients that present a verified TLS certificate with one of the specified
+// SPIFFE IDs (e.g. "spiffe://example.org/ns/default/sa/api") proceed without authorization.
+func (m *Middleware) WithTrustedSPIFFEIDs(ids ...string) *Middleware {
+	m.trusted.WithSPIFFEIDs(ids...)
+	return m
+}
+
+// WithErrorCodeMapping determines how the middleware responds when the authorizer fails with specific gRPC codes.
+// Errors with a code that isn't in the mapping fail the request.
+//
+// For example, to treat a missing policy instance as a denied request:
+//
+//	mw.WithErrorCodeMapping(map[codes.Code]middleware.Outcome{codes.NotFound: middleware.OutcomeDeny})
+func (m *Middleware) WithErrorCodeMapping(mapping map[codes.Code]middleware.Outcome) *Middleware {
+	m.errorOutcomes = mapping
+	return m
+}
+
+// WithFailureMode sets the middleware.FailureMode of the middleware or, if policy paths are given, of requests
+// authorized using those paths. Default: middleware.FailClosed.
+func (m *Middleware) WithFailureMode(mode middleware.FailureMode, policyPaths ...string) *Middleware {
+	m.failureModes.Set(mode, policyPaths...)
+	return m
+}
+
+// WithDecision selects the decision used to authorize requests by name, overriding the policy's Decision.
+//
+// The authorizer's response may include additional decisions (e.g. to be inspected by a decision post-processor).
+// The outcome is that of the decision whose name matches. Responses without a matching decision fail.
+func (m *Middleware) WithDecision(name string) *Middleware {
+	m.decision = name
+	return m
+}
+
+// WithLogLevel sets the minimum level of the middleware's own log messages, such as the debug-level "authorizing
+// request" message, independently of the level of the logger in the request context.
+// For example, WithLogLevel(zerolog.DebugLevel) enables authorization tracing while other debug logs stay disabled.
+// Note that the zerolog global level still applies.
+func (m *Middleware) WithLogLevel(level zerolog.Level) *Middleware {
+	m.logLevel = &level
+	return m
+}
+
+// WithLogRedaction replaces the values of the named fields with "***" in authorization requests written to the
+// middleware's debug logs. The field "identity" is the caller's identity. Other fields are in the resource context and
+// nested fields use dot notation (e.g. "user.email").
+//
+// Redaction only applies to logs. Authorization calls receive the original values.
+func (m *Middleware) WithLogRedaction(fields ...string) *Middleware {
+	m.logRedactions = append(m.logRedactions, fields...)
+	return m
+}
+
+// WithDecisionPostProcessor sets a function that is called after the authorizer responds and can override its
+// decision. This allows applying additional business rules (e.g. a feature-flag controlled freeze) to
+// authorization decisions.
+func (m *Middleware) WithDecisionPostProcessor(processor middleware.DecisionPostProcessor) *Middleware {
+	m.postProcessor = processor
+	return m
+}
+
+// WithDecisionLogger sets a function that is called after every authorization call with a description of the
+// decision, including the caller's identity, the evaluated policy, the resource context, and the outcome.
+func (m *Middleware) WithDecisionLogger(logger middleware.DecisionLogger) *Middleware {
+	m.decisionLogger = logger
+	return m
+}
+
+// WithPathSeparator sets the separator that joins the segments of policy paths derived from gRPC method names by
+// the middleware's default policy mapper. Separators within package names are replaced too. Default: ".".
+func (m *Middleware) WithPathSeparator(sep string) *Middleware {
+	m.pathFormat.Separator = sep
+	return m
+}
+
+// WithPathCase sets the case of policy paths derived from gRPC method names by the middleware's default policy mapper.
+// Default: middleware.PathCasePreserve.
+func (m *Middleware) WithPathCase(pathCase middleware.PathCase) *Middleware {
+	m.pathFormat.Case = pathCase
+	return m
+}
+
 // WithPolicyPathMapper takes a custom StringMapper for extracting the authorization policy path form
 // incoming message.
 func (m *Middleware) WithPolicyPathMapper(mapper StringMapper) *Middleware {
@@ -191,6 +313,62 @@ func (m *Middleware) WithResourceMapper(mapper ResourceMapper) *Middleware {
 	return m
 }
 
+// WithoutEmptyResource sends authorization calls without a resource context when resource mappers don't produce any
+// fields, instead of sending an empty resource. This lets policies distinguish "no resource" from "empty resource".
+func (m *Middleware) WithoutEmptyResource() *Middleware {
+	m.omitEmptyResource = true
+	return m
+}
+
+// WithResourceFallback adds the fields produced by the primary ResourceMapper to the resource context. If primary
+// produces no fields, the fields produced by fallback are added instead.
+func (m *Middleware) WithResourceFallback(primary, fallback ResourceMapper) *Middleware {
+	return m.WithResourceMapper(func(ctx context.Context, req interface{}, resource map[string]interface{}) {
+		fields := map[string]interface{}{}
+
+		primary(ctx, req, fields)
+
+		if len(fields) == 0 {
+			fallback(ctx, req, fields)
+		}
+
+		for k, v := range fields {
+			resource[k] = v
+		}
+	})
+}
+
+// WithManualIdentityInResource adds the caller's identity to the resource context under the given field when the
+// identity type is manual. Manual identities are passed to policies as-is, so this lets policies that don't read
+// input.identity access them.
+func (m *Middleware) WithManualIdentityInResource(field string) *Middleware {
+	m.manualIdentity = field
+
+	return m
+}
+
+// WithoutMessageInspection instructs the middleware to ignore the contents of incoming messages.
+// Policy path, identity, and resource mappers receive a nil message, and message-based resource mappers such as
+// WithResourceFromFields have no effect.
+//
+// Use it when authorization depends only on the caller, method, and metadata to avoid the cost of proto reflection.
+func (m *Middleware) WithoutMessageInspection() *Middleware {
+	m.skipMessage = true
+	return m
+}
+
+// WithPerMessageStreamAuthorization authorizes each message received on streams in addition to authorizing streams
+// when they are opened. Every call to the stream's RecvMsg is authorized with the received message as the request, so
+// resource mappers can read message fields in client-streaming and bidirectional RPCs. Denied messages fail RecvMsg
+// with the authorization error.
+//
+// Streams are authorized when they are opened without a request message, as they are without per-message
+// authorization, and the stream handler isn't called if they are denied.
+func (m *Middleware) WithPerMessageStreamAuthorization() *Middleware {
+	m.perMessage = true
+	return m
+}
+
 // Unary returns a grpc.UnaryServiceInterceptor that authorizes incoming messages.
 func (m *Middleware) Unary() grpc.UnaryServerInterceptor {
 	return func(
@@ -199,7 +377,8 @@ func (m *Middleware) Unary() grpc.UnaryServerInterceptor {
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (interface{}, error) {
-		if err := m.authorize(ctx, req); err != nil {
+		ctx, err := m.authorize(ctx, req)
+		if err != nil {
 			return nil, err
 		}
 
@@ -215,86 +394,265 @@ func (m *Middleware) Stream() grpc.StreamServerInterceptor {
 		info *grpc.StreamServerInfo,
 		handler grpc.StreamHandler,
 	) error {
-		ctx := stream.Context()
-
-		if err := m.authorize(ctx, nil); err != nil {
+		ctx, err := m.authorize(stream.Context(), nil)
+		if err != nil {
 			return err
 		}
 
-		return handler(srv, stream)
+		if m.perMessage {
+			return handler(srv, &authorizingStream{ServerStream: stream, mw: m, ctx: ctx})
+		}
+
+		return handler(srv, &serverStream{ServerStream: stream, ctx: ctx})
 	}
 }
 
-func (m *Middleware) authorize(ctx context.Context, req interface{}) error {
-	if m.isAllowedMethod(ctx) {
-		return nil
+// authorize returns a copy of ctx that carries the caller's identity if the request is allowed.
+func (m *Middleware) authorize(ctx context.Context, req interface{}) (context.Context, error) {
+	if err := m.trusted.Err(); err != nil {
+		return ctx, cerr.WrapContext(err, ctx, "failed to check trusted peers")
+	}
+
+	if m.isAllowedMethod(ctx) || m.isTrustedPeer(ctx) {
+		return ctx, nil
+	}
+
+	if m.skipMessage {
+		req = nil
 	}
 
 	policyContext := internal.DefaultPolicyContext(m.policy)
+	if m.decision != "" {
+		policyContext.Decisions = []string{m.decision}
+	}
+
 	if m.policyMapper != nil {
 		policyContext.Path = m.policyMapper(ctx, req)
 	}
 
 	if m.ignoredPaths.Contains(policyContext.Path) {
-		return nil
+		return ctx, nil
 	}
 
-	resource, err := m.resourceContext(ctx, req)
+	identity, err := m.Identity.build(ctx, req)
 	if err != nil {
-		return cerr.WrapContext(err, ctx, "failed to apply resource mapper")
+		return ctx, cerr.WrapContext(err, ctx, "failed to resolve identity")
+	}
+
+	resource, err := m.resourceContext(ctx, req, identity)
+	if err != nil {
+		return ctx, cerr.WrapContext(err, ctx, "failed to apply resource mapper")
 	}
 
 	isReq := &authz.IsRequest{
-		IdentityContext: m.Identity.build(ctx, req),
+		IdentityContext: identity,
 		PolicyContext:   policyContext,
 		ResourceContext: resource,
 		PolicyInstance:  internal.DefaultPolicyInstance(m.policy),
 	}
 
-	logger := zerolog.Ctx(ctx).With().Interface("is", isReq).Logger()
-	logger.Debug().Msg("authorizing request")
-	ctx = logger.WithContext(ctx)
+	start := time.Now()
+
+	spanCtx, span := internal.StartAuthorizeSpan(ctx, m.tracer, policyContext)
+	err = m.is(spanCtx, isReq)
+	internal.EndAuthorizeSpan(span, err == nil, err)
+
+	elapsed := time.Since(start)
+	m.metrics.Observe(policyContext, elapsed, err == nil, err)
+	m.logDecision(ctx, isReq, elapsed, err)
+
+	if err != nil {
+		return ctx, err
+	}
+
+	return middleware.WithIdentity(ctx, identity), nil
+}
+
+// serverStream overrides the context of a grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// authorizingStream authorizes each message received on a grpc.ServerStream.
+type authorizingStream struct {
+	grpc.ServerStream
+	mw *Middleware
+
+	// ctx is updated by RecvMsg and can be read concurrently by handlers that send and receive on separate goroutines.
+	mu  sync.Mutex
+	ctx context.Context
+}
+
+func (s *authorizingStream) Context() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.ctx
+}
+
+func (s *authorizingStream) RecvMsg(msg interface{}) error {
+	if err := s.ServerStream.RecvMsg(msg); err != nil {
+		return err
+	}
+
+	ctx, err := s.mw.authorize(s.ServerStream.Context(), msg)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (m *Middleware) is(ctx context.Context, isReq *authz.IsRequest) error {
+	if m.propagateRID {
+		ctx = outgoingRequestID(ctx)
+	}
+
+	log := internal.NewRequestLog(ctx, m.logLevel, "is", isReq, m.logRedactions)
+
+	log.Debug().Msg("authorizing request")
+	ctx = log.WithContext(ctx)
 
 	resp, err := m.client.Is(ctx, isReq)
 	if err != nil {
-		return cerr.WrapContext(err, ctx, "authorization call failed")
+		ctx = log.WithRequestContext(ctx)
+
+		allowed, err := internal.ResolveError(ctx, m.errorOutcomes, &m.failureModes, isReq.GetPolicyContext().GetPath(), err)
+		if err != nil {
+			return cerr.WrapContext(err, ctx, "authorization call failed")
+		}
+
+		if !allowed {
+			return cerr.WithContext(aerr.ErrAuthorizationFailed, ctx)
+		}
+
+		return nil
 	}
 
-	if len(resp.Decisions) == 0 {
-		return cerr.WithContext(aerr.ErrInvalidDecision, ctx)
+	allowed, err := m.outcome(resp)
+	if err != nil {
+		return cerr.WithContext(err, log.WithRequestContext(ctx))
 	}
 
-	if !resp.Decisions[0].Is {
-		return cerr.WithContext(aerr.ErrAuthorizationFailed, ctx)
+	if m.postProcessor != nil {
+		if allowed, err = m.postProcessor(ctx, allowed, resp); err != nil {
+			return cerr.WrapContext(err, log.WithRequestContext(ctx), "decision post-processor failed")
+		}
+	}
+
+	if !allowed {
+		err := cerr.WithContext(aerr.ErrAuthorizationFailed, log.WithRequestContext(ctx))
+		if m.denialDetails {
+			return &denialError{err: err, req: isReq, resp: resp}
+		}
+
+		return err
 	}
 
 	return nil
 }
 
+// outcome returns the outcome of the authorizer's response. Unless a decision is selected with WithDecision, the
+// first decision is used.
+func (m *Middleware) outcome(resp *authz.IsResponse) (bool, error) {
+	if m.decision != "" {
+		return internal.Outcome(resp, m.decision)
+	}
+
+	if len(resp.GetDecisions()) == 0 {
+		return false, aerr.ErrInvalidDecision
+	}
+
+	return resp.GetDecisions()[0].GetIs(), nil
+}
+
+func (m *Middleware) logDecision(ctx context.Context, isReq *authz.IsRequest, elapsed time.Duration, err error) {
+	if m.decisionLogger == nil {
+		return
+	}
+
+	allowed := err == nil
+	if errors.Is(err, aerr.ErrAuthorizationFailed) {
+		err = nil
+	}
+
+	event := internal.NewDecisionEvent(
+		isReq.GetIdentityContext(),
+		isReq.GetPolicyContext(),
+		isReq.GetResourceContext(),
+		allowed,
+		elapsed,
+		err,
+	)
+	event.RequestID = RequestIDFromContext(ctx)
+
+	m.decisionLogger(ctx, event)
+}
+
 func (m *Middleware) isAllowedMethod(ctx context.Context) bool {
 	method, _ := grpc.Method(ctx)
 	return m.allowedMethods.Contains(method)
 }
 
-func (m *Middleware) resourceContext(ctx context.Context, req interface{}) (*structpb.Struct, error) {
+func (m *Middleware) isTrustedPeer(ctx context.Context) bool {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return false
+	}
+
+	var chain []*x509.Certificate
+	if info, ok := p.AuthInfo.(credentials.TLSInfo); ok {
+		chain = internal.VerifiedChain(&info.State)
+	}
+
+	addr := ""
+	if p.Addr != nil {
+		addr = p.Addr.String()
+	}
+
+	return m.trusted.Trusts(addr, chain)
+}
+
+func (m *Middleware) resourceContext(ctx context.Context, req interface{}, identity *api.IdentityContext) (*structpb.Struct, error) {
 	res := map[string]interface{}{}
 	for _, mapper := range m.resourceMappers {
 		mapper(ctx, req, res)
 	}
 
-	return structpb.NewStruct(res)
+	if m.manualIdentity != "" {
+		internal.AddManualIdentity(identity, m.manualIdentity, res)
+	}
+
+	return internal.NewResource(res, m.omitEmptyResource)
 }
 
-func methodPolicyMapper(policyRoot string) StringMapper {
-	return func(ctx context.Context, _ interface{}) string {
-		method, _ := grpc.Method(ctx)
-		path := internal.ToPolicyPath(method)
+// PolicyPathFor returns the policy path the middleware's default policy mapper derives from a full gRPC method name
+// (e.g. "/package.Service/Method"), optionally prefixed with a policy root.
+//
+// It can be used to test that service methods map to the expected policy paths.
+func PolicyPathFor(fullMethod, root string) string {
+	path := internal.ToPolicyPath(fullMethod)
 
-		if policyRoot == "" {
-			return path
-		}
+	if root == "" {
+		return path
+	}
 
-		return fmt.Sprintf("%s.%s", policyRoot, internal.ToPolicyPath(method))
+	return fmt.Sprintf("%s.%s", root, path)
+}
+
+func methodPolicyMapper(policyRoot string, format *middleware.PathFormat) StringMapper {
+	return func(ctx context.Context, _ interface{}) string {
+		method, _ := grpc.Method(ctx)
+		return format.Join(policyRoot, strings.Split(internal.ToPolicyPath(method), ".")...)
 	}
 }
 
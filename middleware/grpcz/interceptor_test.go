@@ -2,15 +2,27 @@ package grpcz_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"runtime"
 	"testing"
 
+	"github.com/aserto-dev/go-aserto/middleware"
 	grpcmw "github.com/aserto-dev/go-aserto/middleware/grpcz"
 	"github.com/aserto-dev/go-aserto/middleware/internal/mock"
 	"github.com/aserto-dev/go-aserto/middleware/internal/test"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
 	"github.com/aserto-dev/go-authorizer/pkg/aerr"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 type TestCase struct {
@@ -27,6 +39,8 @@ type testOptions struct {
 
 const DefaultPolicyPath = "policy.path"
 
+var errNotFound = status.Error(codes.NotFound, "policy not found")
+
 func NewTest(t *testing.T, name string, options *testOptions) *TestCase {
 	if options.ExpectedRequest == nil && options.PolicyPath == "" {
 		options.PolicyPath = DefaultPolicyPath
@@ -78,6 +92,45 @@ func TestAuthorizer(t *testing.T) {
 				},
 			},
 		),
+		NewTest(
+			t,
+			"unmapped authorizer errors should fail",
+			&testOptions{
+				Options: test.Options{
+					Err: errNotFound,
+				},
+				expectedErr: errNotFound,
+			},
+		),
+		NewTest(
+			t,
+			"authorizer errors mapped to deny should be unauthorized",
+			&testOptions{
+				Options: test.Options{
+					Err: errNotFound,
+				},
+				expectedErr: aerr.ErrAuthorizationFailed,
+				callback: func(mw *grpcmw.Middleware) {
+					mw.WithErrorCodeMapping(
+						map[codes.Code]middleware.Outcome{codes.NotFound: middleware.OutcomeDeny},
+					).Identity.Subject().ID(test.DefaultUsername)
+				},
+			},
+		),
+		NewTest(
+			t,
+			"authorizer errors mapped to allow should succeed",
+			&testOptions{
+				Options: test.Options{
+					Err: errNotFound,
+				},
+				callback: func(mw *grpcmw.Middleware) {
+					mw.WithErrorCodeMapping(
+						map[codes.Code]middleware.Outcome{codes.NotFound: middleware.OutcomeAllow},
+					).Identity.Subject().ID(test.DefaultUsername)
+				},
+			},
+		),
 	}
 
 	for _, test := range tests {
@@ -130,3 +183,347 @@ func runStream(mw *grpcmw.Middleware) error {
 		},
 	)
 }
+
+func TestIdentityInContext(t *testing.T) {
+	base := test.NewTest(t, "identity in context", &test.Options{PolicyPath: DefaultPolicyPath})
+	mw := grpcmw.New(base.Client, test.Policy(DefaultPolicyPath))
+	mw.Identity.Subject().ID(test.DefaultUsername)
+
+	_, err := mw.Unary()(
+		context.Background(),
+		nil,
+		&grpc.UnaryServerInfo{},
+		func(ctx context.Context, _ interface{}) (interface{}, error) {
+			assert.Equal(t, test.DefaultUsername, middleware.IdentityFromContext(ctx).GetIdentity())
+			return nil, nil //nolint: nilnil
+		},
+	)
+	assert.NoError(t, err)
+
+	err = mw.Stream()(
+		nil,
+		&mock.ServerStream{},
+		&grpc.StreamServerInfo{},
+		func(_ interface{}, stream grpc.ServerStream) error {
+			assert.Equal(t, test.DefaultUsername, middleware.IdentityFromContext(stream.Context()).GetIdentity())
+			return nil
+		},
+	)
+	assert.NoError(t, err)
+}
+
+func TestTrustedPeers(t *testing.T) {
+	trusted := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1234}
+	untrusted := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1234}
+
+	for name, tc := range map[string]struct {
+		cidrs    []string
+		addr     net.Addr
+		expected error
+	}{
+		"trusted":      {cidrs: []string{"10.0.0.0/8"}, addr: trusted},
+		"untrusted":    {cidrs: []string{"10.0.0.0/8"}, addr: untrusted, expected: aerr.ErrAuthorizationFailed},
+		"invalid cidr": {cidrs: []string{"10.0.0.0/8", "10.0.0.0/33"}, addr: trusted, expected: middleware.ErrInvalidTrustedPeer},
+	} {
+		t.Run(name, func(t *testing.T) {
+			base := test.NewTest(t, name, &test.Options{PolicyPath: DefaultPolicyPath, Reject: true})
+			mw := grpcmw.New(base.Client, test.Policy(DefaultPolicyPath)).WithTrustedPeers(tc.cidrs...)
+			mw.Identity.Subject().ID(test.DefaultUsername)
+
+			ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: tc.addr})
+
+			_, err := mw.Unary()(
+				ctx,
+				nil,
+				&grpc.UnaryServerInfo{},
+				func(_ context.Context, _ interface{}) (interface{}, error) {
+					return nil, nil //nolint: nilnil
+				},
+			)
+
+			if tc.expected == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.expected)
+			}
+		})
+	}
+}
+
+func TestWithoutMessageInspection(t *testing.T) {
+	base := test.NewTest(t, "without message inspection", &test.Options{PolicyPath: DefaultPolicyPath})
+	mw := grpcmw.New(base.Client, test.Policy(DefaultPolicyPath)).
+		WithoutMessageInspection().
+		WithResourceFromFields("id").
+		WithResourceMapper(func(_ context.Context, req interface{}, _ map[string]interface{}) {
+			assert.Nil(t, req)
+		})
+	mw.Identity.Subject().ID(test.DefaultUsername)
+
+	_, err := mw.Unary()(
+		context.Background(),
+		&grpc_health_v1.HealthCheckRequest{Service: "svc"},
+		&grpc.UnaryServerInfo{},
+		func(_ context.Context, req interface{}) (interface{}, error) {
+			assert.NotNil(t, req)
+			return nil, nil //nolint: nilnil
+		},
+	)
+	assert.NoError(t, err)
+}
+
+// serviceAuthorizer denies requests for the services in denied and records the services of all requests.
+// Requests authorized without a message have an empty service.
+type serviceAuthorizer struct {
+	authz.AuthorizerClient
+
+	denied   map[string]bool
+	services []string
+}
+
+func (c *serviceAuthorizer) Is(_ context.Context, in *authz.IsRequest, _ ...grpc.CallOption) (*authz.IsResponse, error) {
+	service := in.GetResourceContext().GetFields()["service"].GetStringValue()
+	c.services = append(c.services, service)
+
+	return &authz.IsResponse{
+		Decisions: []*authz.Decision{{Decision: test.DefaultDecision, Is: !c.denied[service]}},
+	}, nil
+}
+
+func TestPerMessageStreamAuthorization(t *testing.T) {
+	for name, tc := range map[string]struct {
+		denied   []string
+		called   bool
+		services []string
+		err      error
+	}{
+		"allowed":        {called: true, services: []string{"", "svc", "svc"}, err: io.EOF},
+		"message denied": {denied: []string{"svc"}, called: true, services: []string{"", "svc"}, err: aerr.ErrAuthorizationFailed},
+		"stream denied":  {denied: []string{""}, services: []string{""}, err: aerr.ErrAuthorizationFailed},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := &serviceAuthorizer{denied: map[string]bool{}}
+			for _, service := range tc.denied {
+				client.denied[service] = true
+			}
+
+			mw := grpcmw.New(client, test.Policy(DefaultPolicyPath)).
+				WithPerMessageStreamAuthorization().
+				WithResourceFromFields("service")
+			mw.Identity.Subject().ID(test.DefaultUsername)
+
+			stream := &mock.ServerStream{Messages: []proto.Message{
+				&grpc_health_v1.HealthCheckRequest{Service: "svc"},
+				&grpc_health_v1.HealthCheckRequest{Service: "svc"},
+			}}
+
+			called := false
+
+			err := mw.Stream()(nil, stream, &grpc.StreamServerInfo{}, func(_ interface{}, stream grpc.ServerStream) error {
+				called = true
+
+				for {
+					assert.Equal(t, test.DefaultUsername, middleware.IdentityFromContext(stream.Context()).GetIdentity())
+
+					if err := stream.RecvMsg(&grpc_health_v1.HealthCheckRequest{}); err != nil {
+						return err
+					}
+				}
+			})
+
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.called, called)
+			assert.Equal(t, tc.services, client.services)
+		})
+	}
+}
+
+// TestPerMessageStreamContext reads the stream's context while messages are received. Run it with -race.
+func TestPerMessageStreamContext(t *testing.T) {
+	client := &serviceAuthorizer{}
+
+	mw := grpcmw.New(client, test.Policy(DefaultPolicyPath)).
+		WithPerMessageStreamAuthorization().
+		WithResourceFromFields("service")
+	mw.Identity.Subject().ID(test.DefaultUsername)
+
+	messages := make([]proto.Message, 100)
+	for i := range messages {
+		messages[i] = &grpc_health_v1.HealthCheckRequest{Service: "svc"}
+	}
+
+	stream := &mock.ServerStream{Messages: messages}
+
+	err := mw.Stream()(nil, stream, &grpc.StreamServerInfo{}, func(_ interface{}, stream grpc.ServerStream) error {
+		started, done := make(chan struct{}), make(chan struct{})
+		defer close(done)
+
+		// Handlers commonly send on a separate goroutine, which reads the stream's context while messages are received.
+		go func() {
+			close(started)
+
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					_ = stream.Context()
+
+					runtime.Gosched()
+				}
+			}
+		}()
+
+		<-started
+
+		for {
+			if err := stream.RecvMsg(&grpc_health_v1.HealthCheckRequest{}); err != nil {
+				return err
+			}
+		}
+	})
+
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestFailureMode(t *testing.T) {
+	for name, failOpen := range map[string]bool{"fail closed": false, "fail open": true} {
+		t.Run(name, func(t *testing.T) {
+			base := test.NewTest(t, name, &test.Options{
+				PolicyPath: DefaultPolicyPath,
+				Err:        status.Error(codes.Unavailable, "unavailable"),
+			})
+			mw := grpcmw.New(base.Client, test.Policy(DefaultPolicyPath))
+			mw.Identity.Subject().ID(test.DefaultUsername)
+
+			if failOpen {
+				mw.WithFailureMode(middleware.FailOpen)
+			}
+
+			for _, runner := range runners() {
+				err := runner(mw)
+				if failOpen {
+					assert.NoError(t, err)
+				} else {
+					assert.Equal(t, codes.Unavailable, status.Code(err))
+				}
+			}
+		})
+	}
+}
+
+func TestPolicyPathFor(t *testing.T) {
+	assert.Equal(t, "myapp.v1.Users.GetUser", grpcmw.PolicyPathFor("/myapp.v1.Users/GetUser", ""))
+	assert.Equal(t, "root.myapp.v1.Users.GetUser", grpcmw.PolicyPathFor("/myapp.v1.Users/GetUser", "root"))
+}
+
+func TestDecisionPostProcessor(t *testing.T) {
+	errFreeze := errors.New("freeze")
+
+	for name, tc := range map[string]struct {
+		reject      bool
+		override    bool
+		err         error
+		expectedErr error
+	}{
+		"allowed decision is overridden": {expectedErr: aerr.ErrAuthorizationFailed},
+		"denied decision is overridden":  {reject: true, override: true},
+		"post-processor errors fail":     {err: errFreeze, expectedErr: errFreeze},
+	} {
+		t.Run(name, func(t *testing.T) {
+			base := test.NewTest(t, name, &test.Options{PolicyPath: DefaultPolicyPath, Reject: tc.reject})
+			mw := grpcmw.New(base.Client, test.Policy(DefaultPolicyPath)).WithDecisionPostProcessor(
+				func(_ context.Context, allowed bool, _ *authz.IsResponse) (bool, error) {
+					assert.Equal(t, !tc.reject, allowed)
+					return tc.override, tc.err
+				},
+			)
+			mw.Identity.Subject().ID(test.DefaultUsername)
+
+			err := runUnary(mw)
+			if tc.expectedErr == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
+
+func TestWithDecision(t *testing.T) {
+	base := test.NewTest(t, "with decision", &test.Options{
+		ExpectedRequest: test.Request(test.PolicyPath(DefaultPolicyPath), test.WithDecision("visible")),
+	})
+	base.Client.WithDecisions(
+		&authz.Decision{Decision: "allowed", Is: true},
+		&authz.Decision{Decision: "visible", Is: false},
+	)
+
+	mw := grpcmw.New(base.Client, test.Policy(DefaultPolicyPath)).WithDecision("visible")
+	mw.Identity.Subject().ID(test.DefaultUsername)
+
+	assert.ErrorIs(t, runUnary(mw), aerr.ErrAuthorizationFailed)
+}
+
+func TestDenialReasonInError(t *testing.T) {
+	base := test.NewTest(t, "denial reason", &test.Options{PolicyPath: DefaultPolicyPath, Reject: true})
+	mw := grpcmw.New(base.Client, test.Policy(DefaultPolicyPath)).WithDenialReasonInError()
+	mw.Identity.Subject().ID(test.DefaultUsername)
+
+	err := runUnary(mw)
+	assert.ErrorIs(t, err, aerr.ErrAuthorizationFailed)
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.PermissionDenied, st.Code())
+
+	details := st.Details()
+	assert.Len(t, details, 2)
+
+	info, ok := details[0].(*errdetails.ErrorInfo)
+	assert.True(t, ok)
+	assert.Equal(t, grpcmw.DenialReason, info.GetReason())
+	assert.Equal(t, map[string]string{"policy_path": DefaultPolicyPath, "decision": test.DefaultDecision}, info.GetMetadata())
+
+	resp, ok := details[1].(*authz.IsResponse)
+	assert.True(t, ok)
+	assert.False(t, resp.GetDecisions()[0].GetIs())
+}
+
+func TestDecisionLogger(t *testing.T) {
+	for name, tc := range map[string]struct {
+		reject  bool
+		err     error
+		allowed bool
+	}{
+		"allowed": {allowed: true},
+		"denied":  {reject: true},
+		"failed":  {err: status.Error(codes.Unavailable, "unavailable")},
+	} {
+		t.Run(name, func(t *testing.T) {
+			base := test.NewTest(t, name, &test.Options{PolicyPath: DefaultPolicyPath, Reject: tc.reject, Err: tc.err})
+
+			var events []middleware.DecisionEvent
+
+			mw := grpcmw.New(base.Client, test.Policy(DefaultPolicyPath)).WithDecisionLogger(
+				func(_ context.Context, event middleware.DecisionEvent) {
+					events = append(events, event)
+				},
+			)
+			mw.Identity.Subject().ID(test.DefaultUsername)
+
+			_ = runUnary(mw)
+
+			assert.Len(t, events, 1)
+			assert.Equal(t, test.DefaultUsername, events[0].Identity.GetIdentity())
+			assert.Equal(t, DefaultPolicyPath, events[0].PolicyPath)
+			assert.Equal(t, test.DefaultDecision, events[0].Decision)
+			assert.Equal(t, tc.allowed, events[0].Allowed)
+
+			if tc.err == nil {
+				assert.NoError(t, events[0].Err)
+			} else {
+				assert.Error(t, events[0].Err)
+			}
+		})
+	}
+}
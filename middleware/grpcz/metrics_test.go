@@ -0,0 +1,54 @@
+package grpcz_test
+
+import (
+	"strings"
+	"testing"
+
+	grpcmw "github.com/aserto-dev/go-aserto/middleware/grpcz"
+	"github.com/aserto-dev/go-aserto/middleware/internal/test"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+const expectedDecisions = `
+# HELP aserto_authz_decisions_total Number of authorization decisions.
+# TYPE aserto_authz_decisions_total counter
+aserto_authz_decisions_total{allowed="false",decision="allowed"} 1
+aserto_authz_decisions_total{allowed="true",decision="allowed"} 2
+`
+
+func TestMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	allow := test.NewTest(t, "allow", &test.Options{PolicyPath: DefaultPolicyPath})
+	deny := test.NewTest(t, "deny", &test.Options{PolicyPath: DefaultPolicyPath, Reject: true})
+
+	allowMW := grpcmw.New(allow.Client, test.Policy(DefaultPolicyPath)).WithMetrics(registry)
+	allowMW.Identity.Subject().ID(test.DefaultUsername)
+
+	// Collectors registered by another middleware are reused.
+	denyMW := grpcmw.New(deny.Client, test.Policy(DefaultPolicyPath)).WithMetrics(registry)
+	denyMW.Identity.Subject().ID(test.DefaultUsername)
+
+	assert.NoError(t, runUnary(allowMW))
+	assert.NoError(t, runStream(allowMW))
+	assert.Error(t, runUnary(denyMW))
+
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expectedDecisions), "aserto_authz_decisions_total"))
+	assert.Equal(t, 1, testutil.CollectAndCount(registry, "aserto_authz_duration_seconds"))
+}
+
+func TestNoMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	base := test.NewTest(t, "no metrics", &test.Options{PolicyPath: DefaultPolicyPath})
+	mw := grpcmw.New(base.Client, test.Policy(DefaultPolicyPath))
+	mw.Identity.Subject().ID(test.DefaultUsername)
+
+	assert.NoError(t, runUnary(mw))
+
+	count, err := testutil.GatherAndCount(registry)
+	assert.NoError(t, err)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,90 @@
+package grpcz
+
+import (
+	"context"
+
+	"github.com/aserto-dev/go-aserto/middleware/internal"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// altsAuthInfo is implemented by the AuthInfo of ALTS connections.
+type altsAuthInfo interface {
+	PeerServiceAccount() string
+}
+
+// WithPeerInfoInResource adds the transport-level authentication info of the caller to the resource context under
+// the given field, so policies can combine the transport identity with the caller's application identity.
+//
+// The value is an object with the following fields. Fields that don't apply to the connection are omitted.
+//
+//	{
+//	  "address": "10.0.0.1:53412",        // the peer's network address
+//	  "auth_type": "tls",                 // the authentication protocol ("tls", "alts", etc.)
+//	  "subject": "CN=api,O=Acme",         // the verified client certificate's subject
+//	  "common_name": "api",               // the verified client certificate's subject common name
+//	  "dns_names": ["api.acme.com"],      // the verified client certificate's DNS SANs
+//	  "uris": ["spiffe://acme.com/api"],  // the verified client certificate's URI SANs (e.g. SPIFFE IDs)
+//	  "service_account": "api@acme.com"   // the peer's service account on ALTS connections
+//	}
+//
+// Certificate fields are only set if the client certificate was verified. Calls without peer information don't add
+// the field.
+func (m *Middleware) WithPeerInfoInResource(field string) *Middleware {
+	return m.WithResourceMapper(func(ctx context.Context, _ interface{}, resource map[string]interface{}) {
+		if info := peerInfo(ctx); len(info) > 0 {
+			resource[field] = info
+		}
+	})
+}
+
+func peerInfo(ctx context.Context) map[string]interface{} {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	info := map[string]interface{}{}
+
+	if p.Addr != nil {
+		info["address"] = p.Addr.String()
+	}
+
+	if p.AuthInfo == nil {
+		return info
+	}
+
+	info["auth_type"] = p.AuthInfo.AuthType()
+
+	switch auth := p.AuthInfo.(type) {
+	case credentials.TLSInfo:
+		if chain := internal.VerifiedChain(&auth.State); len(chain) > 0 {
+			leaf := chain[0]
+
+			info["subject"] = leaf.Subject.String()
+			info["common_name"] = leaf.Subject.CommonName
+			info["dns_names"] = toList(leaf.DNSNames)
+
+			uris := make([]string, len(leaf.URIs))
+			for i, uri := range leaf.URIs {
+				uris[i] = uri.String()
+			}
+
+			info["uris"] = toList(uris)
+		}
+	case altsAuthInfo:
+		info["service_account"] = auth.PeerServiceAccount()
+	}
+
+	return info
+}
+
+// toList converts a slice of strings to a list that can be added to a resource context.
+func toList(values []string) []interface{} {
+	list := make([]interface{}, len(values))
+	for i, v := range values {
+		list[i] = v
+	}
+
+	return list
+}
@@ -0,0 +1,91 @@
+package grpcz_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/url"
+	"testing"
+
+	grpcmw "github.com/aserto-dev/go-aserto/middleware/grpcz"
+	"github.com/aserto-dev/go-aserto/middleware/internal/test"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestPeerInfoInResource(t *testing.T) {
+	spiffeID, err := url.Parse("spiffe://acme.com/api")
+	assert.NoError(t, err)
+
+	cert := &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "api", Organization: []string{"Acme"}},
+		DNSNames: []string{"api.acme.com"},
+		URIs:     []*url.URL{spiffeID},
+	}
+
+	addr := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1234}
+
+	for name, tc := range map[string]struct {
+		peer     *peer.Peer
+		resource map[string]interface{}
+	}{
+		"no peer": {resource: map[string]interface{}{}},
+		"insecure": {
+			peer:     &peer.Peer{Addr: addr},
+			resource: map[string]interface{}{"peer": map[string]interface{}{"address": "10.0.0.1:1234"}},
+		},
+		"unverified tls": {
+			peer: &peer.Peer{
+				Addr:     addr,
+				AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}},
+			},
+			resource: map[string]interface{}{"peer": map[string]interface{}{"address": "10.0.0.1:1234", "auth_type": "tls"}},
+		},
+		"verified tls": {
+			peer: &peer.Peer{
+				Addr:     addr,
+				AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}},
+			},
+			resource: map[string]interface{}{"peer": map[string]interface{}{
+				"address":     "10.0.0.1:1234",
+				"auth_type":   "tls",
+				"subject":     "CN=api,O=Acme",
+				"common_name": "api",
+				"dns_names":   []interface{}{"api.acme.com"},
+				"uris":        []interface{}{"spiffe://acme.com/api"},
+			}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			resource, err := structpb.NewStruct(tc.resource)
+			assert.NoError(t, err)
+
+			base := test.NewTest(t, name, &test.Options{
+				ExpectedRequest: test.Request(test.PolicyPath(DefaultPolicyPath), test.Resource(resource)),
+			})
+
+			mw := grpcmw.New(base.Client, test.Policy(DefaultPolicyPath)).WithPeerInfoInResource("peer")
+			mw.Identity.Subject().ID(test.DefaultUsername)
+
+			ctx := context.Background()
+			if tc.peer != nil {
+				ctx = peer.NewContext(ctx, tc.peer)
+			}
+
+			_, err = mw.Unary()(
+				ctx,
+				nil,
+				&grpc.UnaryServerInfo{},
+				func(_ context.Context, _ interface{}) (interface{}, error) {
+					return nil, nil //nolint: nilnil
+				},
+			)
+			assert.NoError(t, err)
+		})
+	}
+}
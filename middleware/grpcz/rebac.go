@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/aserto-dev/go-aserto/middleware"
 	"github.com/aserto-dev/go-aserto/middleware/internal"
 	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
 	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
@@ -12,6 +13,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/samber/lo"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
@@ -27,6 +29,7 @@ type RebacMiddleware struct {
 	objType         string
 	ignoredPaths    internal.Lookup[string]
 	allowedMethods  internal.Lookup[string]
+	errorOutcomes   map[codes.Code]middleware.Outcome
 }
 
 /*
@@ -89,14 +92,23 @@ func (c *RebacMiddleware) WithAllowedMethods(methods ...string) *RebacMiddleware
 	return c
 }
 
+// WithErrorCodeMapping determines how the middleware responds when the authorizer fails with specific gRPC codes.
+// Errors with a code that isn't in the mapping fail the request.
+func (c *RebacMiddleware) WithErrorCodeMapping(mapping map[codes.Code]middleware.Outcome) *RebacMiddleware {
+	c.errorOutcomes = mapping
+	return c
+}
+
 func NewRebacMiddleware(authzClient AuthorizerClient, policy *Policy) *RebacMiddleware {
-	policyMapper := methodPolicyMapper("")
+	format := middleware.DefaultPathFormat()
+
+	policyMapper := methodPolicyMapper("", &format)
 	if policy.Path != "" {
 		policyMapper = nil
 	}
 
 	return &RebacMiddleware{
-		Identity:     (&IdentityBuilder{}).Subject().FromMetadata("authorization"),
+		Identity:     (&IdentityBuilder{}).Subject().FromMetadata("authorization").byDefault(),
 		client:       authzClient,
 		policy:       policy,
 		policyMapper: policyMapper,
@@ -153,17 +165,31 @@ func (c *RebacMiddleware) authorize(ctx context.Context, req interface{}) error
 		return nil
 	}
 
+	identity, err := c.Identity.build(ctx, req)
+	if err != nil {
+		return errors.Wrap(err, "failed to resolve identity")
+	}
+
 	resp, err := c.client.Is(
 		ctx,
 		&authz.IsRequest{
-			IdentityContext: c.identityContext(ctx, req),
+			IdentityContext: identity,
 			PolicyContext:   policyContext,
 			ResourceContext: resource,
 			PolicyInstance:  internal.DefaultPolicyInstance(c.policy),
 		},
 	)
 	if err != nil {
-		return errors.Wrap(err, "authorization call failed")
+		allowed, err := internal.ResolveError(ctx, c.errorOutcomes, nil, policyContext.GetPath(), err)
+		if err != nil {
+			return errors.Wrap(err, "authorization call failed")
+		}
+
+		if !allowed {
+			return aerr.ErrAuthorizationFailed
+		}
+
+		return nil
 	}
 
 	if len(resp.Decisions) == 0 {
@@ -202,10 +228,6 @@ func (c *RebacMiddleware) policyContext() *api.PolicyContext {
 	return policyContext
 }
 
-func (c *RebacMiddleware) identityContext(ctx context.Context, req interface{}) *api.IdentityContext {
-	return c.Identity.build(ctx, req)
-}
-
 func (c *RebacMiddleware) resourceContext(ctx context.Context, req interface{}) (*structpb.Struct, error) {
 	res := map[string]interface{}{}
 	for _, mapper := range c.resourceMappers {
@@ -0,0 +1,136 @@
+package grpcz
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDMetadataKey is the gRPC metadata key used to propagate request IDs.
+const RequestIDMetadataKey = "x-request-id"
+
+type requestIDKey struct{}
+
+// RequestIDMiddleware assigns an ID to each incoming request.
+//
+// The ID is read from the incoming x-request-id metadata or generated if it's missing. It is sent back to the caller
+// in the response headers, and added to the request context (see RequestIDFromContext) and to the context's
+// zerolog logger.
+type RequestIDMiddleware struct{}
+
+// NewRequestIDMiddleware returns middleware that assigns request IDs to incoming requests.
+func NewRequestIDMiddleware() *RequestIDMiddleware {
+	return &RequestIDMiddleware{}
+}
+
+// Unary returns a grpc.UnaryServerInterceptor that assigns request IDs.
+func (m *RequestIDMiddleware) Unary() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		return handler(withRequestID(ctx), req)
+	}
+}
+
+// Stream returns a grpc.StreamServerInterceptor that assigns request IDs.
+func (m *RequestIDMiddleware) Stream() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		return handler(srv, &serverStream{ServerStream: stream, ctx: withRequestID(stream.Context())})
+	}
+}
+
+// RequestIDFromContext returns the request ID added to the context by RequestIDMiddleware.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithRequestIDPropagation includes the request ID assigned by RequestIDMiddleware in the metadata of calls to the
+// authorizer, which allows correlating authorizer decisions with the requests that triggered them.
+//
+// The request ID middleware must run before the authorization middleware (see ServerInterceptors).
+func (m *Middleware) WithRequestIDPropagation() *Middleware {
+	m.propagateRID = true
+	return m
+}
+
+// ServerInterceptors returns the unary and stream interceptors of the given middleware in the order in which they
+// should run.
+//
+// The request ID middleware runs before authorization so that denied requests are also assigned an ID and
+// authorization logs include it. If ridmw is nil, only the authorization interceptors are returned.
+//
+// For example:
+//
+//	unary, stream := grpcz.ServerInterceptors(mw, grpcz.NewRequestIDMiddleware())
+//
+//	server := grpc.NewServer(
+//		grpc.ChainUnaryInterceptor(unary...),
+//		grpc.ChainStreamInterceptor(stream...),
+//	)
+func ServerInterceptors(
+	mw *Middleware,
+	ridmw *RequestIDMiddleware,
+) ([]grpc.UnaryServerInterceptor, []grpc.StreamServerInterceptor) {
+	var (
+		unary  []grpc.UnaryServerInterceptor
+		stream []grpc.StreamServerInterceptor
+	)
+
+	if ridmw != nil {
+		unary = append(unary, ridmw.Unary())
+		stream = append(stream, ridmw.Stream())
+	}
+
+	return append(unary, mw.Unary()), append(stream, mw.Stream())
+}
+
+func withRequestID(ctx context.Context) context.Context {
+	var id string
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIDMetadataKey); len(values) > 0 {
+			id = values[0]
+		}
+	}
+
+	if id == "" {
+		id = newRequestID()
+	}
+
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadataKey, id))
+
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+	logger := zerolog.Ctx(ctx).With().Str("request_id", id).Logger()
+
+	return logger.WithContext(ctx)
+}
+
+// outgoingRequestID returns a copy of ctx with the request ID added to the outgoing metadata.
+func outgoingRequestID(ctx context.Context) context.Context {
+	id := RequestIDFromContext(ctx)
+	if id == "" {
+		return ctx
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, RequestIDMetadataKey, id)
+}
+
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+
+	return hex.EncodeToString(b[:])
+}
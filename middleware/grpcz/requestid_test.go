@@ -0,0 +1,104 @@
+package grpcz_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	grpcmw "github.com/aserto-dev/go-aserto/middleware/grpcz"
+	"github.com/aserto-dev/go-aserto/middleware/internal/test"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestServerInterceptors(t *testing.T) {
+	base := test.NewTest(t, "server interceptors", &test.Options{PolicyPath: DefaultPolicyPath})
+	mw := grpcmw.New(base.Client, test.Policy(DefaultPolicyPath))
+	mw.Identity.Subject().ID(test.DefaultUsername)
+
+	unary, stream := grpcmw.ServerInterceptors(mw, grpcmw.NewRequestIDMiddleware())
+	assert.Len(t, unary, 2)
+	assert.Len(t, stream, 2)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(grpcmw.RequestIDMetadataKey, "rid-1"))
+
+	var requestID string
+
+	// The request ID interceptor runs first and passes its context on to authorization.
+	_, err := unary[0](ctx, nil, &grpc.UnaryServerInfo{},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			requestID = grpcmw.RequestIDFromContext(ctx)
+
+			return unary[1](ctx, req, &grpc.UnaryServerInfo{},
+				func(_ context.Context, _ interface{}) (interface{}, error) {
+					return nil, nil //nolint: nilnil
+				},
+			)
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, "rid-1", requestID)
+}
+
+func TestServerInterceptorsWithoutRequestID(t *testing.T) {
+	mw := grpcmw.New(nil, test.Policy(DefaultPolicyPath))
+
+	unary, stream := grpcmw.ServerInterceptors(mw, nil)
+	assert.Len(t, unary, 1)
+	assert.Len(t, stream, 1)
+}
+
+func TestGeneratedRequestID(t *testing.T) {
+	var requestID string
+
+	_, err := grpcmw.NewRequestIDMiddleware().Unary()(context.Background(), nil, &grpc.UnaryServerInfo{},
+		func(ctx context.Context, _ interface{}) (interface{}, error) {
+			requestID = grpcmw.RequestIDFromContext(ctx)
+			return nil, nil //nolint: nilnil
+		},
+	)
+	assert.NoError(t, err)
+	assert.Len(t, requestID, 32)
+}
+
+// metadataClient records the outgoing metadata of authorization calls.
+type metadataClient struct {
+	authz.AuthorizerClient
+
+	md metadata.MD
+}
+
+func (c *metadataClient) Is(ctx context.Context, in *authz.IsRequest, opts ...grpc.CallOption) (*authz.IsResponse, error) {
+	c.md, _ = metadata.FromOutgoingContext(ctx)
+	return c.AuthorizerClient.Is(ctx, in, opts...)
+}
+
+func TestRequestIDPropagation(t *testing.T) {
+	base := test.NewTest(t, "request id propagation", &test.Options{PolicyPath: DefaultPolicyPath})
+	client := &metadataClient{AuthorizerClient: base.Client}
+
+	var event middleware.DecisionEvent
+
+	mw := grpcmw.New(client, test.Policy(DefaultPolicyPath)).
+		WithRequestIDPropagation().
+		WithDecisionLogger(func(_ context.Context, e middleware.DecisionEvent) { event = e })
+	mw.Identity.Subject().ID(test.DefaultUsername)
+
+	unary, _ := grpcmw.ServerInterceptors(mw, grpcmw.NewRequestIDMiddleware())
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(grpcmw.RequestIDMetadataKey, "rid-1"))
+
+	_, err := unary[0](ctx, nil, &grpc.UnaryServerInfo{},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return unary[1](ctx, req, &grpc.UnaryServerInfo{},
+				func(_ context.Context, _ interface{}) (interface{}, error) {
+					return nil, nil //nolint: nilnil
+				},
+			)
+		},
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"rid-1"}, client.md.Get(grpcmw.RequestIDMetadataKey))
+	assert.Equal(t, "rid-1", event.RequestID)
+}
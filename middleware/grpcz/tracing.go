@@ -0,0 +1,18 @@
+package grpcz
+
+import (
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// WithTracer instructs the middleware to wrap each authorization call in an OpenTelemetry span named
+// "aserto.authorize". The span records the policy path, decision name, and outcome of the call.
+func (m *Middleware) WithTracer(tracer trace.Tracer) *Middleware {
+	if tracer == nil {
+		tracer = noop.NewTracerProvider().Tracer("")
+	}
+
+	m.tracer = tracer
+
+	return m
+}
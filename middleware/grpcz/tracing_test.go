@@ -0,0 +1,50 @@
+package grpcz_test
+
+import (
+	"testing"
+
+	grpcmw "github.com/aserto-dev/go-aserto/middleware/grpcz"
+	"github.com/aserto-dev/go-aserto/middleware/internal/test"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracer(t *testing.T) {
+	for name, reject := range map[string]bool{"allowed": false, "denied": true} {
+		t.Run(name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+			base := test.NewTest(t, name, &test.Options{PolicyPath: DefaultPolicyPath, Reject: reject})
+			mw := grpcmw.New(base.Client, test.Policy(DefaultPolicyPath)).WithTracer(tracer)
+			mw.Identity.Subject().ID(test.DefaultUsername)
+
+			_ = runUnary(mw)
+
+			spans := recorder.Ended()
+			assert.Len(t, spans, 1)
+			assert.Equal(t, "aserto.authorize", spans[0].Name())
+			assert.Contains(t, spans[0].Attributes(), attribute.String("aserto.policy.path", DefaultPolicyPath))
+			assert.Contains(t, spans[0].Attributes(), attribute.StringSlice("aserto.decision", []string{test.DefaultDecision}))
+			assert.Contains(t, spans[0].Attributes(), attribute.Bool("aserto.allowed", !reject))
+
+			if reject {
+				assert.Equal(t, codes.Error, spans[0].Status().Code)
+			} else {
+				assert.Equal(t, codes.Unset, spans[0].Status().Code)
+			}
+		})
+	}
+}
+
+func TestNoTracer(t *testing.T) {
+	base := test.NewTest(t, "no tracer", &test.Options{PolicyPath: DefaultPolicyPath})
+	mw := grpcmw.New(base.Client, test.Policy(DefaultPolicyPath))
+	mw.Identity.Subject().ID(test.DefaultUsername)
+
+	assert.NoError(t, runUnary(mw))
+	assert.NoError(t, runStream(mw))
+}
@@ -0,0 +1,64 @@
+package grpcz_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	grpcmw "github.com/aserto-dev/go-aserto/middleware/grpcz"
+	"github.com/aserto-dev/go-aserto/middleware/internal/test"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestDecisionTree(t *testing.T) {
+	path, err := structpb.NewStruct(map[string]interface{}{
+		"myapp.GetUser":    map[string]interface{}{"allowed": true},
+		"myapp.DeleteUser": map[string]interface{}{"allowed": false},
+	})
+	assert.NoError(t, err)
+
+	base := test.NewTest(t, "decision tree", &test.Options{})
+	base.Client.WithDecisionTree(path)
+
+	mw := grpcmw.New(base.Client, test.Policy(DefaultPolicyPath))
+	identity := &api.IdentityContext{Type: api.IdentityType_IDENTITY_TYPE_SUB, Identity: test.DefaultUsername}
+
+	tree, err := mw.DecisionTree(context.Background(), identity, "myapp", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]map[string]bool{
+		"myapp.GetUser":    {"allowed": true},
+		"myapp.DeleteUser": {"allowed": false},
+	}, tree)
+}
+
+func TestDecisionTreeFormat(t *testing.T) {
+	path, err := structpb.NewStruct(map[string]interface{}{
+		"myapp.GetUser":    map[string]interface{}{"allowed": true},
+		"myapp.DeleteUser": map[string]interface{}{"allowed": false},
+	})
+	assert.NoError(t, err)
+
+	base := test.NewTest(t, "decision tree format", &test.Options{})
+	base.Client.WithDecisionTree(path)
+
+	mw := grpcmw.New(base.Client, test.Policy(DefaultPolicyPath)).
+		WithDecisionTreeFormat(middleware.DecisionTreeFormat{TrimPrefix: "myapp", AllowedOnly: true})
+	identity := &api.IdentityContext{Type: api.IdentityType_IDENTITY_TYPE_SUB, Identity: test.DefaultUsername}
+
+	tree, err := mw.DecisionTree(context.Background(), identity, "myapp", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]map[string]bool{"GetUser": {"allowed": true}}, tree)
+}
+
+func TestDecisionTreeError(t *testing.T) {
+	base := test.NewTest(t, "decision tree error", &test.Options{Err: status.Error(codes.Unavailable, "down")})
+
+	mw := grpcmw.New(base.Client, test.Policy(DefaultPolicyPath))
+
+	_, err := mw.DecisionTree(context.Background(), &api.IdentityContext{}, "myapp", nil)
+	assert.Error(t, err)
+}
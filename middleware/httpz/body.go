@@ -0,0 +1,168 @@
+package httpz
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultMaxBodySize is the largest request body, in bytes, that WithResourceFromBody and WithPolicyFromGraphQL read
+// by default.
+const DefaultMaxBodySize = 1 << 20
+
+var errBodyTooLarge = errors.New("request body too large")
+
+// WithResourceFromBody adds the specified fields from JSON request bodies to the resource context.
+// Nested fields are selected using dot notation (e.g. "owner.id") and keep their nesting in the resource.
+//
+// The body is restored after it's read so handlers can read it again.
+// Requests with a content type other than JSON, bodies that aren't valid JSON objects, and bodies larger than
+// the limit set by WithMaxBodySize are skipped.
+func (m *Middleware) WithResourceFromBody(fields ...string) *Middleware {
+	return m.WithResourceMapper(func(r *http.Request, resource map[string]interface{}) {
+		body := m.readJSONBody(r)
+		if body == nil {
+			return
+		}
+
+		for _, field := range fields {
+			if value, ok := lookupField(body, field); ok {
+				setField(resource, field, value)
+			}
+		}
+	})
+}
+
+// WithMaxBodySize sets the largest request body, in bytes, that WithResourceFromBody and WithPolicyFromGraphQL read.
+// Default: DefaultMaxBodySize.
+func (m *Middleware) WithMaxBodySize(limit int64) *Middleware {
+	m.maxBodySize = limit
+	return m
+}
+
+func (m *Middleware) readJSONBody(r *http.Request) map[string]interface{} {
+	if r.Body == nil || r.Body == http.NoBody || !isJSON(r.Header.Get("Content-Type")) {
+		return nil
+	}
+
+	buf, err := readBody(r, m.bodyLimit())
+	if err != nil {
+		return nil
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(buf, &body); err != nil {
+		return nil
+	}
+
+	return body
+}
+
+func (m *Middleware) bodyLimit() int64 {
+	if m.maxBodySize <= 0 {
+		return DefaultMaxBodySize
+	}
+
+	return m.maxBodySize
+}
+
+// bufferedBody is a request body that was read ahead, up to a limit. Mappers read the buffered content without
+// modifying the request, so they can run concurrently. Reading the body returns its full content.
+type bufferedBody struct {
+	io.Reader
+	io.Closer
+
+	buf       []byte
+	truncated bool
+	err       error
+}
+
+// bufferBody reads up to limit bytes of the request body, if it isn't buffered already, and replaces the body with
+// a bufferedBody.
+func bufferBody(r *http.Request, limit int64) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return
+	}
+
+	if _, ok := r.Body.(*bufferedBody); ok {
+		return
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+
+	r.Body = &bufferedBody{
+		Reader:    io.MultiReader(bytes.NewReader(buf), r.Body),
+		Closer:    r.Body,
+		buf:       buf,
+		truncated: int64(len(buf)) > limit,
+		err:       err,
+	}
+}
+
+// readBody returns the request body, buffering it first if needed, so handlers can read it again.
+// It returns errBodyTooLarge if the body is larger than limit or than the limit it was buffered with.
+func readBody(r *http.Request, limit int64) ([]byte, error) {
+	bufferBody(r, limit)
+
+	body, ok := r.Body.(*bufferedBody)
+	if !ok {
+		return nil, nil
+	}
+
+	if body.err != nil {
+		return nil, body.err
+	}
+
+	if body.truncated || int64(len(body.buf)) > limit {
+		return nil, errors.Wrapf(errBodyTooLarge, "limit is %d bytes", limit)
+	}
+
+	return body.buf, nil
+}
+
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+func lookupField(obj map[string]interface{}, path string) (interface{}, bool) {
+	var value interface{} = obj
+
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		if value, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+func setField(obj map[string]interface{}, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := obj[key].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			obj[key] = child
+		}
+
+		obj = child
+	}
+
+	obj[keys[len(keys)-1]] = value
+}
@@ -0,0 +1,74 @@
+package httpz_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware/httpz"
+	"github.com/aserto-dev/go-aserto/middleware/internal/test"
+	assert "github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const documentBody = `{"ownerId": "alice", "folder": {"id": "f1", "name": "docs"}, "title": "report"}`
+
+func runBodyTest(t *testing.T, mw *httpz.Middleware, contentType string) {
+	t.Helper()
+
+	mw.Identity.Subject()
+
+	handler := mw.Handler(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		// The handler can still read the body.
+		body, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, documentBody, string(body))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "https://example.com/foo", strings.NewReader(documentBody))
+	req.Header.Add("Authorization", test.DefaultUsername)
+	req.Header.Set("Content-Type", contentType)
+
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestResourceFromBody(t *testing.T) {
+	resource, err := structpb.NewStruct(map[string]interface{}{
+		"ownerId": "alice",
+		"folder":  map[string]interface{}{"id": "f1"},
+	})
+	assert.NoError(t, err)
+
+	base := test.NewTest(t, "body", &test.Options{
+		ExpectedRequest: test.Request(test.PolicyPath("POST.foo"), test.Resource(resource)),
+	})
+
+	mw := httpz.New(base.Client, test.Policy("")).WithResourceFromBody("ownerId", "folder.id", "missing")
+	runBodyTest(t, mw, "application/json; charset=utf-8")
+}
+
+func TestResourceFromBodySkipped(t *testing.T) {
+	for name, tc := range map[string]struct {
+		contentType string
+		maxSize     int64
+	}{
+		"non-json content type": {contentType: "text/plain"},
+		"body too large":        {contentType: "application/json", maxSize: 10},
+	} {
+		t.Run(name, func(t *testing.T) {
+			base := test.NewTest(t, name, &test.Options{PolicyPath: "POST.foo"})
+
+			mw := httpz.New(base.Client, test.Policy("")).WithResourceFromBody("ownerId").WithMaxBodySize(tc.maxSize)
+			runBodyTest(t, mw, tc.contentType)
+		})
+	}
+}
@@ -1,9 +1,11 @@
 package httpz
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
+	"github.com/aserto-dev/go-aserto/middleware"
 	"github.com/aserto-dev/go-aserto/middleware/internal"
 	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
 	"google.golang.org/protobuf/types/known/structpb"
@@ -22,6 +24,30 @@ func WithIdentityMapper(mapper IdentityMapper) CheckOption {
 	}
 }
 
+// WithSubjectIDFromHeader takes the name of a request header whose value is used as the subject id for the check call.
+// If the header is absent, the caller's identity is used.
+func WithSubjectIDFromHeader(name string) CheckOption {
+	return func(o *CheckOptions) {
+		o.subj.mapper = func(r *http.Request, identity middleware.Identity) {
+			if id := r.Header.Get(name); id != "" {
+				identity.Subject().ID(id)
+			}
+		}
+	}
+}
+
+// WithSubjectIDFromQuery takes the name of a query parameter whose value is used as the subject id for the check call.
+// If the parameter is absent, the caller's identity is used.
+func WithSubjectIDFromQuery(name string) CheckOption {
+	return func(o *CheckOptions) {
+		o.subj.mapper = func(r *http.Request, identity middleware.Identity) {
+			if id := r.URL.Query().Get(name); id != "" {
+				identity.Subject().ID(id)
+			}
+		}
+	}
+}
+
 // WithRelation sets the relation/permission to check.
 func WithRelation(name string) CheckOption {
 	return func(o *CheckOptions) {
@@ -29,6 +55,24 @@ func WithRelation(name string) CheckOption {
 	}
 }
 
+// WithAnyRelation checks the specified relations/permissions concurrently and allows the request if any of them
+// exist. It takes precedence over WithRelation and WithRelationMapper.
+func WithAnyRelation(relations ...string) CheckOption {
+	return func(o *CheckOptions) {
+		o.rel.names = relations
+		o.rel.all = false
+	}
+}
+
+// WithAllRelations checks the specified relations/permissions concurrently and allows the request only if all of
+// them exist. It takes precedence over WithRelation and WithRelationMapper.
+func WithAllRelations(relations ...string) CheckOption {
+	return func(o *CheckOptions) {
+		o.rel.names = relations
+		o.rel.all = true
+	}
+}
+
 // WithRelation takes a function that is used to determine the relation/permission to check from the incoming request.
 func WithRelationMapper(mapper StringMapper) CheckOption {
 	return func(o *CheckOptions) {
@@ -57,6 +101,24 @@ func WithObjectIDMapper(mapper StringMapper) CheckOption {
 	}
 }
 
+// WithObjectIDFromHeader takes the name of a request header whose value is used as the object id to check.
+func WithObjectIDFromHeader(name string) CheckOption {
+	return func(o *CheckOptions) {
+		o.obj.idMapper = func(r *http.Request) string {
+			return r.Header.Get(name)
+		}
+	}
+}
+
+// WithObjectIDFromQuery takes the name of a query parameter whose value is used as the object id to check.
+func WithObjectIDFromQuery(name string) CheckOption {
+	return func(o *CheckOptions) {
+		o.obj.idMapper = func(r *http.Request) string {
+			return r.URL.Query().Get(name)
+		}
+	}
+}
+
 // WithObjectMapper takes a function that is used to determine the object type and id to check from the incoming request.
 func WithObjectMapper(mapper ObjectMapper) CheckOption {
 	return func(o *CheckOptions) {
@@ -82,6 +144,8 @@ type CheckOptions struct {
 	rel struct {
 		name   string
 		mapper StringMapper
+		names  []string
+		all    bool
 	}
 	subj struct {
 		subjType string
@@ -141,23 +205,20 @@ func newCheck(mw *Middleware, options ...CheckOption) *Check {
 // Handler returns a middleware handler that checks incoming requests.
 func (c *Check) Handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		policyContext := c.policyContext(r)
-		identityContext := c.identityContext(r)
-		resourceContext, err := c.resourceContext(r)
-
+		identityContext, err := c.identityContext(r.Context(), r)
 		if err != nil {
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			c.mw.fail(w, err.Error(), err)
 			return
 		}
 
-		allowed, err := c.mw.is(r.Context(), identityContext, policyContext, resourceContext)
+		allowed, err := c.authorize(r.Context(), r, identityContext, c.policyContext(r))
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			c.mw.fail(w, err.Error(), err)
 			return
 		}
 
 		if !allowed {
-			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			c.mw.deny(w, r, identityContext)
 			return
 		}
 
@@ -165,6 +226,17 @@ func (c *Check) Handler(next http.Handler) http.Handler {
 	})
 }
 
+// Evaluate returns the outcome of the check for an incoming request without writing a response.
+// It implements middleware.Evaluator.
+func (c *Check) Evaluate(ctx context.Context, r *http.Request) (bool, error) {
+	identityContext, err := c.identityContext(ctx, r)
+	if err != nil {
+		return false, err
+	}
+
+	return c.authorize(ctx, r, identityContext, c.policyContext(r))
+}
+
 // HandlerFunc returns a middleware handler that wraps the given http.HandlerFunc and checks incoming requests.
 func (c *Check) HandlerFunc(next http.HandlerFunc) http.HandlerFunc {
 	return c.Handler(next).ServeHTTP
@@ -195,8 +267,11 @@ func (c *Check) policyContext(r *http.Request) *api.PolicyContext {
 	return policyContext
 }
 
-func (c *Check) identityContext(r *http.Request) *api.IdentityContext {
-	idc := c.mw.Identity.Build(r)
+func (c *Check) identityContext(ctx context.Context, r *http.Request) (*api.IdentityContext, error) {
+	idc, err := c.callerIdentity(ctx, r)
+	if err != nil {
+		return nil, err
+	}
 
 	if c.opts.subj.mapper != nil {
 		identity := internal.NewIdentity(idc.Type, idc.Identity)
@@ -204,11 +279,46 @@ func (c *Check) identityContext(r *http.Request) *api.IdentityContext {
 		idc = identity.Context()
 	}
 
-	return idc
+	return idc, nil
+}
+
+// callerIdentity returns the identity shared by Authorize or, if there isn't one, resolves it from the request.
+func (c *Check) callerIdentity(ctx context.Context, r *http.Request) (*api.IdentityContext, error) {
+	if e := c.mw.sharedEvaluation(ctx); e != nil {
+		return e.identity, nil
+	}
+
+	return c.mw.Identity.resolve(r)
+}
+
+// authorize checks the configured relations. If multiple relations are set with WithAnyRelation or
+// WithAllRelations, they are checked concurrently.
+func (c *Check) authorize(
+	ctx context.Context,
+	r *http.Request,
+	identityContext *api.IdentityContext,
+	policyContext *api.PolicyContext,
+) (bool, error) {
+	check := func(ctx context.Context, relation string) (bool, error) {
+		resourceContext, err := c.resourceContext(r, relation)
+		if err != nil {
+			return false, err
+		}
+
+		return c.mw.is(ctx, identityContext, policyContext, resourceContext)
+	}
+
+	switch {
+	case len(c.opts.rel.names) == 0:
+		return check(ctx, c.opts.relation(r))
+	case c.opts.rel.all:
+		return internal.CheckAll(ctx, c.opts.rel.names, check)
+	default:
+		return internal.CheckAny(ctx, c.opts.rel.names, check)
+	}
 }
 
-func (c *Check) resourceContext(r *http.Request) (*structpb.Struct, error) {
-	relation := c.opts.relation(r)
+func (c *Check) resourceContext(r *http.Request, relation string) (*structpb.Struct, error) {
 	objType, objID := c.opts.object(r)
 	subjType := c.opts.subjectType()
 
@@ -0,0 +1,319 @@
+package httpz_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	"github.com/aserto-dev/go-aserto/middleware/httpz"
+	"github.com/aserto-dev/go-aserto/middleware/internal/test"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	assert "github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// relationAuthorizer allows checks on a fixed set of relations.
+type relationAuthorizer struct {
+	authz.AuthorizerClient
+
+	allowed map[string]bool
+}
+
+func (c *relationAuthorizer) Is(_ context.Context, in *authz.IsRequest, _ ...grpc.CallOption) (*authz.IsResponse, error) {
+	relation := in.GetResourceContext().GetFields()["relation"].GetStringValue()
+
+	return &authz.IsResponse{
+		Decisions: []*authz.Decision{{Decision: test.DefaultDecision, Is: c.allowed[relation]}},
+	}, nil
+}
+
+func TestCheckMultipleRelations(t *testing.T) {
+	client := &relationAuthorizer{allowed: map[string]bool{"can_read": true}}
+
+	for name, tc := range map[string]struct {
+		option   httpz.CheckOption
+		expected int
+	}{
+		"single relation":       {option: httpz.WithRelation("can_read"), expected: http.StatusOK},
+		"any relation allowed":  {option: httpz.WithAnyRelation("can_write", "can_read"), expected: http.StatusOK},
+		"any relation denied":   {option: httpz.WithAnyRelation("can_write", "can_delete"), expected: http.StatusForbidden},
+		"all relations allowed": {option: httpz.WithAllRelations("can_read"), expected: http.StatusOK},
+		"all relations denied":  {option: httpz.WithAllRelations("can_read", "can_write"), expected: http.StatusForbidden},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mw := httpz.New(client, test.Policy(""))
+			mw.Identity.Subject()
+
+			check := mw.Check(httpz.WithObjectType("doc"), httpz.WithObjectID("doc1"), tc.option)
+
+			req := httptest.NewRequest(http.MethodGet, "https://example.com/docs/doc1", http.NoBody)
+			req.Header.Add("Authorization", test.DefaultUsername)
+
+			w := httptest.NewRecorder()
+
+			check.Handler(http.HandlerFunc(noopHandler)).ServeHTTP(w, req)
+
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.expected, resp.StatusCode)
+		})
+	}
+}
+
+// combinedAuthorizer allows policy decisions for "GET.foo" and checks on a fixed set of relations.
+type combinedAuthorizer struct {
+	authz.AuthorizerClient
+
+	policyAllowed bool
+	relations     map[string]bool
+}
+
+func (c *combinedAuthorizer) Is(_ context.Context, in *authz.IsRequest, _ ...grpc.CallOption) (*authz.IsResponse, error) {
+	allowed := c.policyAllowed
+	if in.GetPolicyContext().GetPath() == "check" {
+		allowed = c.relations[in.GetResourceContext().GetFields()["relation"].GetStringValue()]
+	}
+
+	return &authz.IsResponse{
+		Decisions: []*authz.Decision{{Decision: test.DefaultDecision, Is: allowed}},
+	}, nil
+}
+
+func TestAuthorizeCombined(t *testing.T) {
+	for name, tc := range map[string]struct {
+		policyAllowed bool
+		relation      string
+		anyOf         bool
+		expected      int
+	}{
+		"all of: both allowed":  {policyAllowed: true, relation: "can_read", expected: http.StatusOK},
+		"all of: policy denied": {relation: "can_read", expected: http.StatusForbidden},
+		"all of: check denied":  {policyAllowed: true, relation: "can_write", expected: http.StatusForbidden},
+		"any of: policy denied": {relation: "can_read", anyOf: true, expected: http.StatusOK},
+		"any of: both denied":   {relation: "can_write", anyOf: true, expected: http.StatusForbidden},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client := &combinedAuthorizer{policyAllowed: tc.policyAllowed, relations: map[string]bool{"can_read": true}}
+
+			mw := httpz.New(client, test.Policy(""))
+			mw.Identity.Subject()
+
+			check := mw.Check(httpz.WithObjectType("doc"), httpz.WithObjectID("doc1"), httpz.WithRelation(tc.relation))
+
+			combine := middleware.AllOf[*http.Request]
+			if tc.anyOf {
+				combine = middleware.AnyOf[*http.Request]
+			}
+
+			identity := ""
+			handler := mw.Authorize(combine(mw, check))(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				identity = middleware.IdentityFromContext(r.Context()).GetIdentity()
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "https://example.com/foo", http.NoBody)
+			req.Header.Add("Authorization", test.DefaultUsername)
+
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.expected, resp.StatusCode)
+
+			if tc.expected == http.StatusOK {
+				assert.Equal(t, test.DefaultUsername, identity)
+			}
+		})
+	}
+}
+
+func TestAuthorizeSharesRequest(t *testing.T) {
+	const body = `{"ownerId": "alice"}`
+
+	client := &combinedAuthorizer{policyAllowed: true, relations: map[string]bool{"can_read": true}}
+
+	var identities, resources atomic.Int32
+
+	mw := httpz.New(client, test.Policy("")).
+		WithResourceFromBody("ownerId").
+		WithResourceMapper(func(*http.Request, map[string]interface{}) { resources.Add(1) })
+	mw.Identity.Mapper(func(_ *http.Request, identity middleware.Identity) {
+		identities.Add(1)
+		identity.Subject().ID(test.DefaultUsername)
+	})
+
+	// A separate middleware reads the body concurrently with mw.
+	other := httpz.New(client, test.Policy("")).WithResourceFromBody("ownerId")
+	other.Identity.Subject()
+
+	check := mw.Check(httpz.WithObjectType("doc"), httpz.WithObjectID("doc1"), httpz.WithRelation("can_read"))
+
+	evaluator := middleware.AllOf[*http.Request](mw, check, mw, other)
+
+	handler := mw.Authorize(evaluator)(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		read, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, body, string(read))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "https://example.com/foo", strings.NewReader(body))
+	req.Header.Add("Authorization", test.DefaultUsername)
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(1), identities.Load())
+	assert.Equal(t, int32(1), resources.Load())
+}
+
+func TestAuthorizeIdentityConflict(t *testing.T) {
+	client := &combinedAuthorizer{policyAllowed: true}
+
+	mw := httpz.New(client, test.Policy(""))
+	mw.Identity.Subject().FromHeader("Authorization").FromHeader("X-User").Precedence(middleware.IdentityStrict)
+
+	allow := middleware.EvaluatorFunc[*http.Request](func(context.Context, *http.Request) (bool, error) {
+		return true, nil
+	})
+
+	handler := mw.Authorize(allow)(http.HandlerFunc(noopHandler))
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/foo", http.NoBody)
+	req.Header.Add("Authorization", "alice")
+	req.Header.Add("X-User", "bob")
+
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+}
+
+// objectAuthorizer allows checks on a single object id.
+type objectAuthorizer struct {
+	authz.AuthorizerClient
+
+	allowed string
+}
+
+func (c *objectAuthorizer) Is(_ context.Context, in *authz.IsRequest, _ ...grpc.CallOption) (*authz.IsResponse, error) {
+	objID := in.GetResourceContext().GetFields()["object_id"].GetStringValue()
+
+	return &authz.IsResponse{
+		Decisions: []*authz.Decision{{Decision: test.DefaultDecision, Is: objID == c.allowed}},
+	}, nil
+}
+
+func TestCheckObjectIDFromRequest(t *testing.T) {
+	client := &objectAuthorizer{allowed: "doc1"}
+
+	for name, tc := range map[string]struct {
+		option   httpz.CheckOption
+		target   string
+		header   string
+		expected int
+	}{
+		"header":         {option: httpz.WithObjectIDFromHeader("X-Resource-Id"), header: "doc1", expected: http.StatusOK},
+		"other header":   {option: httpz.WithObjectIDFromHeader("X-Resource-Id"), header: "doc2", expected: http.StatusForbidden},
+		"missing header": {option: httpz.WithObjectIDFromHeader("X-Resource-Id"), expected: http.StatusForbidden},
+		"query":          {option: httpz.WithObjectIDFromQuery("id"), target: "?id=doc1", expected: http.StatusOK},
+		"other query":    {option: httpz.WithObjectIDFromQuery("id"), target: "?id=doc2", expected: http.StatusForbidden},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mw := httpz.New(client, test.Policy(""))
+			mw.Identity.Subject()
+
+			check := mw.Check(httpz.WithObjectType("doc"), httpz.WithRelation("can_read"), tc.option)
+
+			req := httptest.NewRequest(http.MethodGet, "https://example.com/docs"+tc.target, http.NoBody)
+			req.Header.Add("Authorization", test.DefaultUsername)
+
+			if tc.header != "" {
+				req.Header.Add("X-Resource-Id", tc.header)
+			}
+
+			w := httptest.NewRecorder()
+
+			check.Handler(http.HandlerFunc(noopHandler)).ServeHTTP(w, req)
+
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.expected, resp.StatusCode)
+		})
+	}
+}
+
+// subjectAuthorizer allows checks for a single subject id.
+type subjectAuthorizer struct {
+	authz.AuthorizerClient
+
+	allowed string
+}
+
+func (c *subjectAuthorizer) Is(_ context.Context, in *authz.IsRequest, _ ...grpc.CallOption) (*authz.IsResponse, error) {
+	subject := in.GetIdentityContext()
+	allowed := subject.GetType() == api.IdentityType_IDENTITY_TYPE_SUB && subject.GetIdentity() == c.allowed
+
+	return &authz.IsResponse{
+		Decisions: []*authz.Decision{{Decision: test.DefaultDecision, Is: allowed}},
+	}, nil
+}
+
+func TestCheckSubjectIDFromRequest(t *testing.T) {
+	client := &subjectAuthorizer{allowed: "acting-user"}
+
+	for name, tc := range map[string]struct {
+		option   httpz.CheckOption
+		target   string
+		header   string
+		expected int
+	}{
+		"header":         {option: httpz.WithSubjectIDFromHeader("X-Act-As"), header: "acting-user", expected: http.StatusOK},
+		"other header":   {option: httpz.WithSubjectIDFromHeader("X-Act-As"), header: "other-user", expected: http.StatusForbidden},
+		"missing header": {option: httpz.WithSubjectIDFromHeader("X-Act-As"), expected: http.StatusForbidden},
+		"query":          {option: httpz.WithSubjectIDFromQuery("as"), target: "?as=acting-user", expected: http.StatusOK},
+		"other query":    {option: httpz.WithSubjectIDFromQuery("as"), target: "?as=other-user", expected: http.StatusForbidden},
+	} {
+		t.Run(name, func(t *testing.T) {
+			mw := httpz.New(client, test.Policy(""))
+			mw.Identity.JWT()
+
+			check := mw.Check(httpz.WithObjectType("doc"), httpz.WithRelation("can_read"), tc.option)
+
+			req := httptest.NewRequest(http.MethodGet, "https://example.com/docs"+tc.target, http.NoBody)
+			req.Header.Add("Authorization", "Bearer "+test.DefaultUsername)
+
+			if tc.header != "" {
+				req.Header.Add("X-Act-As", tc.header)
+			}
+
+			w := httptest.NewRecorder()
+
+			check.Handler(http.HandlerFunc(noopHandler)).ServeHTTP(w, req)
+
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.expected, resp.StatusCode)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package httpz
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+)
+
+// DebugInfo describes the effective configuration of a middleware.
+type DebugInfo struct {
+	Policy          PolicyDebugInfo   `json:"policy"`
+	PolicyMapper    bool              `json:"policy_mapper"`
+	Identity        IdentityDebugInfo `json:"identity"`
+	ResourceMappers int               `json:"resource_mappers"`
+	BypassPaths     []string          `json:"bypass_paths,omitempty"`
+	ErrorOutcomes   map[string]string `json:"error_outcomes,omitempty"`
+	DenialStatus    int               `json:"denial_status"`
+	ErrorStatus     int               `json:"error_status"`
+	DenialHandler   bool              `json:"denial_handler"`
+	ProblemJSON     bool              `json:"problem_json"`
+	PostProcessor   bool              `json:"post_processor"`
+}
+
+// PolicyDebugInfo describes the policy evaluated by the middleware.
+type PolicyDebugInfo struct {
+	Name          string `json:"name,omitempty"`
+	InstanceLabel string `json:"instance_label,omitempty"`
+	Path          string `json:"path,omitempty"`
+	Decision      string `json:"decision"`
+	Root          string `json:"root,omitempty"`
+}
+
+// IdentityDebugInfo describes how the middleware determines caller identities.
+type IdentityDebugInfo struct {
+	// Type is the configured identity type (e.g. "sub" or "jwt").
+	Type string `json:"type"`
+
+	// Source is where identities are read from (e.g. "header:Authorization" or "cookie:session").
+	// It is "static" if a fixed identity is set with ID() and empty if no source is configured.
+	// If multiple sources are used, they are listed in the order they were configured, separated by semicolons.
+	Source string `json:"source,omitempty"`
+
+	// Precedence is the identity precedence (e.g. "strict") if multiple sources are used.
+	Precedence string `json:"precedence,omitempty"`
+
+	// Anonymous is the subject set with AnonymousAs().
+	Anonymous string `json:"anonymous,omitempty"`
+}
+
+// DebugInfo returns a description of the middleware's effective configuration.
+func (m *Middleware) DebugInfo() *DebugInfo {
+	info := &DebugInfo{
+		Policy: PolicyDebugInfo{
+			Name:          m.policy.Name,
+			InstanceLabel: m.policy.InstanceLabel,
+			Path:          m.policy.Path,
+			Decision:      m.policy.Decision,
+			Root:          m.policy.Root,
+		},
+		PolicyMapper:    m.policyMapper != nil,
+		Identity:        m.Identity.debugInfo(),
+		ResourceMappers: len(m.resourceMappers),
+		DenialStatus:    m.denialStatus,
+		ErrorStatus:     m.errorStatus,
+		DenialHandler:   m.denialHandler != nil,
+		ProblemJSON:     m.problems != nil,
+		PostProcessor:   m.postProcessor != nil,
+	}
+
+	for path := range m.bypassPaths {
+		info.BypassPaths = append(info.BypassPaths, path)
+	}
+
+	sort.Strings(info.BypassPaths)
+
+	if len(m.errorOutcomes) > 0 {
+		info.ErrorOutcomes = make(map[string]string, len(m.errorOutcomes))
+		for code, outcome := range m.errorOutcomes {
+			info.ErrorOutcomes[code.String()] = outcome.String()
+		}
+	}
+
+	return info
+}
+
+// DebugHandler returns a handler that responds with the middleware's configuration encoded as JSON.
+// See DebugInfo for details.
+//
+// The handler is intended for troubleshooting and shouldn't be exposed publicly.
+//
+// # Example
+//
+//	debugMux.Handle("/debug/authz", mw.DebugHandler())
+func (m *Middleware) DebugHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(w).Encode(m.DebugInfo()); err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	})
+}
+
+func (b *IdentityBuilder) debugInfo() IdentityDebugInfo {
+	info := IdentityDebugInfo{
+		Type:      strings.ToLower(strings.TrimPrefix(b.identityType.String(), "IDENTITY_TYPE_")),
+		Anonymous: b.anonymous,
+	}
+
+	if len(b.sources) > 0 && b.precedence == middleware.IdentityLastSource {
+		info.Source = b.sources[len(b.sources)-1].Name
+	} else if len(b.sources) > 0 {
+		names := make([]string, len(b.sources))
+		for i, source := range b.sources {
+			names[i] = source.Name
+		}
+
+		info.Source = strings.Join(names, ";")
+		info.Precedence = b.precedence.String()
+	}
+
+	if info.Source == "" && b.defaultIdentity != "" {
+		info.Source = "static"
+	}
+
+	if b.identityType == api.IdentityType_IDENTITY_TYPE_UNKNOWN {
+		info.Type = ""
+	}
+
+	return info
+}
@@ -0,0 +1,42 @@
+package httpz_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	"github.com/aserto-dev/go-aserto/middleware/httpz"
+	"github.com/aserto-dev/go-aserto/middleware/internal/test"
+	assert "github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+func TestDebugHandler(t *testing.T) {
+	mw := httpz.New(nil, test.Policy("")).
+		WithBypassSafeMethods("/health", "/docs").
+		WithErrorCodeMapping(map[codes.Code]middleware.Outcome{codes.NotFound: middleware.OutcomeDeny}).
+		WithResourceFromQuery("id")
+	mw.Identity.Subject().FromCookie("session").AnonymousAs("anonymous")
+
+	w := httptest.NewRecorder()
+	mw.DebugHandler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "https://example.com/debug", http.NoBody))
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var info httpz.DebugInfo
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&info))
+
+	assert.Equal(t, "policyName", info.Policy.Name)
+	assert.True(t, info.PolicyMapper)
+	assert.Equal(t, httpz.IdentityDebugInfo{Type: "sub", Source: "cookie:session", Anonymous: "anonymous"}, info.Identity)
+	assert.Equal(t, 1, info.ResourceMappers)
+	assert.Equal(t, []string{"/docs", "/health"}, info.BypassPaths)
+	assert.Equal(t, map[string]string{"NotFound": "deny"}, info.ErrorOutcomes)
+	assert.Equal(t, http.StatusForbidden, info.DenialStatus)
+	assert.Equal(t, http.StatusInternalServerError, info.ErrorStatus)
+}
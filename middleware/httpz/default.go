@@ -0,0 +1,105 @@
+package httpz
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/metadata"
+)
+
+// RequestIDHeader is the HTTP header used to propagate request IDs.
+const RequestIDHeader = "X-Request-ID"
+
+// DefaultOption functions customize the middleware created by Default.
+type DefaultOption func(*Middleware)
+
+type requestIDKey struct{}
+
+// Default returns a ready-to-use handler wrapper that authorizes incoming requests.
+//
+// The wrapper:
+//
+//   - Uses the incoming X-Request-ID header as the request ID or generates one if it's missing. The ID is added to
+//     the response headers, to the request context (see RequestIDFromContext), and to the context's zerolog logger.
+//   - Reads the caller's identity from the Authorization header.
+//   - Derives the policy path from the request method and URL, unless policy.Path is set.
+//
+// Options are applied to the underlying middleware and can call any of its ".With...()" methods.
+// For example, to include path values of routes registered with http.ServeMux in the resource context:
+//
+//	authorize := httpz.Default(client, policy, func(mw *httpz.Middleware) {
+//		mw.WithResourceFromPathValues("id")
+//	})
+//
+//	http.Handle("GET /users/{id}", authorize(usersHandler))
+func Default(client AuthorizerClient, policy *Policy, opts ...DefaultOption) func(http.Handler) http.Handler {
+	mw := New(client, policy)
+	for _, opt := range opts {
+		opt(mw)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return withRequestID(mw.Handler(next))
+	}
+}
+
+// RequestIDFromContext returns the request ID added to the context by the handler returned from Default.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithRequestIDPropagation includes the request ID assigned by Default in the metadata of calls to the authorizer,
+// which allows correlating authorizer decisions with the requests that triggered them.
+func (m *Middleware) WithRequestIDPropagation() *Middleware {
+	m.propagateRID = true
+	return m
+}
+
+// WithResourceFromPathValues adds the named path values of routes registered with http.ServeMux to the resource
+// context. Missing or empty values are omitted.
+func (m *Middleware) WithResourceFromPathValues(names ...string) *Middleware {
+	return m.WithResourceMapper(func(r *http.Request, resource map[string]interface{}) {
+		for _, name := range names {
+			if value := r.PathValue(name); value != "" {
+				resource[name] = value
+			}
+		}
+	})
+}
+
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+		logger := zerolog.Ctx(ctx).With().Str("request_id", id).Logger()
+
+		next.ServeHTTP(w, r.WithContext(logger.WithContext(ctx)))
+	})
+}
+
+// outgoingRequestID returns a copy of ctx with the request ID added to the outgoing gRPC metadata.
+func outgoingRequestID(ctx context.Context) context.Context {
+	id := RequestIDFromContext(ctx)
+	if id == "" {
+		return ctx
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, RequestIDHeader, id)
+}
+
+func newRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,110 @@
+package httpz_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	"github.com/aserto-dev/go-aserto/middleware/httpz"
+	"github.com/aserto-dev/go-aserto/middleware/internal/test"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	assert "github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestDefault(t *testing.T) {
+	resource, err := structpb.NewStruct(map[string]interface{}{"id": "123"})
+	assert.NoError(t, err)
+
+	base := test.NewTest(t, "default", &test.Options{
+		ExpectedRequest: test.Request(test.PolicyPath("GET.users.123"), test.Resource(resource)),
+	})
+
+	authorize := httpz.Default(base.Client, test.Policy(""), func(mw *httpz.Middleware) {
+		mw.Identity.Subject()
+		mw.WithResourceFromPathValues("id", "missing")
+	})
+
+	var requestID string
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /users/{id}", authorize(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		requestID = httpz.RequestIDFromContext(r.Context())
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/users/123", http.NoBody)
+	req.Header.Add("Authorization", test.DefaultUsername)
+
+	w := httptest.NewRecorder()
+
+	mux.ServeHTTP(w, req)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.NotEmpty(t, requestID)
+	assert.Equal(t, requestID, resp.Header.Get(httpz.RequestIDHeader))
+}
+
+func TestDefaultPropagatesRequestID(t *testing.T) {
+	base := test.NewTest(t, "request id", &test.Options{PolicyPath: DefaultPolicyPath})
+
+	authorize := httpz.Default(base.Client, test.Policy(""), func(mw *httpz.Middleware) {
+		mw.Identity.Subject()
+	})
+
+	handler := authorize(http.HandlerFunc(noopHandler))
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/foo", http.NoBody)
+	req.Header.Add("Authorization", test.DefaultUsername)
+	req.Header.Set(httpz.RequestIDHeader, "abc")
+
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "abc", resp.Header.Get(httpz.RequestIDHeader))
+}
+
+// metadataClient records the outgoing metadata of authorization calls.
+type metadataClient struct {
+	authz.AuthorizerClient
+
+	md metadata.MD
+}
+
+func (c *metadataClient) Is(ctx context.Context, in *authz.IsRequest, opts ...grpc.CallOption) (*authz.IsResponse, error) {
+	c.md, _ = metadata.FromOutgoingContext(ctx)
+	return c.AuthorizerClient.Is(ctx, in, opts...)
+}
+
+func TestRequestIDPropagation(t *testing.T) {
+	base := test.NewTest(t, "request id propagation", &test.Options{PolicyPath: DefaultPolicyPath})
+	client := &metadataClient{AuthorizerClient: base.Client}
+
+	var event middleware.DecisionEvent
+
+	authorize := httpz.Default(client, test.Policy(""), func(mw *httpz.Middleware) {
+		mw.WithRequestIDPropagation().
+			WithDecisionLogger(func(_ context.Context, e middleware.DecisionEvent) { event = e }).
+			Identity.Subject()
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/foo", http.NoBody)
+	req.Header.Add("Authorization", test.DefaultUsername)
+	req.Header.Set(httpz.RequestIDHeader, "abc")
+
+	authorize(http.HandlerFunc(noopHandler)).ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, []string{"abc"}, client.md.Get(httpz.RequestIDHeader))
+	assert.Equal(t, "abc", event.RequestID)
+}
@@ -0,0 +1,152 @@
+package httpz
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+)
+
+type gatewayParamsKey struct{}
+
+// GatewayMiddleware returns a grpc-gateway middleware that authorizes requests before they reach the gateway's
+// handlers. Install it using runtime.WithMiddlewares:
+//
+//	mux := runtime.NewServeMux(runtime.WithMiddlewares(mw.GatewayMiddleware()))
+//
+// Path parameters matched by the gateway are made available to WithResourceFromGatewayPathParams.
+func (m *Middleware) GatewayMiddleware() runtime.Middleware {
+	return func(next runtime.HandlerFunc) runtime.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+			handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				next(w, r, pathParams)
+			}))
+
+			ctx := context.WithValue(r.Context(), gatewayParamsKey{}, pathParams)
+			handler.ServeHTTP(w, r.WithContext(ctx))
+		}
+	}
+}
+
+// WithResourceFromGatewayPathParams adds the path parameters of routes served by a grpc-gateway runtime.ServeMux to
+// the resource context.
+//
+// Parameters are read from the request context. The middleware must run inside the gateway's mux, either using
+// GatewayMiddleware or another middleware installed with runtime.WithMiddlewares.
+func (m *Middleware) WithResourceFromGatewayPathParams() *Middleware {
+	return m.WithResourceMapper(func(r *http.Request, resource map[string]interface{}) {
+		for k, v := range gatewayPathParams(r) {
+			resource[k] = v
+		}
+	})
+}
+
+// WithPolicyFromGatewayPattern derives the policy path from the grpc-gateway route pattern matched by the request
+// instead of its concrete URL. Path parameters are replaced by their names, prefixed with "__", and custom verbs
+// become the last segment of the path.
+//
+// # Example
+//
+// Using 'WithPolicyFromGatewayPattern("myapp")', the route
+//
+//	GET /v1/users/{id}:activate
+//
+// becomes the policy path
+//
+//	"myapp.GET.v1.users.__id.activate"
+//
+// If the prefix is empty, the policy's Root is used. Requests that don't have a gateway pattern in their context fall back to the paths returned by PolicyPathFor.
+func (m *Middleware) WithPolicyFromGatewayPattern(prefix string) *Middleware {
+	if prefix == "" {
+		prefix = m.policy.Root
+	}
+
+	m.graphql = false
+	m.policyMapper = gatewayPolicyPathMapper(prefix, &m.pathFormat)
+
+	return m
+}
+
+func gatewayPolicyPathMapper(prefix string, format *middleware.PathFormat) StringMapper {
+	return func(r *http.Request) string {
+		pattern, ok := runtime.HTTPPattern(r.Context())
+		if !ok {
+			return policyPathFor(r, prefix, format)
+		}
+
+		return format.Join(prefix, append([]string{r.Method}, gatewaySegments(pattern, format)...)...)
+	}
+}
+
+// gatewayPathParams returns the path parameters added to the context by GatewayMiddleware or, if there are none,
+// matches the request path against the gateway pattern in the context.
+func gatewayPathParams(r *http.Request) map[string]string {
+	if params, ok := r.Context().Value(gatewayParamsKey{}).(map[string]string); ok {
+		return params
+	}
+
+	pattern, ok := runtime.HTTPPattern(r.Context())
+	if !ok {
+		return nil
+	}
+
+	components := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+
+	verb := pattern.Verb()
+	if last := components[len(components)-1]; verb != "" && strings.HasSuffix(last, ":"+verb) {
+		components[len(components)-1] = strings.TrimSuffix(last, ":"+verb)
+	}
+
+	params, err := pattern.Match(components, verb)
+	if err != nil {
+		return nil
+	}
+
+	return params
+}
+
+// gatewaySegments splits a gateway pattern like "/v1/{name=shelves/*}/books:list" into policy path segments.
+func gatewaySegments(pattern runtime.Pattern, format *middleware.PathFormat) []string {
+	path := strings.TrimPrefix(pattern.String(), "/")
+	if verb := pattern.Verb(); verb != "" {
+		path = strings.TrimSuffix(path, ":"+verb)
+	}
+
+	var (
+		segments []string
+		depth    int
+		start    int
+	)
+
+	for i, c := range path {
+		switch {
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+		case c == '/' && depth == 0:
+			segments = append(segments, gatewaySegment(path[start:i], format))
+			start = i + 1
+		}
+	}
+
+	segments = append(segments, gatewaySegment(path[start:], format))
+
+	if verb := pattern.Verb(); verb != "" {
+		segments = append(segments, verb)
+	}
+
+	return segments
+}
+
+func gatewaySegment(segment string, format *middleware.PathFormat) string {
+	if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+		return segment
+	}
+
+	name, _, _ := strings.Cut(segment[1:len(segment)-1], "=")
+
+	return format.Param(name)
+}
@@ -0,0 +1,80 @@
+package httpz_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware/httpz"
+	"github.com/aserto-dev/go-aserto/middleware/internal/test"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	assert "github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestGateway(t *testing.T) {
+	resource, err := structpb.NewStruct(map[string]interface{}{"shelf": "fiction", "book": "dune"})
+	assert.NoError(t, err)
+
+	for name, install := range map[string]func(*httpz.Middleware) runtime.Middleware{
+		"gateway middleware": (*httpz.Middleware).GatewayMiddleware,
+		"handler": func(mw *httpz.Middleware) runtime.Middleware {
+			return func(next runtime.HandlerFunc) runtime.HandlerFunc {
+				return func(w http.ResponseWriter, r *http.Request, params map[string]string) {
+					mw.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { next(w, r, params) }).ServeHTTP(w, r)
+				}
+			}
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			base := test.NewTest(t, name, &test.Options{
+				ExpectedRequest: test.Request(
+					test.PolicyPath("myapp.GET.v1.shelves.__shelf.books.__book.read"),
+					test.Resource(resource),
+				),
+			})
+
+			mw := httpz.New(base.Client, test.Policy("")).
+				WithPolicyFromGatewayPattern("myapp").
+				WithResourceFromGatewayPathParams()
+			mw.Identity.Subject()
+
+			mux := runtime.NewServeMux(runtime.WithMiddlewares(install(mw)))
+			err := mux.HandlePath(http.MethodGet, "/v1/shelves/{shelf}/books/{book}:read",
+				func(w http.ResponseWriter, _ *http.Request, _ map[string]string) { w.WriteHeader(http.StatusOK) },
+			)
+			assert.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "https://example.com/v1/shelves/fiction/books/dune:read", http.NoBody)
+			req.Header.Add("Authorization", test.DefaultUsername)
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, req)
+
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+		})
+	}
+}
+
+func TestGatewayPolicyFallback(t *testing.T) {
+	base := test.NewTest(t, "fallback", &test.Options{
+		ExpectedRequest: test.Request(test.PolicyPath("myapp.GET.foo")),
+	})
+
+	mw := httpz.New(base.Client, test.Policy("")).WithPolicyFromGatewayPattern("myapp")
+	mw.Identity.Subject()
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/foo", http.NoBody)
+	req.Header.Add("Authorization", test.DefaultUsername)
+
+	w := httptest.NewRecorder()
+	mw.HandlerFunc(noopHandler).ServeHTTP(w, req)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
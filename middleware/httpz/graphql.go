@@ -0,0 +1,199 @@
+package httpz
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+var ErrGraphQLOperation = errors.New("invalid graphql operation")
+
+// GraphQLOperation describes the GraphQL operation carried by an incoming request.
+type GraphQLOperation struct {
+	// Name of the operation. Empty for anonymous operations.
+	Name string
+
+	// Type of the operation: "query", "mutation", or "subscription".
+	Type string
+
+	// Fields holds the names of the top-level fields selected by the operation.
+	Fields []string
+}
+
+type graphqlOperationKey struct{}
+
+type graphqlRequest struct {
+	Query         string `json:"query"`
+	OperationName string `json:"operationName"`
+}
+
+// ParseGraphQLOperation extracts the GraphQL operation from an incoming request.
+//
+// POST requests are expected to carry a JSON body with "query" and "operationName" fields. The body is
+// restored after it is read so it remains available to downstream handlers.
+// GET requests read the same fields from URL query parameters.
+// Bodies larger than DefaultMaxBodySize are rejected.
+func ParseGraphQLOperation(r *http.Request) (*GraphQLOperation, error) {
+	return parseGraphQLOperation(r, DefaultMaxBodySize)
+}
+
+func parseGraphQLOperation(r *http.Request, limit int64) (*GraphQLOperation, error) {
+	gqlReq, err := readGraphQLRequest(r, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, gqlErr := parser.ParseQuery(&ast.Source{Input: gqlReq.Query})
+	if gqlErr != nil {
+		return nil, errors.Wrap(ErrGraphQLOperation, gqlErr.Error())
+	}
+
+	var op *ast.OperationDefinition
+
+	switch {
+	case gqlReq.OperationName != "":
+		op = doc.Operations.ForName(gqlReq.OperationName)
+	case len(doc.Operations) == 1:
+		op = doc.Operations[0]
+	}
+
+	if op == nil {
+		return nil, errors.Wrap(ErrGraphQLOperation, "operation not found")
+	}
+
+	fields := []string{}
+
+	for _, sel := range op.SelectionSet {
+		if field, ok := sel.(*ast.Field); ok {
+			fields = append(fields, field.Name)
+		}
+	}
+
+	return &GraphQLOperation{Name: op.Name, Type: string(op.Operation), Fields: fields}, nil
+}
+
+// WithPolicyFromGraphQL instructs the middleware to authorize GraphQL requests using the name of the
+// requested operation as the policy path.
+//
+// Anonymous operations use the operation type (e.g. "query") instead. An optional prefix can be specified to be
+// included in all paths. The operation type and top-level field names are added to the resource context as
+// "operation_type" and "fields".
+//
+// Requests that don't carry a valid GraphQL operation, including those with bodies larger than the limit set by
+// WithMaxBodySize, are rejected with 400 Bad Request without calling the authorizer.
+//
+// Setting another policy mapper, e.g. with WithPolicyFromURL or WithPolicyPathMapper, stops the middleware from
+// authorizing GraphQL operations.
+//
+// # Example
+//
+// Using 'WithPolicyFromGraphQL("myapp")', the operation
+//
+//	query GetUser { user(id: "1") { name } }
+//
+// is authorized with the policy path
+//
+//	"myapp.GetUser"
+func (m *Middleware) WithPolicyFromGraphQL(prefix string) *Middleware {
+	m.graphql = true
+	m.policyMapper = m.graphqlPolicyPathMapper(prefix)
+
+	return m
+}
+
+// withGraphQLOperation parses the request's GraphQL operation, if the middleware authorizes GraphQL requests, and
+// returns a request that carries it so the operation is only parsed once.
+func (m *Middleware) withGraphQLOperation(r *http.Request) (*http.Request, error) {
+	if !m.graphql {
+		return r, nil
+	}
+
+	if _, ok := r.Context().Value(graphqlOperationKey{}).(*GraphQLOperation); ok {
+		return r, nil
+	}
+
+	op, err := parseGraphQLOperation(r, m.bodyLimit())
+	if err != nil {
+		return r, err
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), graphqlOperationKey{}, op)), nil
+}
+
+// graphqlOperation returns the request's GraphQL operation, parsing it if withGraphQLOperation wasn't called.
+func (m *Middleware) graphqlOperation(r *http.Request) (*GraphQLOperation, error) {
+	if op, ok := r.Context().Value(graphqlOperationKey{}).(*GraphQLOperation); ok {
+		return op, nil
+	}
+
+	return parseGraphQLOperation(r, m.bodyLimit())
+}
+
+func (m *Middleware) graphqlPolicyPathMapper(prefix string) StringMapper {
+	return func(r *http.Request) string {
+		op, err := m.graphqlOperation(r)
+		if err != nil {
+			return ""
+		}
+
+		name := op.Name
+		if name == "" {
+			name = op.Type
+		}
+
+		if prefix != "" {
+			return strings.Trim(prefix, ".") + "." + name
+		}
+
+		return name
+	}
+}
+
+func (m *Middleware) graphqlResourceMapper(r *http.Request, resource map[string]interface{}) {
+	op, err := m.graphqlOperation(r)
+	if err != nil {
+		return
+	}
+
+	fields := make([]interface{}, len(op.Fields))
+	for i, f := range op.Fields {
+		fields[i] = f
+	}
+
+	resource["operation_type"] = op.Type
+	resource["fields"] = fields
+}
+
+func readGraphQLRequest(r *http.Request, limit int64) (*graphqlRequest, error) {
+	if r.Method == http.MethodGet {
+		return &graphqlRequest{
+			Query:         r.URL.Query().Get("query"),
+			OperationName: r.URL.Query().Get("operationName"),
+		}, nil
+	}
+
+	if r.Body == nil {
+		return nil, errors.Wrap(ErrGraphQLOperation, "empty request body")
+	}
+
+	body, err := readBody(r, limit)
+
+	switch {
+	case errors.Is(err, errBodyTooLarge):
+		return nil, errors.Wrap(ErrGraphQLOperation, err.Error())
+	case err != nil:
+		return nil, errors.Wrap(err, "failed to read request body")
+	}
+
+	var gqlReq graphqlRequest
+	if err := json.Unmarshal(body, &gqlReq); err != nil {
+		return nil, errors.Wrap(ErrGraphQLOperation, err.Error())
+	}
+
+	return &gqlReq, nil
+}
@@ -0,0 +1,152 @@
+package httpz_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware/httpz"
+	"github.com/aserto-dev/go-aserto/middleware/internal/test"
+	assert "github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const getUserQuery = `{"query": "query GetUser { user(id: \"1\") { name } org { id } }", "operationName": "GetUser"}`
+
+func TestParseGraphQLOperation(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "https://example.com/graphql", strings.NewReader(getUserQuery))
+
+	op, err := httpz.ParseGraphQLOperation(req)
+	assert.NoError(t, err)
+	assert.Equal(t, "GetUser", op.Name)
+	assert.Equal(t, "query", op.Type)
+	assert.Equal(t, []string{"user", "org"}, op.Fields)
+
+	// The body is still readable.
+	body, err := io.ReadAll(req.Body)
+	assert.NoError(t, err)
+	assert.Equal(t, getUserQuery, string(body))
+}
+
+func TestParseGraphQLOperationErrors(t *testing.T) {
+	for name, body := range map[string]string{
+		"invalid json":      `not json`,
+		"invalid query":     `{"query": "query {"}`,
+		"unknown operation": `{"query": "query A { a } query B { b }", "operationName": "C"}`,
+		"ambiguous":         `{"query": "query A { a } query B { b }"}`,
+		"too large":         `{"query": "` + strings.Repeat(" ", httpz.DefaultMaxBodySize) + `{ a }"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "https://example.com/graphql", strings.NewReader(body))
+
+			_, err := httpz.ParseGraphQLOperation(req)
+			assert.ErrorIs(t, err, httpz.ErrGraphQLOperation)
+		})
+	}
+}
+
+func TestPolicyFromGraphQL(t *testing.T) {
+	resource, err := structpb.NewStruct(map[string]interface{}{
+		"operation_type": "query",
+		"fields":         []interface{}{"user", "org"},
+	})
+	assert.NoError(t, err)
+
+	graphql := func(mw *httpz.Middleware) {
+		mw.WithPolicyFromGraphQL("myapp").Identity.Subject().ID(test.DefaultUsername)
+	}
+
+	// Rejected requests don't call the authorizer, which would fail the test because the request doesn't match.
+	rejected := test.Options{PolicyPath: "unexpected"}
+
+	tests := []*TestCase{
+		NewTest(
+			t,
+			"operations should be authorized",
+			&testOptions{
+				Options: test.Options{
+					ExpectedRequest: test.Request(test.PolicyPath("myapp.GetUser"), test.Resource(resource)),
+				},
+				method:   http.MethodPost,
+				url:      "https://example.com/graphql",
+				body:     getUserQuery,
+				callback: graphql,
+				handler: func(_ http.ResponseWriter, r *http.Request) {
+					body, err := io.ReadAll(r.Body)
+					assert.NoError(t, err)
+					assert.Equal(t, getUserQuery, string(body))
+				},
+			},
+		),
+		NewTest(
+			t,
+			"invalid json should be rejected",
+			&testOptions{
+				Options:            rejected,
+				expectedStatusCode: http.StatusBadRequest,
+				method:             http.MethodPost,
+				url:                "https://example.com/graphql",
+				body:               `not json`,
+				callback:           graphql,
+			},
+		),
+		NewTest(
+			t,
+			"invalid queries should be rejected",
+			&testOptions{
+				Options:            rejected,
+				expectedStatusCode: http.StatusBadRequest,
+				method:             http.MethodPost,
+				url:                "https://example.com/graphql",
+				body:               `{"query": "query {"}`,
+				callback:           graphql,
+			},
+		),
+		NewTest(
+			t,
+			"bodies over the size limit should be rejected",
+			&testOptions{
+				Options:            rejected,
+				expectedStatusCode: http.StatusBadRequest,
+				method:             http.MethodPost,
+				url:                "https://example.com/graphql",
+				body:               getUserQuery,
+				callback: func(mw *httpz.Middleware) {
+					graphql(mw.WithMaxBodySize(16))
+				},
+			},
+		),
+		NewTest(
+			t,
+			"url policy mapper should replace graphql",
+			&testOptions{
+				Options: test.Options{PolicyPath: "POST.graphql"},
+				method:  http.MethodPost,
+				url:     "https://example.com/graphql",
+				body:    `not json`,
+				callback: func(mw *httpz.Middleware) {
+					graphql(mw)
+					mw.WithPolicyFromURL("")
+				},
+			},
+		),
+		NewTest(
+			t,
+			"policy path mapper should replace graphql",
+			&testOptions{
+				Options: test.Options{PolicyPath: test.OverridePolicyPath},
+				method:  http.MethodPost,
+				url:     "https://example.com/graphql",
+				body:    getUserQuery,
+				callback: func(mw *httpz.Middleware) {
+					graphql(mw)
+					mw.WithPolicyPathMapper(func(*http.Request) string { return test.OverridePolicyPath })
+				},
+			},
+		),
+	}
+
+	runTests(t, tests...)
+}
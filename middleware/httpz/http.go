@@ -8,19 +8,32 @@ package httpz
 
 import (
 	"context"
+	"math"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	cerr "github.com/aserto-dev/errors"
 	"github.com/aserto-dev/go-aserto/middleware"
 	"github.com/aserto-dev/go-aserto/middleware/internal"
 	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
 	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
-	aerr "github.com/aserto-dev/go-authorizer/pkg/aerr"
+	"github.com/pkg/errors"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/structpb"
 )
 
+// ReturnToParam is the query parameter that holds the original request URL in login redirects.
+const ReturnToParam = "return_to"
+
 type (
 	Policy           = middleware.Policy
 	AuthorizerClient = authz.AuthorizerClient
@@ -43,10 +56,38 @@ type Middleware struct {
 	// Identity determines the caller identity used in authorization calls.
 	Identity *IdentityBuilder
 
-	client          AuthorizerClient
-	policy          *Policy
-	policyMapper    StringMapper
-	resourceMappers []ResourceMapper
+	client            AuthorizerClient
+	policy            *Policy
+	policyMapper      StringMapper
+	pathFormat        middleware.PathFormat
+	resourceMappers   []ResourceMapper
+	omitEmptyResource bool
+	manualIdentity    string
+	errorOutcomes     map[codes.Code]middleware.Outcome
+	failureModes      internal.FailureModes
+	denialStatus      int
+	errorStatus       int
+	unavailStatus     int
+	retryAfter        time.Duration
+	denialHandler     http.HandlerFunc
+	loginURL          string
+	problems          *problemOptions
+	decision          string
+	postProcessor     middleware.DecisionPostProcessor
+	decisionLogger    middleware.DecisionLogger
+	logLevel          *zerolog.Level
+	logRedactions     []string
+	propagateRID      bool
+	bypassPaths       internal.Lookup[string]
+	allowedPaths      *internal.PathMatcher
+	allowedMethods    internal.Lookup[string]
+	trusted           internal.TrustedPeers
+	authzPreflight    bool
+	maxBodySize       int64
+	graphql           bool
+	tracer            trace.Tracer
+	metrics           *internal.Metrics
+	treeFormat        middleware.DecisionTreeFormat
 }
 
 type (
@@ -64,48 +105,163 @@ type (
 // Those can be overridden using `Middleware.Identity` to specify the caller's identity, or using
 // the middleware's ".With...()" functions to set policy path and resource mappers.
 func New(client AuthorizerClient, policy *Policy) *Middleware {
-	policyMapper := urlPolicyPathMapper("")
-	if policy.Path != "" {
-		policyMapper = nil
-	}
-
-	return &Middleware{
-		Identity:        (&IdentityBuilder{}).FromHeader("Authorization"),
+	mw := &Middleware{
+		Identity:        (&IdentityBuilder{}).FromHeader("Authorization").byDefault(),
 		client:          client,
 		policy:          policy,
 		resourceMappers: []ResourceMapper{},
-		policyMapper:    policyMapper,
+		pathFormat:      middleware.DefaultPathFormat(),
+		denialStatus:    http.StatusForbidden,
+		errorStatus:     http.StatusInternalServerError,
+		unavailStatus:   http.StatusServiceUnavailable,
+		tracer:          noop.NewTracerProvider().Tracer(""),
+	}
+
+	if policy.Path == "" {
+		mw.policyMapper = urlPolicyPathMapper(policy.Root, &mw.pathFormat)
 	}
+
+	return mw
 }
 
 // Handler returns a middlleware handler that authorizes incoming requests.
 func (m *Middleware) Handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		policyContext := m.policyContext()
+		if err := m.trusted.Err(); err != nil {
+			m.fail(w, err.Error(), err)
+			return
+		}
 
-		if m.policyMapper != nil {
-			policyContext.Path = m.policyMapper(r)
+		if m.isBypassed(r) {
+			next.ServeHTTP(w, r)
+			return
 		}
 
-		resource, err := m.resourceContext(r)
+		identity, err := m.Identity.resolve(r)
 		if err != nil {
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			m.fail(w, err.Error(), err)
 			return
 		}
 
-		allowed, err := m.is(r.Context(), m.Identity.Build(r), policyContext, resource)
+		allowed, err := m.evaluate(r.Context(), r, identity)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			m.fail(w, err.Error(), err)
 			return
 		}
 
 		if !allowed {
-			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			m.deny(w, r, identity)
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(middleware.WithIdentity(r.Context(), identity)))
+	})
+}
+
+// Authorize returns a handler wrapper that authorizes incoming requests using the given evaluator instead of the
+// middleware's policy. Denials and errors are handled, and the caller's identity is added to the request context,
+// as configured on the middleware.
+//
+// It can be used to combine policy decisions and relation checks using middleware.AllOf or middleware.AnyOf:
+//
+//	mw.Authorize(middleware.AllOf[*http.Request](mw, mw.Check(httpz.WithRelation("can_read"))))(handler)
+//
+// The caller's identity is resolved once and shared with the evaluators created from the middleware, which also
+// share the resource context. The request body, up to the limit set by WithMaxBodySize, is read before the
+// evaluators run so they can read it concurrently.
+func (m *Middleware) Authorize(evaluator middleware.Evaluator[*http.Request]) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := m.trusted.Err(); err != nil {
+				m.fail(w, err.Error(), err)
+				return
+			}
+
+			if m.isBypassed(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			identity, err := m.Identity.resolve(r)
+			if err != nil {
+				m.fail(w, err.Error(), err)
+				return
+			}
+
+			bufferBody(r, m.bodyLimit())
+
+			req, err := m.withGraphQLOperation(r)
+			if err != nil {
+				m.fail(w, err.Error(), err)
+				return
+			}
+
+			ctx := context.WithValue(req.Context(), evaluationKey{}, &evaluation{mw: m, identity: identity})
+
+			allowed, err := evaluator.Evaluate(ctx, req.WithContext(ctx))
+			if err != nil {
+				m.fail(w, err.Error(), err)
+				return
+			}
+
+			if !allowed {
+				m.deny(w, r, identity)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(middleware.WithIdentity(r.Context(), identity)))
+		})
+	}
+}
+
+// Evaluate returns the authorizer's decision for an incoming request without writing a response.
+// It implements middleware.Evaluator.
+func (m *Middleware) Evaluate(ctx context.Context, r *http.Request) (bool, error) {
+	if e := m.sharedEvaluation(ctx); e != nil {
+		resource, err := e.resourceContext(r)
+		if err != nil {
+			return false, err
+		}
+
+		return m.is(ctx, e.identity, m.requestPolicyContext(r), resource)
+	}
+
+	identity, err := m.Identity.resolve(r)
+	if err != nil {
+		return false, err
+	}
+
+	return m.evaluate(ctx, r, identity)
+}
+
+type evaluationKey struct{}
+
+// evaluation holds the values that Authorize shares with the evaluators created from its middleware.
+type evaluation struct {
+	mw       *Middleware
+	identity *api.IdentityContext
+
+	resourceOnce sync.Once
+	resource     *structpb.Struct
+	resourceErr  error
+}
+
+// resourceContext builds the middleware's resource context the first time it's called.
+func (e *evaluation) resourceContext(r *http.Request) (*structpb.Struct, error) {
+	e.resourceOnce.Do(func() {
+		e.resource, e.resourceErr = e.mw.resourceContext(r, e.identity)
 	})
+
+	return e.resource, e.resourceErr
+}
+
+// sharedEvaluation returns the evaluation shared by Authorize, if ctx carries one created by the middleware.
+func (m *Middleware) sharedEvaluation(ctx context.Context) *evaluation {
+	if e, ok := ctx.Value(evaluationKey{}).(*evaluation); ok && e.mw == m {
+		return e
+	}
+
+	return nil
 }
 
 // HandlerFunc returns a middleware handler that wraps the given http.HandlerFunc and authorizes incoming requests.
@@ -120,17 +276,118 @@ func (m *Middleware) Check(options ...CheckOption) *Check {
 	return newCheck(m, options...)
 }
 
+func (m *Middleware) evaluate(ctx context.Context, r *http.Request, identity *api.IdentityContext) (bool, error) {
+	r, err := m.withGraphQLOperation(r)
+	if err != nil {
+		return false, err
+	}
+
+	resource, err := m.resourceContext(r, identity)
+	if err != nil {
+		return false, err
+	}
+
+	return m.is(ctx, identity, m.requestPolicyContext(r), resource)
+}
+
+func (m *Middleware) deny(w http.ResponseWriter, r *http.Request, identity *api.IdentityContext) {
+	if m.loginURL != "" && m.Identity.isAnonymous(identity) {
+		m.redirectToLogin(w, r)
+		return
+	}
+
+	if m.denialHandler != nil {
+		m.denialHandler(w, r)
+		return
+	}
+
+	if m.problems != nil {
+		writeProblem(w, m.denialStatus, DenialDetail)
+		return
+	}
+
+	http.Error(w, http.StatusText(m.denialStatus), m.denialStatus)
+}
+
+// redirectToLogin redirects the caller to the login URL with the original request URL in the return_to parameter.
+func (m *Middleware) redirectToLogin(w http.ResponseWriter, r *http.Request) {
+	login, err := url.Parse(m.loginURL)
+	if err != nil {
+		m.fail(w, http.StatusText(m.errorStatus), err)
+		return
+	}
+
+	query := login.Query()
+	query.Set(ReturnToParam, r.URL.RequestURI())
+	login.RawQuery = query.Encode()
+
+	http.Redirect(w, r, login.String(), http.StatusFound)
+}
+
+// fail responds to a failed authorization. msg is written in plain-text responses. Problem documents only include
+// the error if verbose problem details are enabled.
+func (m *Middleware) fail(w http.ResponseWriter, msg string, err error) {
+	code := m.errorStatus
+
+	switch {
+	case errors.Is(err, ErrGraphQLOperation):
+		code = http.StatusBadRequest
+	case status.Code(err) == codes.Unavailable:
+		code = m.unavailStatus
+
+		if m.retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(m.retryAfter.Seconds()))))
+		}
+	}
+
+	if m.problems == nil {
+		http.Error(w, msg, code)
+		return
+	}
+
+	detail := ""
+	if m.problems.verbose {
+		detail = err.Error()
+	}
+
+	writeProblem(w, code, detail)
+}
+
 func (m *Middleware) policyContext() *api.PolicyContext {
-	return internal.DefaultPolicyContext(m.policy)
+	policyContext := internal.DefaultPolicyContext(m.policy)
+	if m.decision != "" {
+		policyContext.Decisions = []string{m.decision}
+	}
+
+	return policyContext
 }
 
-func (m *Middleware) resourceContext(r *http.Request) (*structpb.Struct, error) {
+// requestPolicyContext returns the policy context with the path mapped from the request.
+func (m *Middleware) requestPolicyContext(r *http.Request) *api.PolicyContext {
+	policyContext := m.policyContext()
+
+	if m.policyMapper != nil {
+		policyContext.Path = m.policyMapper(r)
+	}
+
+	return policyContext
+}
+
+func (m *Middleware) resourceContext(r *http.Request, identity *api.IdentityContext) (*structpb.Struct, error) {
 	res := map[string]interface{}{}
 	for _, mapper := range m.resourceMappers {
 		mapper(r, res)
 	}
 
-	return structpb.NewStruct(res)
+	if m.graphql {
+		m.graphqlResourceMapper(r, res)
+	}
+
+	if m.manualIdentity != "" {
+		internal.AddManualIdentity(identity, m.manualIdentity, res)
+	}
+
+	return internal.NewResource(res, m.omitEmptyResource)
 }
 
 func (m *Middleware) is(
@@ -138,6 +395,31 @@ func (m *Middleware) is(
 	identityContext *api.IdentityContext,
 	policyContext *api.PolicyContext,
 	resourceContext *structpb.Struct,
+) (bool, error) {
+	start := time.Now()
+
+	ctx, span := internal.StartAuthorizeSpan(ctx, m.tracer, policyContext)
+	allowed, err := m.callAuthorizer(ctx, identityContext, policyContext, resourceContext)
+	internal.EndAuthorizeSpan(span, allowed, err)
+
+	elapsed := time.Since(start)
+	m.metrics.Observe(policyContext, elapsed, allowed, err)
+
+	if m.decisionLogger != nil {
+		event := internal.NewDecisionEvent(identityContext, policyContext, resourceContext, allowed, elapsed, err)
+		event.RequestID = RequestIDFromContext(ctx)
+
+		m.decisionLogger(ctx, event)
+	}
+
+	return allowed, err
+}
+
+func (m *Middleware) callAuthorizer(
+	ctx context.Context,
+	identityContext *api.IdentityContext,
+	policyContext *api.PolicyContext,
+	resourceContext *structpb.Struct,
 ) (bool, error) {
 	isRequest := &authz.IsRequest{
 		IdentityContext: identityContext,
@@ -146,31 +428,51 @@ func (m *Middleware) is(
 		PolicyInstance:  internal.DefaultPolicyInstance(m.policy),
 	}
 
-	logger := zerolog.Ctx(ctx).With().Interface("is_request", isRequest).Logger()
-	logger.Debug().Msg("authorizing request")
-	ctx = logger.WithContext(ctx)
+	if m.propagateRID {
+		ctx = outgoingRequestID(ctx)
+	}
+
+	log := internal.NewRequestLog(ctx, m.logLevel, "is_request", isRequest, m.logRedactions)
+
+	log.Debug().Msg("authorizing request")
+	ctx = log.WithContext(ctx)
 
 	resp, err := m.client.Is(ctx, isRequest)
 
-	switch {
-	case err != nil:
-		return false, cerr.WithContext(err, ctx)
-	case len(resp.Decisions) != 1:
-		return false, cerr.WithContext(aerr.ErrInvalidDecision, ctx)
+	if err != nil {
+		ctx = log.WithRequestContext(ctx)
+
+		allowed, err := internal.ResolveError(ctx, m.errorOutcomes, &m.failureModes, policyContext.GetPath(), err)
+		if err != nil {
+			return false, cerr.WithContext(err, ctx)
+		}
+
+		return allowed, nil
+	}
+
+	allowed, err := internal.Outcome(resp, m.decision)
+	if err != nil {
+		return false, cerr.WithContext(err, log.WithRequestContext(ctx))
 	}
 
-	if !resp.Decisions[0].Is {
-		logger.Info().Msg("authorization failed")
+	if m.postProcessor != nil {
+		if allowed, err = m.postProcessor(ctx, allowed, resp); err != nil {
+			return false, cerr.WrapContext(err, log.WithRequestContext(ctx), "decision post-processor failed")
+		}
 	}
 
-	return resp.Decisions[0].Is, nil
+	if !allowed {
+		log.Info().Msg("authorization failed")
+	}
+
+	return allowed, nil
 }
 
 // WithPolicyFromURL instructs the middleware to construct the policy path from the path segment
 // of the incoming request's URL.
 //
 // Path separators ('/') are replaced with dots ('.').
-// An optional prefix can be specified to be included in all paths.
+// An optional prefix can be specified to be included in all paths. If the prefix is empty, the policy's Root is used.
 //
 // # Example
 //
@@ -182,14 +484,43 @@ func (m *Middleware) is(
 //
 //	"myapp.POST.api.products"
 func (m *Middleware) WithPolicyFromURL(prefix string) *Middleware {
-	m.policyMapper = urlPolicyPathMapper(prefix)
+	if prefix == "" {
+		prefix = m.policy.Root
+	}
+
+	m.graphql = false
+	m.policyMapper = urlPolicyPathMapper(prefix, &m.pathFormat)
+
+	return m
+}
+
+// WithPathSeparator sets the separator that joins the segments of policy paths derived from requests by the
+// middleware's URL and route pattern policy mappers. Default: ".".
+func (m *Middleware) WithPathSeparator(sep string) *Middleware {
+	m.pathFormat.Separator = sep
+	return m
+}
+
+// WithPathCase sets the case of policy paths derived from requests by the middleware's URL and route pattern policy
+// mappers. The policy prefix is left unchanged. Default: middleware.PathCasePreserve.
+func (m *Middleware) WithPathCase(pathCase middleware.PathCase) *Middleware {
+	m.pathFormat.Case = pathCase
+	return m
+}
+
+// WithPathParamPrefix sets the prefix of route parameter names in policy paths derived from route patterns
+// (e.g. by WithPolicyFromGatewayPattern). Pass an empty string to use bare parameter names. Default: "__".
+func (m *Middleware) WithPathParamPrefix(prefix string) *Middleware {
+	m.pathFormat.ParamPrefix = prefix
 	return m
 }
 
 // WithPolicyPathMapper sets a custom policy mapper, a function that takes an incoming request
 // and returns the path within the policy of the package to query.
 func (m *Middleware) WithPolicyPathMapper(mapper StringMapper) *Middleware {
+	m.graphql = false
 	m.policyMapper = mapper
+
 	return m
 }
 
@@ -200,6 +531,117 @@ func (m *Middleware) WithNoResourceContext() *Middleware {
 	return m
 }
 
+// WithErrorCodeMapping determines how the middleware responds when the authorizer fails with specific gRPC codes.
+// Errors with a code that isn't in the mapping fail the request.
+//
+// For example, to treat a missing policy instance as a denied request:
+//
+//	mw.WithErrorCodeMapping(map[codes.Code]middleware.Outcome{codes.NotFound: middleware.OutcomeDeny})
+func (m *Middleware) WithErrorCodeMapping(mapping map[codes.Code]middleware.Outcome) *Middleware {
+	m.errorOutcomes = mapping
+	return m
+}
+
+// WithFailureMode sets the middleware.FailureMode of the middleware or, if policy paths are given, of requests
+// authorized using those paths. Default: middleware.FailClosed.
+func (m *Middleware) WithFailureMode(mode middleware.FailureMode, policyPaths ...string) *Middleware {
+	m.failureModes.Set(mode, policyPaths...)
+	return m
+}
+
+// WithDenialStatus sets the HTTP status code returned when authorization is denied. Default: 403 Forbidden.
+//
+// For example, use http.StatusNotFound to avoid revealing the existence of resources the caller can't access.
+func (m *Middleware) WithDenialStatus(code int) *Middleware {
+	m.denialStatus = code
+	return m
+}
+
+// WithErrorStatus sets the HTTP status code returned when authorization fails with an error.
+// Default: 500 Internal Server Error.
+func (m *Middleware) WithErrorStatus(code int) *Middleware {
+	m.errorStatus = code
+	return m
+}
+
+// WithUnavailableStatus sets the HTTP status code returned when the authorizer is unavailable (i.e. the
+// authorization call fails with codes.Unavailable). Default: 503 Service Unavailable.
+//
+// Unavailable errors mapped to an outcome using WithErrorCodeMapping don't fail the request.
+func (m *Middleware) WithUnavailableStatus(code int) *Middleware {
+	m.unavailStatus = code
+	return m
+}
+
+// WithRetryAfter sets the Retry-After header in responses sent when the authorizer is unavailable, to signal clients
+// to back off. The duration is rounded up to whole seconds.
+func (m *Middleware) WithRetryAfter(d time.Duration) *Middleware {
+	m.retryAfter = d
+	return m
+}
+
+// WithDenialHandler sets a handler that writes the response when authorization is denied.
+// It takes precedence over WithDenialStatus.
+func (m *Middleware) WithDenialHandler(handler http.HandlerFunc) *Middleware {
+	m.denialHandler = handler
+	return m
+}
+
+// WithRedirectOnAnonymousDeny redirects unauthenticated requests that are denied to loginURL instead of responding
+// with the denial status. The original request URL is passed to the login page in the "return_to" query parameter.
+// Denied requests from authenticated callers are handled as usual.
+//
+// A request is unauthenticated if the identity builder didn't resolve an identity or resolved the subject set with
+// IdentityBuilder.AnonymousAs.
+func (m *Middleware) WithRedirectOnAnonymousDeny(loginURL string) *Middleware {
+	m.loginURL = loginURL
+	return m
+}
+
+// WithDecision selects the decision used to authorize requests by name, overriding the policy's Decision.
+//
+// By default, the middleware requires the authorizer to respond with exactly one decision. With WithDecision,
+// responses may include additional decisions (e.g. to be inspected by a decision post-processor) and the outcome
+// is that of the decision whose name matches. Responses without a matching decision fail.
+func (m *Middleware) WithDecision(name string) *Middleware {
+	m.decision = name
+	return m
+}
+
+// WithLogLevel sets the minimum level of the middleware's own log messages, such as the debug-level "authorizing
+// request" message, independently of the level of the logger in the request context.
+// For example, WithLogLevel(zerolog.DebugLevel) enables authorization tracing while other debug logs stay disabled.
+// Note that the zerolog global level still applies.
+func (m *Middleware) WithLogLevel(level zerolog.Level) *Middleware {
+	m.logLevel = &level
+	return m
+}
+
+// WithLogRedaction replaces the values of the named fields with "***" in authorization requests written to the
+// middleware's debug logs. The field "identity" is the caller's identity. Other fields are in the resource context and
+// nested fields use dot notation (e.g. "user.email").
+//
+// Redaction only applies to logs. Authorization calls receive the original values.
+func (m *Middleware) WithLogRedaction(fields ...string) *Middleware {
+	m.logRedactions = append(m.logRedactions, fields...)
+	return m
+}
+
+// WithDecisionPostProcessor sets a function that is called after the authorizer responds and can override its
+// decision. This allows applying additional business rules (e.g. a feature-flag controlled freeze) to
+// authorization decisions.
+func (m *Middleware) WithDecisionPostProcessor(processor middleware.DecisionPostProcessor) *Middleware {
+	m.postProcessor = processor
+	return m
+}
+
+// WithDecisionLogger sets a function that is called after every authorization call with a description of the
+// decision, including the caller's identity, the evaluated policy, the resource context, and the outcome.
+func (m *Middleware) WithDecisionLogger(logger middleware.DecisionLogger) *Middleware {
+	m.decisionLogger = logger
+	return m
+}
+
 // WithResourceMapper sets a custom resource mapper, a function that takes an incoming request
 // and returns the resource object to include with the authorization request as a `structpb.Struct`.
 func (m *Middleware) WithResourceMapper(mapper ResourceMapper) *Middleware {
@@ -207,15 +649,192 @@ func (m *Middleware) WithResourceMapper(mapper ResourceMapper) *Middleware {
 	return m
 }
 
-func urlPolicyPathMapper(prefix string) StringMapper {
-	return func(r *http.Request) string {
-		policyPath := append([]string{r.Method}, getPathSegments(r)...)
+// WithoutEmptyResource sends authorization calls without a resource context when resource mappers don't produce any
+// fields, instead of sending an empty resource. This lets policies distinguish "no resource" from "empty resource".
+func (m *Middleware) WithoutEmptyResource() *Middleware {
+	m.omitEmptyResource = true
+	return m
+}
+
+// WithResourceFallback adds the fields produced by the primary ResourceMapper to the resource context. If primary
+// produces no fields, the fields produced by fallback are added instead.
+func (m *Middleware) WithResourceFallback(primary, fallback ResourceMapper) *Middleware {
+	return m.WithResourceMapper(func(r *http.Request, resource map[string]interface{}) {
+		fields := map[string]interface{}{}
+
+		primary(r, fields)
+
+		if len(fields) == 0 {
+			fallback(r, fields)
+		}
+
+		for k, v := range fields {
+			resource[k] = v
+		}
+	})
+}
+
+// WithManualIdentityInResource adds the caller's identity to the resource context under the given field when the
+// identity type is manual. Manual identities are passed to policies as-is, so this lets policies that don't read
+// input.identity access them.
+func (m *Middleware) WithManualIdentityInResource(field string) *Middleware {
+	m.manualIdentity = field
+
+	return m
+}
 
-		if prefix != "" {
-			policyPath = append([]string{strings.Trim(prefix, ".")}, policyPath...)
+// WithResourceFromQuery adds the specified query string parameters to the resource context.
+// Single values are added as strings and repeated parameters as lists of strings.
+// Pass "*" to include all query parameters.
+func (m *Middleware) WithResourceFromQuery(params ...string) *Middleware {
+	return m.WithResourceMapper(func(r *http.Request, resource map[string]interface{}) {
+		internal.QueryResource(r.URL.Query(), params, resource)
+	})
+}
+
+// WithRawRequestInResource adds the request's escaped URL path and raw query string to the resource context under the
+// given fields, so policies and decision logs can see exactly what was requested. An empty field name omits the
+// corresponding value.
+//
+// For example, with WithRawRequestInResource("path", "query"), a request to "/api/users?role=admin" gets the resource
+// context {"path": "/api/users", "query": "role=admin"}.
+func (m *Middleware) WithRawRequestInResource(pathField, queryField string) *Middleware {
+	return m.WithResourceMapper(func(r *http.Request, resource map[string]interface{}) {
+		if pathField != "" {
+			resource[pathField] = r.URL.EscapedPath()
 		}
 
-		return strings.Join(policyPath, ".")
+		if queryField != "" {
+			resource[queryField] = r.URL.RawQuery
+		}
+	})
+}
+
+// WithBypassSafeMethods lets requests with safe HTTP methods (GET, HEAD, and OPTIONS) to the specified URL paths
+// proceed without authorization. Requests with other methods to the same paths are still authorized.
+// Paths are matched exactly against the request's URL path (e.g. "/api/products").
+// WithBypassSafeMethods can be called multiple times to add more paths.
+func (m *Middleware) WithBypassSafeMethods(paths ...string) *Middleware {
+	if m.bypassPaths == nil {
+		m.bypassPaths = internal.Lookup[string]{}
+	}
+
+	for _, path := range paths {
+		m.bypassPaths[path] = struct{}{}
+	}
+
+	return m
+}
+
+// WithAllowedPaths lets requests to the specified URL paths proceed without authorization, regardless of their
+// HTTP method. Paths are matched exactly against the request's URL path (e.g. "/healthz"). Paths that end with '*'
+// match all request paths that start with the rest of the path (e.g. "/static/*").
+func (m *Middleware) WithAllowedPaths(paths ...string) *Middleware {
+	m.allowedPaths = internal.NewPathMatcher(paths...)
+	return m
+}
+
+// WithAllowedMethods lets requests with the specified HTTP methods (e.g. "OPTIONS") proceed without authorization.
+func (m *Middleware) WithAllowedMethods(methods ...string) *Middleware {
+	m.allowedMethods = internal.NewMethodLookup(methods...)
+	return m
+}
+
+// WithTrustedPeers lets requests from the specified networks proceed without authorization, before the authorizer
+// is called. Entries are CIDR blocks (e.g. "10.0.0.0/8") or single IP addresses, matched against the request's
+// remote address. Forwarding headers such as X-Forwarded-For aren't considered.
+// If an entry is invalid, requests fail with middleware.ErrInvalidTrustedPeer.
+func (m *Middleware) WithTrustedPeers(cidrs ...string) *Middleware {
+	m.trusted.WithCIDRs(cidrs...)
+	return m
+}
+
+// WithTrustedSPIFFEIDs lets requests from clients that present a verified TLS certificate with one of the specified
+// SPIFFE IDs (e.g. "spiffe://example.org/ns/default/sa/api") proceed without authorization.
+func (m *Middleware) WithTrustedSPIFFEIDs(ids ...string) *Middleware {
+	m.trusted.WithSPIFFEIDs(ids...)
+	return m
+}
+
+// WithAuthorizePreflight determines whether CORS preflight requests are authorized.
+//
+// Browsers don't include credentials in preflight requests (OPTIONS requests with an Access-Control-Request-Method
+// header), so by default they proceed without authorization. Pass true to authorize them like any other request.
+func (m *Middleware) WithAuthorizePreflight(authorize bool) *Middleware {
+	m.authzPreflight = authorize
+	return m
+}
+
+// WithResourceFromPathRegex adds a resource mapper that matches pattern against the request's URL path and maps
+// numbered capture groups to resource fields.
+// Groups that are missing from groupFields or that don't participate in the match are ignored. If the path doesn't
+// match the pattern, no fields are added.
+//
+// # Example
+//
+//	mw.WithResourceFromPathRegex(
+//		regexp.MustCompile(`^/orgs/([^/]+)/repos/([^/]+)$`),
+//		map[int]string{1: "org_id", 2: "repo_id"},
+//	)
+func (m *Middleware) WithResourceFromPathRegex(pattern *regexp.Regexp, groupFields map[int]string) *Middleware {
+	return m.WithResourceMapper(pathRegexResourceMapper(pattern, groupFields))
+}
+
+func (m *Middleware) isBypassed(r *http.Request) bool {
+	if m.allowedMethods.Contains(r.Method) || m.allowedPaths.Matches(r.URL.Path) || m.trusted.TrustsRequest(r) {
+		return true
+	}
+
+	if !m.authzPreflight && isPreflight(r) {
+		return true
+	}
+
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return m.bypassPaths.Contains(r.URL.Path)
+	default:
+		return false
+	}
+}
+
+// isPreflight returns true if r is a CORS preflight request.
+func isPreflight(r *http.Request) bool {
+	return r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+}
+
+func pathRegexResourceMapper(pattern *regexp.Regexp, groupFields map[int]string) ResourceMapper {
+	return func(r *http.Request, resource map[string]interface{}) {
+		match := pattern.FindStringSubmatchIndex(r.URL.Path)
+		if match == nil {
+			return
+		}
+
+		for group, field := range groupFields {
+			if group <= 0 || 2*group+1 >= len(match) || match[2*group] < 0 {
+				continue
+			}
+
+			resource[field] = r.URL.Path[match[2*group]:match[2*group+1]]
+		}
+	}
+}
+
+// PolicyPathFor returns the policy path the middleware's default policy mapper derives from a request.
+// The path has the form "prefix.METHOD.path.to.endpoint".
+//
+// It can be used to test that routes map to the expected policy paths.
+func PolicyPathFor(r *http.Request, prefix string) string {
+	format := middleware.DefaultPathFormat()
+	return policyPathFor(r, prefix, &format)
+}
+
+func policyPathFor(r *http.Request, prefix string, format *middleware.PathFormat) string {
+	return format.Join(prefix, append([]string{r.Method}, getPathSegments(r)...)...)
+}
+
+func urlPolicyPathMapper(prefix string, format *middleware.PathFormat) StringMapper {
+	return func(r *http.Request) string {
+		return policyPathFor(r, prefix, format)
 	}
 }
 
@@ -1,25 +1,59 @@
 package httpz_test
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/aserto-dev/go-aserto/middleware"
 	"github.com/aserto-dev/go-aserto/middleware/httpz"
 	"github.com/aserto-dev/go-aserto/middleware/internal/test"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/rs/zerolog"
 	assert "github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 type TestCase struct {
 	*test.Case
 	expectedStatusCode int
 	middleware         *httpz.Middleware
+	options            *testOptions
 }
 
 type testOptions struct {
 	test.Options
 	expectedStatusCode int
 	callback           func(*httpz.Middleware)
+
+	// policy is used to create the middleware. Default: test.Policy("").
+	policy *middleware.Policy
+
+	// method and url of the test request. Default: GET https://example.com/foo.
+	method string
+	url    string
+
+	// body of the test request. Default: empty.
+	body string
+
+	// prepare, if set, modifies the test request before it is served.
+	prepare func(*http.Request) *http.Request
+
+	// handler is called if the request is authorized. Default: noopHandler.
+	handler http.HandlerFunc
+
+	// verify, if set, makes additional assertions on the response.
+	verify func(*testing.T, *http.Response)
 }
 
 func (opts *testOptions) HasStatusCode() bool {
@@ -37,9 +71,25 @@ func NewTest(t *testing.T, name string, options *testOptions) *TestCase {
 		options.expectedStatusCode = http.StatusOK
 	}
 
+	if options.policy == nil {
+		options.policy = test.Policy("")
+	}
+
+	if options.method == "" {
+		options.method = http.MethodGet
+	}
+
+	if options.url == "" {
+		options.url = "https://example.com/foo"
+	}
+
+	if options.handler == nil {
+		options.handler = noopHandler
+	}
+
 	base := test.NewTest(t, name, &options.Options)
 
-	mw := httpz.New(base.Client, test.Policy(""))
+	mw := httpz.New(base.Client, options.policy)
 
 	if options.callback == nil {
 		mw.Identity.Subject().ID(test.DefaultUsername)
@@ -47,7 +97,18 @@ func NewTest(t *testing.T, name string, options *testOptions) *TestCase {
 		options.callback(mw)
 	}
 
-	return &TestCase{Case: base, expectedStatusCode: options.expectedStatusCode, middleware: mw}
+	return &TestCase{Case: base, expectedStatusCode: options.expectedStatusCode, middleware: mw, options: options}
+}
+
+func runTests(t *testing.T, tests ...*TestCase) {
+	t.Helper()
+
+	for _, test := range tests {
+		t.Run(
+			test.Case.Name,
+			testCase(test),
+		)
+	}
 }
 
 func TestAuthorizer(t *testing.T) {
@@ -83,32 +144,778 @@ func TestAuthorizer(t *testing.T) {
 				},
 			},
 		),
+		NewTest(
+			t,
+			"unmapped authorizer errors should fail",
+			&testOptions{
+				Options: test.Options{
+					Err: status.Error(codes.NotFound, "policy not found"),
+				},
+				expectedStatusCode: http.StatusInternalServerError,
+			},
+		),
+		NewTest(
+			t,
+			"authorizer errors mapped to deny should be forbidden",
+			&testOptions{
+				Options: test.Options{
+					Err: status.Error(codes.NotFound, "policy not found"),
+				},
+				expectedStatusCode: http.StatusForbidden,
+				callback: func(mw *httpz.Middleware) {
+					mw.WithErrorCodeMapping(
+						map[codes.Code]middleware.Outcome{codes.NotFound: middleware.OutcomeDeny},
+					).Identity.Subject().ID(test.DefaultUsername)
+				},
+			},
+		),
+		NewTest(
+			t,
+			"authorizer errors mapped to allow should succeed",
+			&testOptions{
+				Options: test.Options{
+					Err: status.Error(codes.Unavailable, "unavailable"),
+				},
+				callback: func(mw *httpz.Middleware) {
+					mw.WithErrorCodeMapping(
+						map[codes.Code]middleware.Outcome{codes.Unavailable: middleware.OutcomeAllow},
+					).Identity.Subject().ID(test.DefaultUsername)
+				},
+			},
+		),
+		NewTest(
+			t,
+			"denials should use the configured status",
+			&testOptions{
+				Options: test.Options{
+					Reject: true,
+				},
+				expectedStatusCode: http.StatusNotFound,
+				callback: func(mw *httpz.Middleware) {
+					mw.WithDenialStatus(http.StatusNotFound).Identity.Subject().ID(test.DefaultUsername)
+				},
+			},
+		),
+		NewTest(
+			t,
+			"errors should use the configured status",
+			&testOptions{
+				Options: test.Options{
+					Err: status.Error(codes.Internal, "failed"),
+				},
+				expectedStatusCode: http.StatusBadGateway,
+				callback: func(mw *httpz.Middleware) {
+					mw.WithErrorStatus(http.StatusBadGateway).Identity.Subject().ID(test.DefaultUsername)
+				},
+			},
+		),
+		NewTest(
+			t,
+			"unavailable authorizer should be service unavailable",
+			&testOptions{
+				Options: test.Options{
+					Err: status.Error(codes.Unavailable, "unavailable"),
+				},
+				expectedStatusCode: http.StatusServiceUnavailable,
+			},
+		),
+		NewTest(
+			t,
+			"unavailable authorizer should use the configured status",
+			&testOptions{
+				Options: test.Options{
+					Err: status.Error(codes.Unavailable, "unavailable"),
+				},
+				expectedStatusCode: http.StatusBadGateway,
+				callback: func(mw *httpz.Middleware) {
+					mw.WithUnavailableStatus(http.StatusBadGateway).Identity.Subject().ID(test.DefaultUsername)
+				},
+			},
+		),
+		NewTest(
+			t,
+			"denial handler should write the response",
+			&testOptions{
+				Options: test.Options{
+					Reject: true,
+				},
+				expectedStatusCode: http.StatusUnauthorized,
+				callback: func(mw *httpz.Middleware) {
+					mw.WithDenialStatus(http.StatusNotFound).
+						WithDenialHandler(func(w http.ResponseWriter, _ *http.Request) {
+							w.WriteHeader(http.StatusUnauthorized)
+						}).
+						Identity.Subject().ID(test.DefaultUsername)
+				},
+			},
+		),
 	}
 
-	for _, test := range tests {
-		t.Run(
-			test.Case.Name,
-			testCase(test),
-		)
-	}
+	runTests(t, tests...)
 }
 
 func noopHandler(_ http.ResponseWriter, _ *http.Request) {}
 
 func testCase(testCase *TestCase) func(*testing.T) {
 	return func(t *testing.T) {
-		handler := testCase.middleware.Handler(http.HandlerFunc(noopHandler))
+		opts := testCase.options
+
+		var body io.Reader = http.NoBody
+		if opts.body != "" {
+			body = strings.NewReader(opts.body)
+		}
 
-		req := httptest.NewRequest(http.MethodGet, "https://example.com/foo", http.NoBody)
+		req := httptest.NewRequest(opts.method, opts.url, body)
 		req.Header.Add("Authorization", test.DefaultUsername)
 
+		if opts.prepare != nil {
+			req = opts.prepare(req)
+		}
+
 		w := httptest.NewRecorder()
 
-		handler.ServeHTTP(w, req)
+		testCase.middleware.Handler(opts.handler).ServeHTTP(w, req)
 
 		resp := w.Result()
 		defer resp.Body.Close()
 
 		assert.Equal(t, testCase.expectedStatusCode, resp.StatusCode)
+
+		if opts.verify != nil {
+			opts.verify(t, resp)
+		}
+	}
+}
+
+// withLogger returns a request preparer that adds logger to the request context.
+func withLogger(logger zerolog.Logger) func(*http.Request) *http.Request {
+	return func(r *http.Request) *http.Request {
+		return r.WithContext(logger.WithContext(r.Context()))
+	}
+}
+
+func TestResourceFromPathRegex(t *testing.T) {
+	resource, err := structpb.NewStruct(map[string]interface{}{"org_id": "acme", "repo_id": "widgets"})
+	assert.NoError(t, err)
+
+	runTests(t, NewTest(t, "path regex", &testOptions{
+		Options: test.Options{
+			ExpectedRequest: test.Request(test.PolicyPath(DefaultPolicyPath), test.Resource(resource)),
+		},
+		policy: test.Policy(DefaultPolicyPath),
+		url:    "https://example.com/orgs/acme/repos/widgets",
+		callback: func(mw *httpz.Middleware) {
+			mw.WithResourceFromPathRegex(
+				regexp.MustCompile(`^/orgs/([^/]+)/repos/([^/]+)(/settings)?$`),
+				map[int]string{1: "org_id", 2: "repo_id", 3: "settings", 4: "out_of_range"},
+			).Identity.Subject()
+		},
+	}))
+}
+
+func TestManualIdentityInResource(t *testing.T) {
+	tests := []*TestCase{}
+
+	for _, tc := range []struct {
+		name     string
+		idType   api.IdentityType
+		resource map[string]interface{}
+	}{
+		{
+			"manual identity is added",
+			api.IdentityType_IDENTITY_TYPE_MANUAL,
+			map[string]interface{}{"user": test.DefaultUsername},
+		},
+		{"subject identity is ignored", api.IdentityType_IDENTITY_TYPE_SUB, map[string]interface{}{}},
+	} {
+		resource, err := structpb.NewStruct(tc.resource)
+		assert.NoError(t, err)
+
+		tests = append(tests, NewTest(t, tc.name, &testOptions{
+			Options: test.Options{
+				ExpectedRequest: test.Request(
+					test.PolicyPath(DefaultPolicyPath),
+					test.IdentityType(tc.idType),
+					test.Resource(resource),
+				),
+			},
+			callback: func(mw *httpz.Middleware) {
+				mw.WithManualIdentityInResource("user")
+
+				if tc.idType == api.IdentityType_IDENTITY_TYPE_MANUAL {
+					mw.Identity.Manual()
+				} else {
+					mw.Identity.Subject()
+				}
+			},
+		}))
+	}
+
+	runTests(t, tests...)
+}
+
+func TestManualIdentityResolvedOnce(t *testing.T) {
+	resource, err := structpb.NewStruct(map[string]interface{}{"user": test.DefaultUsername})
+	assert.NoError(t, err)
+
+	calls := 0
+
+	runTests(t, NewTest(t, "identity sources run once", &testOptions{
+		Options: test.Options{
+			ExpectedRequest: test.Request(
+				test.PolicyPath(DefaultPolicyPath),
+				test.IdentityType(api.IdentityType_IDENTITY_TYPE_MANUAL),
+				test.Resource(resource),
+			),
+		},
+		callback: func(mw *httpz.Middleware) {
+			mw.WithManualIdentityInResource("user").Identity.Manual().Mapper(func(_ *http.Request, identity middleware.Identity) {
+				calls++
+
+				identity.ID(test.DefaultUsername)
+			})
+		},
+	}))
+
+	assert.Equal(t, 1, calls)
+}
+
+func TestWithoutEmptyResource(t *testing.T) {
+	fields, err := structpb.NewStruct(map[string]interface{}{"id": "foo"})
+	assert.NoError(t, err)
+
+	tests := []*TestCase{}
+
+	for _, tc := range []struct {
+		name     string
+		mapper   httpz.ResourceMapper
+		expected *structpb.Struct
+	}{
+		{"empty resource is omitted", func(*http.Request, map[string]interface{}) {}, nil},
+		{"resource with fields is sent", func(_ *http.Request, res map[string]interface{}) { res["id"] = "foo" }, fields},
+	} {
+		tests = append(tests, NewTest(t, tc.name, &testOptions{
+			Options: test.Options{
+				ExpectedRequest: test.Request(test.PolicyPath(DefaultPolicyPath), test.Resource(tc.expected)),
+			},
+			callback: func(mw *httpz.Middleware) {
+				mw.WithResourceMapper(tc.mapper).WithoutEmptyResource().Identity.Subject()
+			},
+		}))
+	}
+
+	runTests(t, tests...)
+}
+
+func TestFailureMode(t *testing.T) {
+	tests := []*TestCase{}
+
+	for name, tc := range map[string]struct {
+		err      error
+		reject   bool
+		mode     func(*httpz.Middleware)
+		expected int
+	}{
+		"fail closed by default": {
+			err:      status.Error(codes.Unavailable, "unavailable"),
+			mode:     func(*httpz.Middleware) {},
+			expected: http.StatusServiceUnavailable,
+		},
+		"fail open": {
+			err:      status.Error(codes.Unavailable, "unavailable"),
+			mode:     func(mw *httpz.Middleware) { mw.WithFailureMode(middleware.FailOpen) },
+			expected: http.StatusOK,
+		},
+		"fail open for policy path": {
+			err:      status.Error(codes.Internal, "failed"),
+			mode:     func(mw *httpz.Middleware) { mw.WithFailureMode(middleware.FailOpen, DefaultPolicyPath) },
+			expected: http.StatusOK,
+		},
+		"fail open for other policy path": {
+			err:      status.Error(codes.Internal, "failed"),
+			mode:     func(mw *httpz.Middleware) { mw.WithFailureMode(middleware.FailOpen, "GET.bar") },
+			expected: http.StatusInternalServerError,
+		},
+		"policy path overrides fail open": {
+			err: status.Error(codes.Internal, "failed"),
+			mode: func(mw *httpz.Middleware) {
+				mw.WithFailureMode(middleware.FailOpen).WithFailureMode(middleware.FailClosed, DefaultPolicyPath)
+			},
+			expected: http.StatusInternalServerError,
+		},
+		"denials are not affected": {
+			reject:   true,
+			mode:     func(mw *httpz.Middleware) { mw.WithFailureMode(middleware.FailOpen) },
+			expected: http.StatusForbidden,
+		},
+	} {
+		tests = append(tests, NewTest(t, name, &testOptions{
+			Options:            test.Options{Err: tc.err, Reject: tc.reject},
+			expectedStatusCode: tc.expected,
+			callback: func(mw *httpz.Middleware) {
+				tc.mode(mw)
+				mw.Identity.Subject()
+			},
+		}))
+	}
+
+	runTests(t, tests...)
+}
+
+func TestRawRequestInResource(t *testing.T) {
+	resource, err := structpb.NewStruct(map[string]interface{}{"path": "/api/a%2Fb", "query": "role=admin&x=%20"})
+	assert.NoError(t, err)
+
+	runTests(t, NewTest(t, "raw request", &testOptions{
+		Options: test.Options{
+			ExpectedRequest: test.Request(test.PolicyPath(DefaultPolicyPath), test.Resource(resource)),
+		},
+		policy: test.Policy(DefaultPolicyPath),
+		url:    "https://example.com/api/a%2Fb?role=admin&x=%20",
+		callback: func(mw *httpz.Middleware) {
+			mw.WithRawRequestInResource("path", "query").Identity.Subject()
+		},
+	}))
+}
+
+func TestResourceFallback(t *testing.T) {
+	fromQuery := func(r *http.Request, resource map[string]interface{}) {
+		if id := r.URL.Query().Get("id"); id != "" {
+			resource["id"] = id
+		}
+	}
+
+	fallback := func(_ *http.Request, resource map[string]interface{}) {
+		resource["id"] = "default"
+	}
+
+	tests := []*TestCase{}
+
+	for _, tc := range []struct {
+		name     string
+		url      string
+		expected string
+	}{
+		{"primary fields are used", "https://example.com/foo?id=123", "123"},
+		{"fallback is used if primary is empty", "https://example.com/foo", "default"},
+	} {
+		resource, err := structpb.NewStruct(map[string]interface{}{"id": tc.expected})
+		assert.NoError(t, err)
+
+		tests = append(tests, NewTest(t, tc.name, &testOptions{
+			Options: test.Options{
+				ExpectedRequest: test.Request(test.PolicyPath(DefaultPolicyPath), test.Resource(resource)),
+			},
+			url: tc.url,
+			callback: func(mw *httpz.Middleware) {
+				mw.WithResourceFallback(fromQuery, fallback).Identity.Subject()
+			},
+		}))
+	}
+
+	runTests(t, tests...)
+}
+
+func TestIdentityInContext(t *testing.T) {
+	var identity string
+
+	runTests(t, NewTest(t, "identity in context", &testOptions{
+		callback: func(mw *httpz.Middleware) { mw.Identity.Subject() },
+		handler: func(_ http.ResponseWriter, r *http.Request) {
+			identity = middleware.IdentityFromContext(r.Context()).GetIdentity()
+		},
+		verify: func(t *testing.T, _ *http.Response) {
+			assert.Equal(t, test.DefaultUsername, identity)
+		},
+	}))
+}
+
+func TestBypassSafeMethods(t *testing.T) {
+	tests := []*TestCase{}
+
+	for method, expected := range map[string]int{
+		http.MethodGet:    http.StatusOK,
+		http.MethodHead:   http.StatusOK,
+		http.MethodDelete: http.StatusForbidden,
+	} {
+		tests = append(tests, NewTest(t, method, &testOptions{
+			Options:            test.Options{PolicyPath: method + ".public", Reject: true},
+			expectedStatusCode: expected,
+			method:             method,
+			url:                "https://example.com/public",
+			callback: func(mw *httpz.Middleware) {
+				mw.WithBypassSafeMethods("/public").Identity.Subject()
+			},
+		}))
+	}
+
+	tests = append(tests, NewTest(t, "paths are added", &testOptions{
+		Options: test.Options{Reject: true},
+		callback: func(mw *httpz.Middleware) {
+			mw.WithBypassSafeMethods("/foo").WithBypassSafeMethods("/public").Identity.Subject()
+		},
+	}))
+
+	runTests(t, tests...)
+}
+
+func TestAllowedPathsAndMethods(t *testing.T) {
+	tests := []*TestCase{}
+
+	for name, tc := range map[string]struct {
+		method   string
+		path     string
+		expected int
+	}{
+		"exact path":        {method: http.MethodGet, path: "/healthz", expected: http.StatusOK},
+		"prefix path":       {method: http.MethodPost, path: "/static/app.js", expected: http.StatusOK},
+		"allowed method":    {method: http.MethodOptions, path: "/api", expected: http.StatusOK},
+		"not allowed":       {method: http.MethodGet, path: "/api", expected: http.StatusForbidden},
+		"unmatched subpath": {method: http.MethodGet, path: "/healthz/live", expected: http.StatusForbidden},
+	} {
+		tests = append(tests, NewTest(t, name, &testOptions{
+			Options: test.Options{
+				PolicyPath: httpz.PolicyPathFor(httptest.NewRequest(tc.method, tc.path, http.NoBody), ""),
+				Reject:     true,
+			},
+			expectedStatusCode: tc.expected,
+			method:             tc.method,
+			url:                "https://example.com" + tc.path,
+			callback: func(mw *httpz.Middleware) {
+				mw.WithAllowedPaths("/healthz", "/static/*").
+					WithAllowedMethods(http.MethodOptions).
+					Identity.Subject()
+			},
+		}))
+	}
+
+	runTests(t, tests...)
+}
+
+func TestTrustedPeers(t *testing.T) {
+	tests := []*TestCase{}
+
+	for name, tc := range map[string]struct {
+		cidrs      []string
+		remoteAddr string
+		expected   int
+	}{
+		"trusted":      {cidrs: []string{"10.0.0.0/8"}, remoteAddr: "10.0.0.1:1234", expected: http.StatusOK},
+		"untrusted":    {cidrs: []string{"10.0.0.0/8"}, remoteAddr: "192.0.2.1:1234", expected: http.StatusForbidden},
+		"invalid cidr": {cidrs: []string{"10.0.0.0/8", "10.0.0.0/33"}, remoteAddr: "10.0.0.1:1234", expected: http.StatusInternalServerError},
+	} {
+		tests = append(tests, NewTest(t, name, &testOptions{
+			Options:            test.Options{Reject: true},
+			expectedStatusCode: tc.expected,
+			callback: func(mw *httpz.Middleware) {
+				mw.WithTrustedPeers(tc.cidrs...).Identity.Subject()
+			},
+			prepare: func(r *http.Request) *http.Request {
+				r.RemoteAddr = tc.remoteAddr
+				return r
+			},
+		}))
+	}
+
+	runTests(t, tests...)
+}
+
+func TestPreflight(t *testing.T) {
+	tests := []*TestCase{}
+
+	for name, tc := range map[string]struct {
+		authorize     bool
+		requestMethod string
+		expected      int
+	}{
+		"preflight is skipped by default":   {requestMethod: http.MethodDelete, expected: http.StatusOK},
+		"preflight can be authorized":       {authorize: true, requestMethod: http.MethodDelete, expected: http.StatusForbidden},
+		"plain OPTIONS requests authorized": {expected: http.StatusForbidden},
+	} {
+		tests = append(tests, NewTest(t, name, &testOptions{
+			Options:            test.Options{PolicyPath: "OPTIONS.foo", Reject: true},
+			expectedStatusCode: tc.expected,
+			method:             http.MethodOptions,
+			callback: func(mw *httpz.Middleware) {
+				mw.WithAuthorizePreflight(tc.authorize).Identity.Subject()
+			},
+			prepare: func(r *http.Request) *http.Request {
+				if tc.requestMethod != "" {
+					r.Header.Add("Access-Control-Request-Method", tc.requestMethod)
+				}
+
+				return r
+			},
+		}))
+	}
+
+	runTests(t, tests...)
+}
+
+func TestPolicyPathFor(t *testing.T) {
+	for _, tc := range []struct {
+		method, url, prefix, expected string
+	}{
+		{http.MethodGet, "https://example.com/foo", "", "GET.foo"},
+		{http.MethodPost, "https://example.com/api/users/", "myapp", "myapp.POST.api.users"},
+		{http.MethodDelete, "https://example.com/api/users", ".myapp.", "myapp.DELETE.api.users"},
+	} {
+		req := httptest.NewRequest(tc.method, tc.url, http.NoBody)
+		assert.Equal(t, tc.expected, httpz.PolicyPathFor(req, tc.prefix))
+	}
+}
+
+func TestPolicyRootPrefix(t *testing.T) {
+	policy := test.Policy("")
+	policy.Root = "myapp"
+
+	runTests(t, NewTest(t, "policy root", &testOptions{
+		Options:  test.Options{PolicyPath: "myapp.GET.foo"},
+		policy:   policy,
+		callback: func(mw *httpz.Middleware) { mw.Identity.Subject() },
+	}))
+}
+
+func TestPathFormat(t *testing.T) {
+	runTests(t, NewTest(t, "path format", &testOptions{
+		Options: test.Options{PolicyPath: "myapp/get/api/users"},
+		url:     "https://example.com/API/Users",
+		callback: func(mw *httpz.Middleware) {
+			mw.WithPolicyFromURL("myapp").
+				WithPathSeparator("/").
+				WithPathCase(middleware.PathCaseLower).
+				Identity.Subject()
+		},
+	}))
+}
+
+func TestDecisionPostProcessor(t *testing.T) {
+	tests := []*TestCase{}
+
+	for name, tc := range map[string]struct {
+		reject   bool
+		override bool
+		err      error
+		expected int
+	}{
+		"allowed decision is overridden": {reject: false, override: false, expected: http.StatusForbidden},
+		"denied decision is overridden":  {reject: true, override: true, expected: http.StatusOK},
+		"post-processor errors fail":     {err: errors.New("freeze"), expected: http.StatusInternalServerError},
+	} {
+		tests = append(tests, NewTest(t, name, &testOptions{
+			Options:            test.Options{Reject: tc.reject},
+			expectedStatusCode: tc.expected,
+			callback: func(mw *httpz.Middleware) {
+				mw.WithDecisionPostProcessor(
+					func(_ context.Context, allowed bool, resp *authz.IsResponse) (bool, error) {
+						assert.Equal(t, !tc.reject, allowed)
+						assert.Len(t, resp.GetDecisions(), 1)
+
+						return tc.override, tc.err
+					},
+				).Identity.Subject()
+			},
+		}))
+	}
+
+	runTests(t, tests...)
+}
+
+func TestWithDecision(t *testing.T) {
+	decisions := []*authz.Decision{
+		{Decision: "allowed", Is: true},
+		{Decision: "visible", Is: false},
+	}
+
+	tests := []*TestCase{}
+
+	for name, tc := range map[string]struct {
+		decision string
+		expected int
+	}{
+		"multiple decisions without selection fail": {expected: http.StatusInternalServerError},
+		"selected decision is used":                 {decision: "visible", expected: http.StatusForbidden},
+		"missing selected decision fails":           {decision: "enabled", expected: http.StatusInternalServerError},
+	} {
+		decision := test.DefaultDecision
+		if tc.decision != "" {
+			decision = tc.decision
+		}
+
+		testCase := NewTest(t, name, &testOptions{
+			Options: test.Options{
+				ExpectedRequest: test.Request(test.PolicyPath(DefaultPolicyPath), test.WithDecision(decision)),
+			},
+			expectedStatusCode: tc.expected,
+			callback: func(mw *httpz.Middleware) {
+				mw.WithDecision(tc.decision).Identity.Subject()
+			},
+		})
+		testCase.Client.WithDecisions(decisions...)
+
+		tests = append(tests, testCase)
+	}
+
+	runTests(t, tests...)
+}
+
+func TestLogLevel(t *testing.T) {
+	tests := []*TestCase{}
+
+	for name, tc := range map[string]struct {
+		override bool
+	}{
+		"context level applies by default":  {},
+		"log level overrides context level": {override: true},
+	} {
+		var buf bytes.Buffer
+
+		tests = append(tests, NewTest(t, name, &testOptions{
+			callback: func(mw *httpz.Middleware) {
+				if tc.override {
+					mw.WithLogLevel(zerolog.DebugLevel)
+				}
+
+				mw.Identity.Subject()
+			},
+			prepare: withLogger(zerolog.New(&buf).Level(zerolog.InfoLevel)),
+			verify: func(t *testing.T, _ *http.Response) {
+				assert.Equal(t, tc.override, strings.Contains(buf.String(), "authorizing request"))
+			},
+		}))
 	}
+
+	runTests(t, tests...)
+}
+
+func TestDenialLog(t *testing.T) {
+	var buf bytes.Buffer
+
+	// Denials are logged with the request even if debug messages aren't written.
+	runTests(t, NewTest(t, "denial log", &testOptions{
+		Options:            test.Options{Reject: true},
+		expectedStatusCode: http.StatusForbidden,
+		prepare:            withLogger(zerolog.New(&buf).Level(zerolog.InfoLevel)),
+		verify: func(t *testing.T, _ *http.Response) {
+			assert.Contains(t, buf.String(), "authorization failed")
+			assert.Contains(t, buf.String(), "is_request")
+			assert.NotContains(t, buf.String(), "authorizing request")
+		},
+	}))
+}
+
+func TestLogRedaction(t *testing.T) {
+	resource, err := structpb.NewStruct(map[string]interface{}{"email": "alice@example.com"})
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+
+	// The authorizer receives the original values.
+	runTests(t, NewTest(t, "log redaction", &testOptions{
+		Options: test.Options{
+			ExpectedRequest: test.Request(test.PolicyPath(DefaultPolicyPath), test.Resource(resource)),
+		},
+		url: "https://example.com/foo?email=alice@example.com",
+		callback: func(mw *httpz.Middleware) {
+			mw.WithResourceFromQuery("email").
+				WithLogLevel(zerolog.DebugLevel).
+				WithLogRedaction("identity", "email").
+				Identity.Subject()
+		},
+		prepare: withLogger(zerolog.New(&buf)),
+		verify: func(t *testing.T, _ *http.Response) {
+			assert.Contains(t, buf.String(), "authorizing request")
+			assert.NotContains(t, buf.String(), "alice@example.com")
+			assert.NotContains(t, buf.String(), test.DefaultUsername)
+		},
+	}))
+}
+
+func TestResourceFromQuery(t *testing.T) {
+	resource, err := structpb.NewStruct(map[string]interface{}{
+		"tenant": "acme",
+		"region": []interface{}{"us", "eu"},
+	})
+	assert.NoError(t, err)
+
+	runTests(t, NewTest(t, "query", &testOptions{
+		Options: test.Options{
+			ExpectedRequest: test.Request(test.PolicyPath(DefaultPolicyPath), test.Resource(resource)),
+		},
+		url: "https://example.com/foo?tenant=acme&region=us&region=eu&page=2",
+		callback: func(mw *httpz.Middleware) {
+			mw.WithResourceFromQuery("tenant", "region").Identity.Subject()
+		},
+	}))
+}
+
+func TestRetryAfter(t *testing.T) {
+	runTests(t, NewTest(t, "retry after", &testOptions{
+		Options:            test.Options{Err: status.Error(codes.Unavailable, "unavailable")},
+		expectedStatusCode: http.StatusServiceUnavailable,
+		callback: func(mw *httpz.Middleware) {
+			mw.WithRetryAfter(1500 * time.Millisecond).Identity.Subject()
+		},
+		verify: func(t *testing.T, resp *http.Response) {
+			assert.Equal(t, "2", resp.Header.Get("Retry-After"))
+		},
+	}))
+}
+
+func TestDecisionLogger(t *testing.T) {
+	authzErr := status.Error(codes.Unavailable, "unavailable")
+
+	resource, err := structpb.NewStruct(map[string]interface{}{"id": "123"})
+	assert.NoError(t, err)
+
+	tests := []*TestCase{}
+
+	for name, tc := range map[string]struct {
+		reject   bool
+		err      error
+		allowed  bool
+		expected int
+	}{
+		"allowed": {allowed: true, expected: http.StatusOK},
+		"denied":  {reject: true, expected: http.StatusForbidden},
+		"failed":  {err: authzErr, expected: http.StatusServiceUnavailable},
+	} {
+		var events []middleware.DecisionEvent
+
+		tests = append(tests, NewTest(t, name, &testOptions{
+			Options: test.Options{
+				ExpectedRequest: test.Request(test.PolicyPath(DefaultPolicyPath), test.Resource(resource)),
+				Reject:          tc.reject,
+				Err:             tc.err,
+			},
+			expectedStatusCode: tc.expected,
+			url:                "https://example.com/foo?id=123",
+			callback: func(mw *httpz.Middleware) {
+				mw.WithResourceFromQuery("id").
+					WithDecisionLogger(func(_ context.Context, event middleware.DecisionEvent) {
+						events = append(events, event)
+					}).
+					Identity.Subject()
+			},
+			verify: func(t *testing.T, _ *http.Response) {
+				assert.Len(t, events, 1)
+
+				event := events[0]
+				assert.Equal(t, test.DefaultUsername, event.Identity.GetIdentity())
+				assert.Equal(t, DefaultPolicyPath, event.PolicyPath)
+				assert.Equal(t, test.DefaultDecision, event.Decision)
+				assert.Equal(t, map[string]interface{}{"id": "123"}, event.Resource)
+				assert.Equal(t, tc.allowed, event.Allowed)
+				assert.Positive(t, event.Duration)
+
+				if tc.err == nil {
+					assert.NoError(t, event.Err)
+				} else {
+					assert.Error(t, event.Err)
+				}
+			},
+		}))
+	}
+
+	runTests(t, tests...)
 }
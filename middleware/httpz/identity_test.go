@@ -0,0 +1,203 @@
+package httpz_test
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	"github.com/aserto-dev/go-aserto/middleware/httpz"
+	"github.com/aserto-dev/go-aserto/middleware/internal/test"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	assert "github.com/stretchr/testify/require"
+)
+
+const sessionCookie = "session"
+
+func requestWithCookie(value string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/foo", http.NoBody)
+	req.AddCookie(&http.Cookie{Name: sessionCookie, Value: value})
+
+	return req
+}
+
+func TestIdentityFromCookie(t *testing.T) {
+	identity := (&httpz.IdentityBuilder{}).JWT().FromCookie(sessionCookie).Build(requestWithCookie("token"))
+
+	assert.Equal(t, api.IdentityType_IDENTITY_TYPE_JWT, identity.GetType())
+	assert.Equal(t, "token", identity.GetIdentity())
+}
+
+func TestIdentityFromMissingCookie(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/foo", http.NoBody)
+	identity := (&httpz.IdentityBuilder{}).Subject().FromCookie(sessionCookie).Build(req)
+
+	assert.Equal(t, api.IdentityType_IDENTITY_TYPE_NONE, identity.GetType())
+	assert.Empty(t, identity.GetIdentity())
+}
+
+func TestSubjectFromCookieToken(t *testing.T) {
+	token, err := jwt.NewBuilder().Subject("alice").Build()
+	assert.NoError(t, err)
+
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.HS256, []byte("secret")))
+	assert.NoError(t, err)
+
+	identity := (&httpz.IdentityBuilder{}).Subject().FromCookie(sessionCookie).Build(requestWithCookie(string(signed)))
+
+	assert.Equal(t, api.IdentityType_IDENTITY_TYPE_SUB, identity.GetType())
+	assert.Equal(t, "alice", identity.GetIdentity())
+}
+
+func TestRejectExpired(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		expiry   time.Duration
+		skew     time.Duration
+		idType   api.IdentityType
+		expected string
+	}{
+		{"valid tokens are accepted", time.Hour, 0, api.IdentityType_IDENTITY_TYPE_SUB, "alice"},
+		{"expired tokens are anonymous", -time.Hour, 0, api.IdentityType_IDENTITY_TYPE_NONE, ""},
+		{"tokens expired within the skew are accepted", -time.Minute, time.Hour, api.IdentityType_IDENTITY_TYPE_SUB, "alice"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			token, err := jwt.NewBuilder().Subject("alice").Expiration(time.Now().Add(tc.expiry)).Build()
+			assert.NoError(t, err)
+
+			signed, err := jwt.Sign(token, jwt.WithKey(jwa.HS256, []byte("secret")))
+			assert.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodGet, "https://example.com/foo", http.NoBody)
+			req.Header.Set("Authorization", "Bearer "+string(signed))
+
+			identity := (&httpz.IdentityBuilder{}).Subject().FromHeader("Authorization").RejectExpired(tc.skew).Build(req)
+
+			assert.Equal(t, tc.idType, identity.GetType())
+			assert.Equal(t, tc.expected, identity.GetIdentity())
+		})
+	}
+}
+
+type tokenKey struct{}
+
+func TestRejectExpiredFromContextValue(t *testing.T) {
+	token, err := jwt.NewBuilder().Subject("alice").Expiration(time.Now().Add(-time.Hour)).Build()
+	assert.NoError(t, err)
+
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.HS256, []byte("secret")))
+	assert.NoError(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/foo", http.NoBody)
+	req = req.WithContext(context.WithValue(req.Context(), tokenKey{}, string(signed)))
+
+	identity := (&httpz.IdentityBuilder{}).JWT().FromContextValue(tokenKey{}).RejectExpired(0).Build(req)
+
+	assert.Equal(t, api.IdentityType_IDENTITY_TYPE_NONE, identity.GetType())
+	assert.Empty(t, identity.GetIdentity())
+}
+
+func TestIdentityFromClientCert(t *testing.T) {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: "service-a"}, EmailAddresses: []string{"a@acme.com"}}
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/foo", http.NoBody)
+	req.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{cert},
+		VerifiedChains:   [][]*x509.Certificate{{cert}},
+	}
+
+	identity := (&httpz.IdentityBuilder{}).Subject().FromClientCert(nil).Build(req)
+	assert.Equal(t, "service-a", identity.GetIdentity())
+
+	identity = (&httpz.IdentityBuilder{}).Subject().FromClientCert(func(c *x509.Certificate) string {
+		return c.EmailAddresses[0]
+	}).Build(req)
+	assert.Equal(t, "a@acme.com", identity.GetIdentity())
+}
+
+func TestIdentityFromUnverifiedClientCert(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/foo", http.NoBody)
+	req.TLS = &tls.ConnectionState{
+		PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "service-a"}}},
+	}
+
+	identity := (&httpz.IdentityBuilder{}).Subject().FromClientCert(nil).Build(req)
+	assert.Equal(t, api.IdentityType_IDENTITY_TYPE_NONE, identity.GetType())
+}
+
+func TestAnonymousAs(t *testing.T) {
+	builder := (&httpz.IdentityBuilder{}).JWT().FromCookie(sessionCookie).AnonymousAs("anonymous")
+
+	identity := builder.Build(httptest.NewRequest(http.MethodGet, "https://example.com/foo", http.NoBody))
+	assert.Equal(t, api.IdentityType_IDENTITY_TYPE_SUB, identity.GetType())
+	assert.Equal(t, "anonymous", identity.GetIdentity())
+
+	identity = builder.Build(requestWithCookie("token"))
+	assert.Equal(t, api.IdentityType_IDENTITY_TYPE_JWT, identity.GetType())
+	assert.Equal(t, "token", identity.GetIdentity())
+}
+
+type userKey struct{}
+
+func TestIdentityPrecedence(t *testing.T) {
+	request := func(header, value string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "https://example.com/foo", http.NoBody)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+
+		if value != "" {
+			req = req.WithContext(context.WithValue(req.Context(), userKey{}, value))
+		}
+
+		return req
+	}
+
+	builder := func(precedence middleware.IdentityPrecedence) *httpz.IdentityBuilder {
+		return (&httpz.IdentityBuilder{}).Subject().FromHeader("Authorization").FromContextValue(userKey{}).
+			Precedence(precedence)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		precedence middleware.IdentityPrecedence
+		req        *http.Request
+		expected   string
+	}{
+		{"last source is used by default", middleware.IdentityLastSource, request("alice", "bob"), "bob"},
+		{"last source doesn't fall back", middleware.IdentityLastSource, request("alice", ""), ""},
+		{"first source is preferred", middleware.IdentityFirstSource, request("alice", "bob"), "alice"},
+		{"first source falls back", middleware.IdentityFirstSource, request("", "bob"), "bob"},
+		{"strict accepts a single identity", middleware.IdentityStrict, request("", "bob"), "bob"},
+		{"strict accepts matching identities", middleware.IdentityStrict, request("bob", "bob"), "bob"},
+		{"strict conflicts are anonymous", middleware.IdentityStrict, request("alice", "bob"), ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, builder(tc.precedence).Build(tc.req).GetIdentity())
+		})
+	}
+}
+
+func TestIdentityConflict(t *testing.T) {
+	base := test.NewTest(t, "identity conflict", &test.Options{})
+
+	mw := httpz.New(base.Client, test.Policy(DefaultPolicyPath))
+	mw.Identity.Subject().FromHeader("Authorization").FromContextValue(userKey{}).Precedence(middleware.IdentityStrict)
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/foo", http.NoBody)
+	req.Header.Set("Authorization", "alice")
+	req = req.WithContext(context.WithValue(req.Context(), userKey{}, "bob"))
+
+	w := httptest.NewRecorder()
+	mw.Handler(http.HandlerFunc(noopHandler)).ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), middleware.ErrIdentityConflict.Error())
+}
@@ -0,0 +1,39 @@
+package httpz_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware/httpz"
+	"github.com/aserto-dev/go-aserto/middleware/internal/test"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	assert "github.com/stretchr/testify/require"
+)
+
+const expectedDecisions = `
+# HELP aserto_authz_decisions_total Number of authorization decisions.
+# TYPE aserto_authz_decisions_total counter
+aserto_authz_decisions_total{allowed="false",decision="allowed"} 1
+aserto_authz_decisions_total{allowed="true",decision="allowed"} 1
+`
+
+func TestMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+
+	for name, reject := range map[string]bool{"allowed": false, "denied": true} {
+		base := test.NewTest(t, name, &test.Options{PolicyPath: DefaultPolicyPath, Reject: reject})
+		mw := httpz.New(base.Client, test.Policy("")).WithMetrics(registry)
+		mw.Identity.Subject().ID(test.DefaultUsername)
+
+		req := httptest.NewRequest(http.MethodGet, "https://example.com/foo", http.NoBody)
+		req.Header.Add("Authorization", test.DefaultUsername)
+
+		mw.Handler(http.HandlerFunc(noopHandler)).ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	assert.NoError(t, testutil.GatherAndCompare(registry, strings.NewReader(expectedDecisions), "aserto_authz_decisions_total"))
+	assert.Equal(t, 1, testutil.CollectAndCount(registry, "aserto_authz_duration_seconds"))
+}
@@ -0,0 +1,50 @@
+package httpz
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	// ProblemContentType is the media type of RFC 7807 problem documents.
+	ProblemContentType = "application/problem+json"
+
+	// DenialDetail is the problem detail included in denial responses.
+	DenialDetail = "The caller is not authorized to perform this request."
+)
+
+// Problem is an RFC 7807 problem document.
+type Problem struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type problemOptions struct {
+	verbose bool
+}
+
+// WithProblemJSON makes the middleware respond to denials and errors with an RFC 7807 problem document
+// (application/problem+json) instead of a plain-text body.
+//
+// Authorizer errors are only included in the problem detail if verbose is true. Otherwise, the detail is omitted to
+// avoid leaking internal errors to callers.
+// Denial handlers set with WithDenialHandler take precedence over problem responses.
+func (m *Middleware) WithProblemJSON(verbose bool) *Middleware {
+	m.problems = &problemOptions{verbose: verbose}
+	return m
+}
+
+func writeProblem(w http.ResponseWriter, status int, detail string) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+
+	_ = json.NewEncoder(w).Encode(&Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	})
+}
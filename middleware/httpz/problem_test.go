@@ -0,0 +1,83 @@
+package httpz_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware/httpz"
+	"github.com/aserto-dev/go-aserto/middleware/internal/test"
+	assert "github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestProblemJSON(t *testing.T) {
+	authzErr := status.Error(codes.Internal, "authorizer failed")
+
+	tests := []struct {
+		name     string
+		options  test.Options
+		verbose  bool
+		expected httpz.Problem
+	}{
+		{
+			name:    "denial",
+			options: test.Options{Reject: true},
+			expected: httpz.Problem{
+				Type:   "about:blank",
+				Title:  "Forbidden",
+				Status: http.StatusForbidden,
+				Detail: httpz.DenialDetail,
+			},
+		},
+		{
+			name:    "error",
+			options: test.Options{Err: authzErr},
+			expected: httpz.Problem{
+				Type:   "about:blank",
+				Title:  "Internal Server Error",
+				Status: http.StatusInternalServerError,
+			},
+		},
+		{
+			name:    "verbose error",
+			options: test.Options{Err: authzErr},
+			verbose: true,
+			expected: httpz.Problem{
+				Type:   "about:blank",
+				Title:  "Internal Server Error",
+				Status: http.StatusInternalServerError,
+				Detail: authzErr.Error(),
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.options.PolicyPath = DefaultPolicyPath
+			base := test.NewTest(t, tc.name, &tc.options)
+
+			mw := httpz.New(base.Client, test.Policy("")).WithProblemJSON(tc.verbose)
+			mw.Identity.Subject()
+
+			req := httptest.NewRequest(http.MethodGet, "https://example.com/foo", http.NoBody)
+			req.Header.Add("Authorization", test.DefaultUsername)
+
+			w := httptest.NewRecorder()
+
+			mw.Handler(http.HandlerFunc(noopHandler)).ServeHTTP(w, req)
+
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.expected.Status, resp.StatusCode)
+			assert.Equal(t, httpz.ProblemContentType, resp.Header.Get("Content-Type"))
+
+			var problem httpz.Problem
+			assert.NoError(t, json.NewDecoder(resp.Body).Decode(&problem))
+			assert.Equal(t, tc.expected, problem)
+		})
+	}
+}
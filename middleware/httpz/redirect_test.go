@@ -0,0 +1,68 @@
+package httpz_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware/httpz"
+	"github.com/aserto-dev/go-aserto/middleware/internal/test"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	assert "github.com/stretchr/testify/require"
+)
+
+func TestRedirectOnAnonymousDeny(t *testing.T) {
+	for name, tc := range map[string]struct {
+		identity     *api.IdentityContext
+		header       string
+		anonymousAs  string
+		expectedCode int
+	}{
+		"anonymous": {
+			identity:     &api.IdentityContext{Type: api.IdentityType_IDENTITY_TYPE_NONE},
+			expectedCode: http.StatusFound,
+		},
+		"anonymous as subject": {
+			identity:     &api.IdentityContext{Type: api.IdentityType_IDENTITY_TYPE_SUB, Identity: "guest"},
+			anonymousAs:  "guest",
+			expectedCode: http.StatusFound,
+		},
+		"authenticated": {
+			identity:     &api.IdentityContext{Type: api.IdentityType_IDENTITY_TYPE_SUB, Identity: test.DefaultUsername},
+			header:       test.DefaultUsername,
+			expectedCode: http.StatusForbidden,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			base := test.NewTest(t, name, &test.Options{
+				ExpectedRequest: test.Request(
+					test.PolicyPath(DefaultPolicyPath),
+					test.IdentityType(tc.identity.GetType()),
+					test.Identity(tc.identity.GetIdentity()),
+				),
+				Reject: true,
+			})
+
+			mw := httpz.New(base.Client, test.Policy(DefaultPolicyPath)).
+				WithRedirectOnAnonymousDeny("https://example.com/login?app=foo")
+			mw.Identity.Subject().AnonymousAs(tc.anonymousAs)
+
+			req := httptest.NewRequest(http.MethodGet, "https://example.com/foo?tab=1", http.NoBody)
+			if tc.header != "" {
+				req.Header.Add("Authorization", tc.header)
+			}
+
+			w := httptest.NewRecorder()
+			mw.Handler(http.HandlerFunc(noopHandler)).ServeHTTP(w, req)
+
+			resp := w.Result()
+			defer resp.Body.Close()
+
+			assert.Equal(t, tc.expectedCode, resp.StatusCode)
+
+			if tc.expectedCode == http.StatusFound {
+				assert.Equal(t, "https://example.com/login?app=foo&return_to=%2Ffoo%3Ftab%3D1", resp.Header.Get("Location"))
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package httpz_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware/httpz"
+	"github.com/aserto-dev/go-aserto/middleware/internal/test"
+	assert "github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracer(t *testing.T) {
+	for name, reject := range map[string]bool{"allowed": false, "denied": true} {
+		t.Run(name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+			base := test.NewTest(t, name, &test.Options{PolicyPath: DefaultPolicyPath, Reject: reject})
+			mw := httpz.New(base.Client, test.Policy("")).WithTracer(tracer)
+			mw.Identity.Subject().ID(test.DefaultUsername)
+
+			handler := mw.Handler(http.HandlerFunc(noopHandler))
+			req := httptest.NewRequest(http.MethodGet, "https://example.com/foo", http.NoBody)
+			req.Header.Add("Authorization", test.DefaultUsername)
+
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := recorder.Ended()
+			assert.Len(t, spans, 1)
+			assert.Equal(t, "aserto.authorize", spans[0].Name())
+			assert.Contains(t, spans[0].Attributes(), attribute.String("aserto.policy.path", DefaultPolicyPath))
+			assert.Contains(t, spans[0].Attributes(), attribute.StringSlice("aserto.decision", []string{test.DefaultDecision}))
+			assert.Contains(t, spans[0].Attributes(), attribute.Bool("aserto.allowed", !reject))
+
+			if reject {
+				assert.Equal(t, codes.Error, spans[0].Status().Code)
+			} else {
+				assert.Equal(t, codes.Unset, spans[0].Status().Code)
+			}
+		})
+	}
+}
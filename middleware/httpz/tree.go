@@ -0,0 +1,88 @@
+package httpz
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	cerr "github.com/aserto-dev/errors"
+	"github.com/aserto-dev/go-aserto/middleware"
+	"github.com/aserto-dev/go-aserto/middleware/internal"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// DecisionTree evaluates decisions for all policy modules under policyPath and returns a map from each module's
+// path to the outcome of its decisions.
+//
+// If no decisions are specified, the middleware policy's decision is used.
+// The decision tree is typically used by user interfaces to determine which actions are available to a user.
+// Use WithDecisionTreeFormat to normalize paths or filter out denied decisions.
+func (m *Middleware) DecisionTree(
+	ctx context.Context,
+	identity *api.IdentityContext,
+	policyPath string,
+	resource *structpb.Struct,
+	decisions ...string,
+) (map[string]map[string]bool, error) {
+	if len(decisions) == 0 {
+		decisions = []string{m.policy.Decision}
+	}
+
+	resp, err := m.client.DecisionTree(ctx, &authz.DecisionTreeRequest{
+		PolicyContext:   &api.PolicyContext{Path: policyPath, Decisions: decisions},
+		IdentityContext: identity,
+		Options:         &authz.DecisionTreeOptions{PathSeparator: authz.PathSeparator_PATH_SEPARATOR_DOT},
+		ResourceContext: resource,
+		PolicyInstance:  internal.DefaultPolicyInstance(m.policy),
+	})
+	if err != nil {
+		return nil, cerr.WithContext(err, ctx)
+	}
+
+	return internal.DecisionTree(resp, m.treeFormat), nil
+}
+
+// DecisionTreeHandler returns a handler that responds with the caller's decision tree encoded as JSON.
+//
+// The caller's identity and resource context are determined by the middleware's identity builder and
+// resource mappers.
+//
+// # Example
+//
+//	http.Handle("/api/permissions", mw.DecisionTreeHandler("myapp", "visible", "enabled"))
+func (m *Middleware) DecisionTreeHandler(policyPath string, decisions ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, err := m.Identity.resolve(r)
+		if err != nil {
+			m.fail(w, err.Error(), err)
+			return
+		}
+
+		resource, err := m.resourceContext(r, identity)
+		if err != nil {
+			m.fail(w, http.StatusText(m.errorStatus), err)
+			return
+		}
+
+		tree, err := m.DecisionTree(r.Context(), identity, policyPath, resource, decisions...)
+		if err != nil {
+			m.fail(w, err.Error(), err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if err := json.NewEncoder(w).Encode(tree); err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	})
+}
+
+// WithDecisionTreeFormat sets the format of decision trees returned by DecisionTree and DecisionTreeHandler.
+// By default, policy paths and decisions are returned as they are received from the authorizer.
+func (m *Middleware) WithDecisionTreeFormat(format middleware.DecisionTreeFormat) *Middleware {
+	m.treeFormat = format
+	return m
+}
@@ -0,0 +1,78 @@
+package httpz_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware/httpz"
+	"github.com/aserto-dev/go-aserto/middleware/internal/test"
+	assert "github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestDecisionTreeHandler(t *testing.T) {
+	path, err := structpb.NewStruct(map[string]interface{}{
+		"myapp.GET.users":    map[string]interface{}{"visible": true, "enabled": true},
+		"myapp.DELETE.users": map[string]interface{}{"visible": true, "enabled": false},
+	})
+	assert.NoError(t, err)
+
+	base := test.NewTest(t, "decision tree", &test.Options{})
+	base.Client.WithDecisionTree(path)
+
+	mw := httpz.New(base.Client, test.Policy(""))
+
+	req := httptest.NewRequest(http.MethodGet, "https://example.com/api/permissions", http.NoBody)
+	w := httptest.NewRecorder()
+
+	mw.DecisionTreeHandler("myapp", "visible", "enabled").ServeHTTP(w, req)
+
+	resp := w.Result()
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var tree map[string]map[string]bool
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&tree))
+	assert.Equal(t, map[string]map[string]bool{
+		"myapp.GET.users":    {"visible": true, "enabled": true},
+		"myapp.DELETE.users": {"visible": true, "enabled": false},
+	}, tree)
+}
+
+func TestDecisionTreeHandlerErrors(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err      error
+		options  func(*httpz.Middleware)
+		expected int
+	}{
+		"error":       {err: status.Error(codes.Internal, "failed"), expected: http.StatusInternalServerError},
+		"unavailable": {err: status.Error(codes.Unavailable, "unavailable"), expected: http.StatusServiceUnavailable},
+		"error status": {
+			err:      status.Error(codes.Internal, "failed"),
+			options:  func(mw *httpz.Middleware) { mw.WithErrorStatus(http.StatusBadGateway) },
+			expected: http.StatusBadGateway,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			base := test.NewTest(t, name, &test.Options{Err: tc.err})
+
+			mw := httpz.New(base.Client, test.Policy(""))
+			if tc.options != nil {
+				tc.options(mw)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "https://example.com/api/permissions", http.NoBody)
+			w := httptest.NewRecorder()
+
+			mw.DecisionTreeHandler("myapp").ServeHTTP(w, req)
+
+			assert.Equal(t, tc.expected, w.Code)
+		})
+	}
+}
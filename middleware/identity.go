@@ -1,5 +1,7 @@
 package middleware
 
+import "github.com/pkg/errors"
+
 /*
 Identity provides methods to set caller identity parameters.
 
@@ -27,3 +29,37 @@ type Identity interface {
 	// ID sets the identity value - a string that represents a user ID or a JWT token.
 	ID(identity string) Identity
 }
+
+// ErrIdentityConflict is returned when identity sources resolve to different identities and IdentityStrict
+// precedence is used.
+var ErrIdentityConflict = errors.New("conflicting identities")
+
+// IdentityPrecedence determines how the caller's identity is resolved when multiple identity sources (e.g. a header
+// and a context value) are configured.
+type IdentityPrecedence int
+
+const (
+	// IdentityLastSource uses only the most recently configured identity source. This is the default.
+	IdentityLastSource IdentityPrecedence = iota
+
+	// IdentityFirstSource tries identity sources in the order they were configured and uses the first one that
+	// resolves an identity.
+	IdentityFirstSource
+
+	// IdentityStrict resolves all identity sources and fails authorization with ErrIdentityConflict if they resolve
+	// different identities. Sources that don't resolve an identity are ignored.
+	IdentityStrict
+)
+
+func (p IdentityPrecedence) String() string {
+	switch p {
+	case IdentityLastSource:
+		return "last_source"
+	case IdentityFirstSource:
+		return "first_source"
+	case IdentityStrict:
+		return "strict"
+	default:
+		return "unknown"
+	}
+}
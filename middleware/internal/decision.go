@@ -0,0 +1,34 @@
+package internal
+
+import (
+	"time"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// NewDecisionEvent returns a description of an authorization decision to be passed to a middleware.DecisionLogger.
+func NewDecisionEvent(
+	identity *api.IdentityContext,
+	policy *api.PolicyContext,
+	resource *structpb.Struct,
+	allowed bool,
+	duration time.Duration,
+	err error,
+) middleware.DecisionEvent {
+	event := middleware.DecisionEvent{
+		Identity:   identity,
+		PolicyPath: policy.GetPath(),
+		Resource:   resource.AsMap(),
+		Allowed:    allowed,
+		Duration:   duration,
+		Err:        err,
+	}
+
+	if decisions := policy.GetDecisions(); len(decisions) > 0 {
+		event.Decision = decisions[0]
+	}
+
+	return event
+}
@@ -3,6 +3,7 @@ package internal
 import (
 	"github.com/aserto-dev/go-aserto/middleware"
 	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/pkg/errors"
 )
 
 type Identity struct {
@@ -67,3 +68,92 @@ func (id *Identity) Context() *api.IdentityContext {
 
 	return &id.context
 }
+
+// ContextOrAnonymous returns the identity context. If no identity was resolved and anonymous isn't empty,
+// the returned context is a subject identity with the value of anonymous.
+func (id *Identity) ContextOrAnonymous(anonymous string) *api.IdentityContext {
+	ctx := id.Context()
+	if ctx.GetType() == api.IdentityType_IDENTITY_TYPE_NONE && anonymous != "" {
+		ctx.Type = api.IdentityType_IDENTITY_TYPE_SUB
+		ctx.Identity = anonymous
+	}
+
+	return ctx
+}
+
+// AddManualIdentity adds the value of a manual identity to the resource under the given field.
+// Other identity types are ignored.
+func AddManualIdentity(identity *api.IdentityContext, field string, resource map[string]interface{}) {
+	if identity.GetType() == api.IdentityType_IDENTITY_TYPE_MANUAL {
+		resource[field] = identity.GetIdentity()
+	}
+}
+
+// IdentitySource is a named identity mapper configured on an identity builder.
+type IdentitySource[M any] struct {
+	Name   string
+	Mapper M
+}
+
+// ResolveIdentity resolves the caller's identity from the given sources according to precedence.
+// apply calls a source's mapper on the identity. token, if not nil, is then called with the identity's type and
+// value, and its result replaces the value. It's used to resolve JWTs and reject expired tokens for all sources.
+//
+// If there are no sources, the identity has the given type and default value. With middleware.IdentityStrict
+// precedence, resolving different identities from two sources returns an anonymous identity and an error that wraps
+// middleware.ErrIdentityConflict.
+func ResolveIdentity[M any](
+	identityType api.IdentityType,
+	defaultIdentity string,
+	precedence middleware.IdentityPrecedence,
+	sources []IdentitySource[M],
+	token func(api.IdentityType, string) string,
+	apply func(M, middleware.Identity),
+) (*Identity, error) {
+	if len(sources) == 0 {
+		return NewIdentity(identityType, defaultIdentity), nil
+	}
+
+	resolve := func(source IdentitySource[M]) *Identity {
+		identity := NewIdentity(identityType, defaultIdentity)
+		apply(source.Mapper, identity)
+
+		if token != nil && identity.context.Identity != "" {
+			identity.context.Identity = token(identity.context.Type, identity.context.Identity)
+		}
+
+		return identity
+	}
+
+	if precedence == middleware.IdentityLastSource {
+		return resolve(sources[len(sources)-1]), nil
+	}
+
+	var (
+		resolved *Identity
+		from     string
+		last     *Identity
+	)
+
+	for _, source := range sources {
+		last = resolve(source)
+
+		switch {
+		case last.context.Identity == "":
+			continue
+		case resolved == nil && precedence == middleware.IdentityFirstSource:
+			return last, nil
+		case resolved == nil:
+			resolved, from = last, source.Name
+		case last.context.Identity != resolved.context.Identity:
+			return NewIdentity(api.IdentityType_IDENTITY_TYPE_NONE, ""),
+				errors.Wrapf(middleware.ErrIdentityConflict, "sources %q and %q", from, source.Name)
+		}
+	}
+
+	if resolved != nil {
+		return resolved, nil
+	}
+
+	return last, nil
+}
@@ -0,0 +1,73 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	"github.com/aserto-dev/go-aserto/middleware/internal"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveIdentity(t *testing.T) {
+	// sources returns sources named "a", "b", "c", ... that resolve the given identities.
+	sources := func(ids ...string) []internal.IdentitySource[string] {
+		result := make([]internal.IdentitySource[string], len(ids))
+		for i, id := range ids {
+			result[i] = internal.IdentitySource[string]{Name: string(rune('a' + i)), Mapper: id}
+		}
+
+		return result
+	}
+
+	apply := func(id string, identity middleware.Identity) {
+		if id == "" {
+			identity.None()
+			return
+		}
+
+		identity.ID(id)
+	}
+
+	// token rejects the identity "expired".
+	token := func(_ api.IdentityType, id string) string {
+		if id == "expired" {
+			return ""
+		}
+
+		return id
+	}
+
+	for name, tc := range map[string]struct {
+		precedence middleware.IdentityPrecedence
+		sources    []internal.IdentitySource[string]
+		expected   string
+		conflict   bool
+	}{
+		"no sources":         {expected: "default"},
+		"last source":        {sources: sources("alice", ""), expected: ""},
+		"first source":       {precedence: middleware.IdentityFirstSource, sources: sources("", "bob", "carol"), expected: "bob"},
+		"first source token": {precedence: middleware.IdentityFirstSource, sources: sources("expired", "bob"), expected: "bob"},
+		"last source token":  {sources: sources("alice", "expired"), expected: ""},
+		"first source none":  {precedence: middleware.IdentityFirstSource, sources: sources("", ""), expected: ""},
+		"strict agreement":   {precedence: middleware.IdentityStrict, sources: sources("alice", "", "alice"), expected: "alice"},
+		"strict conflict":    {precedence: middleware.IdentityStrict, sources: sources("alice", "", "bob"), conflict: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			identity, err := internal.ResolveIdentity(
+				api.IdentityType_IDENTITY_TYPE_SUB, "default", tc.precedence, tc.sources, token, apply,
+			)
+
+			if tc.conflict {
+				assert.ErrorIs(t, err, middleware.ErrIdentityConflict)
+				assert.ErrorContains(t, err, `sources "a" and "c"`)
+				assert.Equal(t, api.IdentityType_IDENTITY_TYPE_NONE, identity.Context().GetType())
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, identity.Context().GetIdentity())
+		})
+	}
+}
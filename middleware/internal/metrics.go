@@ -0,0 +1,76 @@
+package internal
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/aserto-dev/go-authorizer/pkg/aerr"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics records the outcome and latency of authorization calls in Prometheus collectors.
+type Metrics struct {
+	decisions *prometheus.CounterVec
+	errors    *prometheus.CounterVec
+	duration  *prometheus.HistogramVec
+}
+
+// NewMetrics registers the authorization collectors with the specified registerer.
+// Collectors that are already registered (e.g. by another middleware) are reused. It panics if the collectors can't
+// be registered for any other reason.
+func NewMetrics(registerer prometheus.Registerer) *Metrics {
+	return &Metrics{
+		decisions: register(registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "aserto_authz_decisions_total",
+			Help: "Number of authorization decisions.",
+		}, []string{"decision", "allowed"})),
+		errors: register(registerer, prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "aserto_authz_errors_total",
+			Help: "Number of failed authorization calls.",
+		}, []string{"decision"})),
+		duration: register(registerer, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "aserto_authz_duration_seconds",
+			Help:    "Latency of authorization calls.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"decision"})),
+	}
+}
+
+func register[T prometheus.Collector](registerer prometheus.Registerer, collector T) T {
+	if err := registerer.Register(collector); err != nil {
+		var are prometheus.AlreadyRegisteredError
+		if !errors.As(err, &are) {
+			panic(err)
+		}
+
+		if existing, ok := are.ExistingCollector.(T); ok {
+			return existing
+		}
+
+		panic(err)
+	}
+
+	return collector
+}
+
+// Observe records the outcome of an authorization call. Denials are reported either with allowed set to false or
+// with aerr.ErrAuthorizationFailed. It is a no-op if m is nil.
+func (m *Metrics) Observe(policyContext *api.PolicyContext, elapsed time.Duration, allowed bool, err error) {
+	if m == nil {
+		return
+	}
+
+	decision := strings.Join(policyContext.GetDecisions(), ",")
+
+	m.duration.WithLabelValues(decision).Observe(elapsed.Seconds())
+
+	if err != nil && !errors.Is(err, aerr.ErrAuthorizationFailed) {
+		m.errors.WithLabelValues(decision).Inc()
+		return
+	}
+
+	m.decisions.WithLabelValues(decision, strconv.FormatBool(allowed && err == nil)).Inc()
+}
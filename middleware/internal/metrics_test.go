@@ -0,0 +1,60 @@
+package internal_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aserto-dev/go-aserto/middleware/internal"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/aserto-dev/go-authorizer/pkg/aerr"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+const expectedMetrics = `
+# HELP aserto_authz_decisions_total Number of authorization decisions.
+# TYPE aserto_authz_decisions_total counter
+aserto_authz_decisions_total{allowed="false",decision="allowed"} 2
+aserto_authz_decisions_total{allowed="true",decision="allowed"} 1
+# HELP aserto_authz_errors_total Number of failed authorization calls.
+# TYPE aserto_authz_errors_total counter
+aserto_authz_errors_total{decision="allowed"} 1
+`
+
+func TestMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	policyContext := &api.PolicyContext{Decisions: []string{"allowed"}}
+
+	internal.NewMetrics(registry).Observe(policyContext, time.Millisecond, true, nil)
+	internal.NewMetrics(registry).Observe(policyContext, time.Millisecond, false, nil)
+	internal.NewMetrics(registry).Observe(policyContext, time.Millisecond, false, aerr.ErrAuthorizationFailed)
+	internal.NewMetrics(registry).Observe(policyContext, time.Millisecond, false, errors.New("boom"))
+
+	assert.NoError(t, testutil.GatherAndCompare(
+		registry,
+		strings.NewReader(expectedMetrics),
+		"aserto_authz_decisions_total", "aserto_authz_errors_total",
+	))
+	assert.Equal(t, 1, testutil.CollectAndCount(registry, "aserto_authz_duration_seconds"))
+}
+
+func TestNilMetrics(t *testing.T) {
+	var metrics *internal.Metrics
+
+	assert.NotPanics(t, func() {
+		metrics.Observe(&api.PolicyContext{}, time.Millisecond, true, nil)
+	})
+}
+
+func TestMetricsConflict(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "aserto_authz_decisions_total",
+		Help: "Number of authorization decisions.",
+	}))
+
+	assert.Panics(t, func() { internal.NewMetrics(registry) })
+}
@@ -8,12 +8,15 @@ import (
 	"github.com/stretchr/testify/assert"
 	"google.golang.org/grpc"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
 )
 
 type Authorizer struct {
 	t        *testing.T
 	expected *authz.IsRequest
 	response authz.IsResponse
+	err      error
+	tree     authz.DecisionTreeResponse
 }
 
 func New(t *testing.T, expectedRequest *authz.IsRequest, decision *authz.Decision) *Authorizer {
@@ -28,12 +31,34 @@ func New(t *testing.T, expectedRequest *authz.IsRequest, decision *authz.Decisio
 
 var _ authz.AuthorizerClient = (*Authorizer)(nil)
 
+// WithError causes calls to Is to fail with the specified error.
+func (c *Authorizer) WithError(err error) *Authorizer {
+	c.err = err
+	return c
+}
+
+// WithDecisions sets the decisions returned from calls to Is.
+func (c *Authorizer) WithDecisions(decisions ...*authz.Decision) *Authorizer {
+	c.response.Decisions = decisions
+	return c
+}
+
+// WithDecisionTree sets the response returned from calls to DecisionTree.
+func (c *Authorizer) WithDecisionTree(path *structpb.Struct) *Authorizer {
+	c.tree.Path = path
+	return c
+}
+
 func (c *Authorizer) DecisionTree(
 	_ context.Context,
 	_ *authz.DecisionTreeRequest,
 	_ ...grpc.CallOption,
 ) (*authz.DecisionTreeResponse, error) {
-	return &authz.DecisionTreeResponse{}, nil
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	return &c.tree, nil
 }
 
 func (c *Authorizer) Is(
@@ -46,6 +71,10 @@ func (c *Authorizer) Is(
 	assert.True(c.t, proto.Equal(c.expected, in))
 	assert.Equal(c.t, c.expected, in)
 
+	if c.err != nil {
+		return nil, c.err
+	}
+
 	return &c.response, nil
 }
 
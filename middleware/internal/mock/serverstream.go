@@ -3,14 +3,19 @@ package mock
 import (
 	"context"
 	"errors"
+	"io"
 
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
 )
 
 var errNotImplemented = errors.New("not implemented")
 
 // Mock grpc.ServerStream.
 type ServerStream struct {
+	// Messages are received in order by RecvMsg, which returns io.EOF once they are exhausted.
+	// If Messages is nil, RecvMsg fails.
+	Messages []proto.Message
 }
 
 func (s *ServerStream) SetHeader(metadata.MD) error {
@@ -32,6 +37,22 @@ func (s *ServerStream) SendMsg(_ interface{}) error {
 	return errNotImplemented
 }
 
-func (s *ServerStream) RecvMsg(_ interface{}) error {
-	return errNotImplemented
+func (s *ServerStream) RecvMsg(m interface{}) error {
+	if s.Messages == nil {
+		return errNotImplemented
+	}
+
+	if len(s.Messages) == 0 {
+		return io.EOF
+	}
+
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return errNotImplemented
+	}
+
+	proto.Merge(msg, s.Messages[0])
+	s.Messages = s.Messages[1:]
+
+	return nil
 }
@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"context"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorOutcome returns the outcome mapped to the gRPC code of err. Unmapped codes result in OutcomeError.
+func ErrorOutcome(mapping map[codes.Code]middleware.Outcome, err error) middleware.Outcome {
+	if outcome, ok := mapping[status.Code(err)]; ok {
+		return outcome
+	}
+
+	return middleware.OutcomeError
+}
+
+// ResolveError determines the outcome of a failed authorization call for the given policy path.
+// Errors whose code is mapped to an allow or deny outcome, and unmapped errors if modes fail open, are resolved to a
+// decision and logged as warnings. Otherwise, err is returned.
+func ResolveError(
+	ctx context.Context,
+	outcomes map[codes.Code]middleware.Outcome,
+	modes *FailureModes,
+	policyPath string,
+	err error,
+) (bool, error) {
+	logger := zerolog.Ctx(ctx)
+
+	switch outcome := ErrorOutcome(outcomes, err); outcome {
+	case middleware.OutcomeAllow, middleware.OutcomeDeny:
+		logger.Warn().Err(err).Str("policy_path", policyPath).Stringer("outcome", outcome).Msg("authorization call failed")
+		return outcome == middleware.OutcomeAllow, nil
+	case middleware.OutcomeError:
+		if modes.FailsOpen(policyPath) {
+			logger.Warn().Err(err).Str("policy_path", policyPath).Msg("authorization call failed, failing open")
+			return true, nil
+		}
+	}
+
+	return false, err
+}
+
+// FailureModes holds the failure mode of a middleware and per-policy overrides.
+type FailureModes struct {
+	mode  middleware.FailureMode
+	paths map[string]middleware.FailureMode
+}
+
+// Set sets the failure mode of the given policy paths or, if there are none, the default failure mode.
+func (f *FailureModes) Set(mode middleware.FailureMode, policyPaths ...string) {
+	if len(policyPaths) == 0 {
+		f.mode = mode
+		return
+	}
+
+	if f.paths == nil {
+		f.paths = map[string]middleware.FailureMode{}
+	}
+
+	for _, path := range policyPaths {
+		f.paths[path] = mode
+	}
+}
+
+// FailsOpen returns true if failed authorization calls for the given policy path are allowed.
+// A nil FailureModes fails closed.
+func (f *FailureModes) FailsOpen(policyPath string) bool {
+	if f == nil {
+		return false
+	}
+
+	if mode, ok := f.paths[policyPath]; ok {
+		return mode == middleware.FailOpen
+	}
+
+	return f.mode == middleware.FailOpen
+}
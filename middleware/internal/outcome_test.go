@@ -0,0 +1,49 @@
+package internal_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	"github.com/aserto-dev/go-aserto/middleware/internal"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestResolveError(t *testing.T) {
+	outcomes := map[codes.Code]middleware.Outcome{
+		codes.NotFound:         middleware.OutcomeDeny,
+		codes.PermissionDenied: middleware.OutcomeAllow,
+	}
+
+	failOpen := &internal.FailureModes{}
+	failOpen.Set(middleware.FailOpen, "GET.open")
+
+	for name, tc := range map[string]struct {
+		code     codes.Code
+		modes    *internal.FailureModes
+		path     string
+		allowed  bool
+		hasError bool
+	}{
+		"mapped to deny":                {code: codes.NotFound, modes: failOpen, path: "GET.open"},
+		"mapped to allow":               {code: codes.PermissionDenied, allowed: true},
+		"unmapped fails closed":         {code: codes.Unavailable, modes: failOpen, path: "GET.closed", hasError: true},
+		"unmapped fails open":           {code: codes.Unavailable, modes: failOpen, path: "GET.open", allowed: true},
+		"nil failure modes fail closed": {code: codes.Unavailable, hasError: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			authzErr := status.Error(tc.code, "failed")
+
+			allowed, err := internal.ResolveError(context.Background(), outcomes, tc.modes, tc.path, authzErr)
+			assert.Equal(t, tc.allowed, allowed)
+
+			if tc.hasError {
+				assert.ErrorIs(t, err, authzErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package internal
+
+import "strings"
+
+// PathMatcher matches URL paths against a set of patterns.
+// Patterns that end with '*' match all paths that start with the rest of the pattern. Other patterns match exactly.
+type PathMatcher struct {
+	exact    Lookup[string]
+	prefixes []string
+}
+
+func NewPathMatcher(patterns ...string) *PathMatcher {
+	matcher := &PathMatcher{exact: Lookup[string]{}}
+
+	for _, pattern := range patterns {
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+			matcher.prefixes = append(matcher.prefixes, prefix)
+		} else {
+			matcher.exact[pattern] = struct{}{}
+		}
+	}
+
+	return matcher
+}
+
+// Matches returns true if path matches any of the patterns. A nil matcher doesn't match any path.
+func (m *PathMatcher) Matches(path string) bool {
+	if m == nil {
+		return false
+	}
+
+	if m.exact.Contains(path) {
+		return true
+	}
+
+	for _, prefix := range m.prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NewMethodLookup returns a lookup of HTTP methods. Methods are normalized to upper case.
+func NewMethodLookup(methods ...string) Lookup[string] {
+	lookup := Lookup[string]{}
+	for _, method := range methods {
+		lookup[strings.ToUpper(method)] = struct{}{}
+	}
+
+	return lookup
+}
@@ -0,0 +1,31 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathMatcher(t *testing.T) {
+	matcher := internal.NewPathMatcher("/healthz", "/metrics", "/static/*")
+
+	for path, expected := range map[string]bool{
+		"/healthz":        true,
+		"/healthz/live":   false,
+		"/metrics":        true,
+		"/static/app.js":  true,
+		"/static/":        true,
+		"/static":         false,
+		"/api/users":      false,
+		"/api/healthz":    false,
+		"/metrics/export": false,
+	} {
+		assert.Equal(t, expected, matcher.Matches(path), path)
+	}
+}
+
+func TestNilPathMatcher(t *testing.T) {
+	var matcher *internal.PathMatcher
+	assert.False(t, matcher.Matches("/healthz"))
+}
@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/netip"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	"github.com/pkg/errors"
+)
+
+// TrustedPeers identifies callers that are trusted to bypass authorization, either by the network address they
+// connect from or by the SPIFFE ID in their client certificate.
+type TrustedPeers struct {
+	prefixes []netip.Prefix
+	ids      Lookup[string]
+	err      error
+}
+
+// WithCIDRs adds network ranges to the trusted peers. Entries are either CIDR blocks (e.g. "10.0.0.0/8") or single
+// IP addresses. Invalid entries are skipped and the first one is reported by Err.
+func (t *TrustedPeers) WithCIDRs(cidrs ...string) *TrustedPeers {
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(cidr)
+			if addrErr != nil {
+				if t.err == nil {
+					t.err = errors.Wrapf(middleware.ErrInvalidTrustedPeer, "%q: %v", cidr, err)
+				}
+
+				continue
+			}
+
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+
+		t.prefixes = append(t.prefixes, prefix.Masked())
+	}
+
+	return t
+}
+
+// WithSPIFFEIDs adds SPIFFE IDs (e.g. "spiffe://example.org/ns/default/sa/api") to the trusted peers.
+func (t *TrustedPeers) WithSPIFFEIDs(ids ...string) *TrustedPeers {
+	if t.ids == nil {
+		t.ids = Lookup[string]{}
+	}
+
+	for _, id := range ids {
+		t.ids[id] = struct{}{}
+	}
+
+	return t
+}
+
+// Err returns an error if an entry passed to WithCIDRs is invalid.
+func (t *TrustedPeers) Err() error {
+	return t.err
+}
+
+// Trusts returns true if a peer with the given network address and verified certificate chain is trusted.
+// The address may include a port. A nil TrustedPeers doesn't trust any peer.
+func (t *TrustedPeers) Trusts(addr string, certs []*x509.Certificate) bool {
+	if t == nil {
+		return false
+	}
+
+	return t.trustsAddr(addr) || t.trustsCerts(certs)
+}
+
+// TrustsRequest returns true if an HTTP request comes from a trusted peer. The peer's address is r.RemoteAddr.
+// Forwarding headers such as X-Forwarded-For aren't considered.
+func (t *TrustedPeers) TrustsRequest(r *http.Request) bool {
+	return t.Trusts(r.RemoteAddr, VerifiedChain(r.TLS))
+}
+
+// VerifiedChain returns the first verified certificate chain of a TLS connection, starting with the leaf
+// certificate. It returns nil if the connection isn't using TLS or the peer's certificate wasn't verified.
+func VerifiedChain(state *tls.ConnectionState) []*x509.Certificate {
+	if state == nil || len(state.VerifiedChains) == 0 {
+		return nil
+	}
+
+	return state.VerifiedChains[0]
+}
+
+func (t *TrustedPeers) trustsAddr(addr string) bool {
+	if len(t.prefixes) == 0 {
+		return false
+	}
+
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return false
+	}
+
+	ip = ip.Unmap()
+
+	for _, prefix := range t.prefixes {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// trustsCerts checks the SPIFFE ID in the URI SAN of the leaf certificate.
+func (t *TrustedPeers) trustsCerts(certs []*x509.Certificate) bool {
+	if len(t.ids) == 0 || len(certs) == 0 {
+		return false
+	}
+
+	for _, uri := range certs[0].URIs {
+		if uri.Scheme == "spiffe" && t.ids.Contains(uri.String()) {
+			return true
+		}
+	}
+
+	return false
+}
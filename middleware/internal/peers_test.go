@@ -0,0 +1,59 @@
+package internal_test
+
+import (
+	"crypto/x509"
+	"net/url"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	"github.com/aserto-dev/go-aserto/middleware/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrustedPeers(t *testing.T) {
+	peers := (&internal.TrustedPeers{}).
+		WithCIDRs("10.0.0.0/8", "192.0.2.7", "2001:db8::/32").
+		WithSPIFFEIDs("spiffe://example.org/ns/default/sa/api")
+
+	spiffe := func(id string) []*x509.Certificate {
+		uri, err := url.Parse(id)
+		assert.NoError(t, err)
+
+		return []*x509.Certificate{{URIs: []*url.URL{uri}}}
+	}
+
+	for name, tc := range map[string]struct {
+		addr     string
+		certs    []*x509.Certificate
+		expected bool
+	}{
+		"cidr":              {addr: "10.1.2.3:443", expected: true},
+		"single address":    {addr: "192.0.2.7:443", expected: true},
+		"other address":     {addr: "192.0.2.8:443", expected: false},
+		"ipv6":              {addr: "[2001:db8::1]:443", expected: true},
+		"ipv4-mapped":       {addr: "[::ffff:10.0.0.1]:443", expected: true},
+		"address sans port": {addr: "10.0.0.1", expected: true},
+		"invalid address":   {addr: "pipe", expected: false},
+		"spiffe id":         {addr: "203.0.113.1:443", certs: spiffe("spiffe://example.org/ns/default/sa/api"), expected: true},
+		"other spiffe id":   {addr: "203.0.113.1:443", certs: spiffe("spiffe://example.org/ns/default/sa/web"), expected: false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, peers.Trusts(tc.addr, tc.certs))
+		})
+	}
+}
+
+func TestNoTrustedPeers(t *testing.T) {
+	var peers *internal.TrustedPeers
+
+	assert.False(t, peers.Trusts("10.0.0.1:443", nil))
+	assert.False(t, (&internal.TrustedPeers{}).Trusts("10.0.0.1:443", nil))
+}
+
+func TestInvalidTrustedPeer(t *testing.T) {
+	peers := (&internal.TrustedPeers{}).WithCIDRs("10.0.0.0/8", "10.0.0.0/33", "not-an-ip")
+
+	assert.ErrorIs(t, peers.Err(), middleware.ErrInvalidTrustedPeer)
+	assert.Contains(t, peers.Err().Error(), "10.0.0.0/33")
+	assert.True(t, peers.Trusts("10.0.0.1:443", nil), "valid entries are still trusted")
+}
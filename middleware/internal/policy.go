@@ -6,15 +6,9 @@ import (
 )
 
 func DefaultPolicyContext(policy *middleware.Policy) *api.PolicyContext {
-	return &api.PolicyContext{
-		Path:      policy.Path,
-		Decisions: []string{policy.Decision},
-	}
+	return policy.ToContext()
 }
 
 func DefaultPolicyInstance(policy *middleware.Policy) *api.PolicyInstance {
-	return &api.PolicyInstance{
-		Name:          policy.Name,
-		InstanceLabel: policy.Name,
-	}
+	return policy.ToInstance()
 }
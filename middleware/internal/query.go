@@ -0,0 +1,41 @@
+package internal
+
+import "net/url"
+
+// AllQueryParams selects every query parameter in QueryResource.
+const AllQueryParams = "*"
+
+// QueryResource adds the specified query parameters to a resource.
+// Parameters with a single value are added as strings, repeated parameters are added as lists of strings.
+// If params includes AllQueryParams, all query parameters are added.
+func QueryResource(query url.Values, params []string, resource map[string]interface{}) {
+	for _, param := range params {
+		if param == AllQueryParams {
+			params = make([]string, 0, len(query))
+			for name := range query {
+				params = append(params, name)
+			}
+
+			break
+		}
+	}
+
+	for _, param := range params {
+		values, ok := query[param]
+		if !ok || len(values) == 0 {
+			continue
+		}
+
+		if len(values) == 1 {
+			resource[param] = values[0]
+			continue
+		}
+
+		list := make([]interface{}, len(values))
+		for i, v := range values {
+			list[i] = v
+		}
+
+		resource[param] = list
+	}
+}
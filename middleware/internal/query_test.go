@@ -0,0 +1,30 @@
+package internal_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryResource(t *testing.T) {
+	query := url.Values{"tenant": {"acme"}, "region": {"us", "eu"}, "page": {"2"}}
+
+	resource := map[string]interface{}{}
+	internal.QueryResource(query, []string{"tenant", "region", "missing"}, resource)
+
+	assert.Equal(t, map[string]interface{}{
+		"tenant": "acme",
+		"region": []interface{}{"us", "eu"},
+	}, resource)
+}
+
+func TestQueryResourceAll(t *testing.T) {
+	query := url.Values{"tenant": {"acme"}, "page": {"2"}}
+
+	resource := map[string]interface{}{}
+	internal.QueryResource(query, []string{internal.AllQueryParams}, resource)
+
+	assert.Equal(t, map[string]interface{}{"tenant": "acme", "page": "2"}, resource)
+}
@@ -0,0 +1,109 @@
+package internal
+
+import (
+	"context"
+	"strings"
+
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/rs/zerolog"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Redacted replaces the values of redacted fields.
+const Redacted = "***"
+
+// RequestLog writes log messages about an authorization request. The request is added to messages under a key, with
+// the named fields redacted. It is only copied and encoded when a message that includes it is written.
+type RequestLog struct {
+	logger     zerolog.Logger
+	key        string
+	req        *authz.IsRequest
+	redactions []string
+}
+
+// NewRequestLog returns a RequestLog that writes to the logger from ctx, set to level if it isn't nil.
+func NewRequestLog(
+	ctx context.Context,
+	level *zerolog.Level,
+	key string,
+	req *authz.IsRequest,
+	redactions []string,
+) *RequestLog {
+	logger := *zerolog.Ctx(ctx)
+	if level != nil {
+		logger = logger.Level(*level)
+	}
+
+	return &RequestLog{logger: logger, key: key, req: req, redactions: redactions}
+}
+
+// Debug starts a debug message that includes the request.
+func (l *RequestLog) Debug() *zerolog.Event {
+	return l.withRequest(l.logger.Debug())
+}
+
+// Info starts an info message that includes the request.
+func (l *RequestLog) Info() *zerolog.Event {
+	return l.withRequest(l.logger.Info())
+}
+
+// WithContext returns a copy of ctx that carries the logger, without the request.
+func (l *RequestLog) WithContext(ctx context.Context) context.Context {
+	return l.logger.WithContext(ctx)
+}
+
+// WithRequestContext returns a copy of ctx that carries the logger with the request added. Errors wrapped with the
+// returned context (e.g. using cerr.WithContext) include the request when they are logged.
+func (l *RequestLog) WithRequestContext(ctx context.Context) context.Context {
+	return l.logger.With().Interface(l.key, RedactRequest(l.req, l.redactions)).Logger().WithContext(ctx)
+}
+
+func (l *RequestLog) withRequest(event *zerolog.Event) *zerolog.Event {
+	if !event.Enabled() {
+		return event
+	}
+
+	return event.Interface(l.key, RedactRequest(l.req, l.redactions))
+}
+
+// RedactRequest returns a copy of an authorization request in which the values of the named fields are replaced with
+// Redacted. The field "identity" is the caller's identity. Other fields are in the resource context and nested fields
+// use dot notation (e.g. "user.email"). Fields that aren't present are ignored.
+//
+// If there are no fields to redact, the request is returned as is.
+func RedactRequest(req *authz.IsRequest, fields []string) *authz.IsRequest {
+	if len(fields) == 0 {
+		return req
+	}
+
+	redacted, _ := proto.Clone(req).(*authz.IsRequest)
+
+	for _, field := range fields {
+		if field == "identity" {
+			if redacted.GetIdentityContext().GetIdentity() != "" {
+				redacted.IdentityContext.Identity = Redacted
+			}
+
+			continue
+		}
+
+		redactField(redacted.GetResourceContext(), strings.Split(field, "."))
+	}
+
+	return redacted
+}
+
+func redactField(s *structpb.Struct, path []string) {
+	value, ok := s.GetFields()[path[0]]
+	if !ok {
+		return
+	}
+
+	if len(path) == 1 {
+		s.Fields[path[0]] = structpb.NewStringValue(Redacted)
+		return
+	}
+
+	redactField(value.GetStructValue(), path[1:])
+}
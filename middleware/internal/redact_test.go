@@ -0,0 +1,86 @@
+package internal_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware/internal"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestRedactRequest(t *testing.T) {
+	resource, err := structpb.NewStruct(map[string]interface{}{
+		"email": "alice@example.com",
+		"user":  map[string]interface{}{"phone": "555-0100", "name": "alice"},
+		"id":    "123",
+	})
+	assert.NoError(t, err)
+
+	req := &authz.IsRequest{
+		IdentityContext: &api.IdentityContext{Type: api.IdentityType_IDENTITY_TYPE_SUB, Identity: "alice"},
+		ResourceContext: resource,
+	}
+
+	redacted := internal.RedactRequest(req, []string{"identity", "email", "user.phone", "missing", "id.nested"})
+
+	assert.Equal(t, internal.Redacted, redacted.GetIdentityContext().GetIdentity())
+	assert.Equal(t, map[string]interface{}{
+		"email": internal.Redacted,
+		"user":  map[string]interface{}{"phone": internal.Redacted, "name": "alice"},
+		"id":    "123",
+	}, redacted.GetResourceContext().AsMap())
+
+	// The original request is unchanged.
+	assert.Equal(t, "alice", req.GetIdentityContext().GetIdentity())
+	assert.Equal(t, "alice@example.com", req.GetResourceContext().AsMap()["email"])
+
+	assert.Same(t, req, internal.RedactRequest(req, nil))
+}
+
+func TestRequestLog(t *testing.T) {
+	req := &authz.IsRequest{
+		IdentityContext: &api.IdentityContext{Type: api.IdentityType_IDENTITY_TYPE_SUB, Identity: "alice"},
+	}
+
+	debug := zerolog.DebugLevel
+
+	for name, tc := range map[string]struct {
+		ctxLevel zerolog.Level
+		level    *zerolog.Level
+		debug    bool
+	}{
+		"debug enabled":          {ctxLevel: zerolog.DebugLevel, debug: true},
+		"debug disabled":         {ctxLevel: zerolog.InfoLevel},
+		"debug enabled by level": {ctxLevel: zerolog.InfoLevel, level: &debug, debug: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			ctx := zerolog.New(&buf).Level(tc.ctxLevel).WithContext(context.Background())
+
+			log := internal.NewRequestLog(ctx, tc.level, "is_request", req, []string{"identity"})
+
+			log.Debug().Msg("authorizing request")
+			assert.Equal(t, tc.debug, bytes.Contains(buf.Bytes(), []byte("is_request")))
+
+			buf.Reset()
+			log.Info().Msg("authorization failed")
+			assert.Contains(t, buf.String(), "is_request")
+
+			buf.Reset()
+			zerolog.Ctx(log.WithContext(ctx)).Error().Msg("failed")
+			assert.NotContains(t, buf.String(), "is_request")
+
+			buf.Reset()
+			zerolog.Ctx(log.WithRequestContext(ctx)).Error().Msg("failed")
+			assert.Contains(t, buf.String(), "is_request")
+
+			assert.NotContains(t, buf.String(), "alice")
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package internal
+
+import (
+	"context"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+)
+
+// RelationCheck checks whether a relation exists.
+type RelationCheck func(ctx context.Context, relation string) (bool, error)
+
+// CheckAny checks the given relations concurrently and returns true as soon as one of the checks succeeds.
+// Checks that are still in flight at that point are canceled. If no check succeeds and some of them fail, their
+// errors are returned.
+func CheckAny(ctx context.Context, relations []string, check RelationCheck) (bool, error) {
+	return middleware.AnyOf(relationEvaluators(relations, check)...).Evaluate(ctx, struct{}{})
+}
+
+// CheckAll checks the given relations concurrently and returns false as soon as one of the checks is denied.
+// Checks that are still in flight at that point are canceled. If no check is denied and some of them fail, their
+// errors are returned.
+func CheckAll(ctx context.Context, relations []string, check RelationCheck) (bool, error) {
+	return middleware.AllOf(relationEvaluators(relations, check)...).Evaluate(ctx, struct{}{})
+}
+
+func relationEvaluators(relations []string, check RelationCheck) []middleware.Evaluator[struct{}] {
+	evaluators := make([]middleware.Evaluator[struct{}], len(relations))
+
+	for i, relation := range relations {
+		evaluators[i] = middleware.EvaluatorFunc[struct{}](func(ctx context.Context, _ struct{}) (bool, error) {
+			return check(ctx, relation)
+		})
+	}
+
+	return evaluators
+}
@@ -0,0 +1,77 @@
+package internal_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+var errCheck = errors.New("check failed")
+
+// relationCheck returns the outcome of each relation. Relations that aren't in the map block until canceled.
+func relationCheck(outcomes map[string]error) internal.RelationCheck {
+	return func(ctx context.Context, relation string) (bool, error) {
+		err, ok := outcomes[relation]
+		if !ok {
+			<-ctx.Done()
+			return false, ctx.Err()
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		return relation != "deny", nil
+	}
+}
+
+func TestCheckAny(t *testing.T) {
+	for name, tc := range map[string]struct {
+		relations []string
+		expected  bool
+		err       error
+	}{
+		"allowed":                 {relations: []string{"deny", "allow"}, expected: true},
+		"denied":                  {relations: []string{"deny", "deny"}},
+		"short-circuits":          {relations: []string{"block", "allow"}, expected: true},
+		"allowed despite errors":  {relations: []string{"fail", "allow"}, expected: true},
+		"errors without allowing": {relations: []string{"fail", "deny"}, err: errCheck},
+	} {
+		t.Run(name, func(t *testing.T) {
+			allowed, err := internal.CheckAny(
+				context.Background(),
+				tc.relations,
+				relationCheck(map[string]error{"allow": nil, "deny": nil, "fail": errCheck}),
+			)
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.expected, allowed)
+		})
+	}
+}
+
+func TestCheckAll(t *testing.T) {
+	for name, tc := range map[string]struct {
+		relations []string
+		expected  bool
+		err       error
+	}{
+		"allowed":                {relations: []string{"allow", "allow"}, expected: true},
+		"denied":                 {relations: []string{"allow", "deny"}},
+		"short-circuits":         {relations: []string{"block", "deny"}},
+		"denied despite errors":  {relations: []string{"fail", "deny"}},
+		"errors without denying": {relations: []string{"fail", "allow"}, err: errCheck},
+	} {
+		t.Run(name, func(t *testing.T) {
+			allowed, err := internal.CheckAll(
+				context.Background(),
+				tc.relations,
+				relationCheck(map[string]error{"allow": nil, "deny": nil, "fail": errCheck}),
+			)
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.expected, allowed)
+		})
+	}
+}
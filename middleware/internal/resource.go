@@ -0,0 +1,14 @@
+package internal
+
+import "google.golang.org/protobuf/types/known/structpb"
+
+// NewResource converts the fields produced by resource mappers to a resource context.
+// If omitEmpty is true and there are no fields, it returns a nil resource context so that policies see no resource
+// rather than an empty one.
+func NewResource(fields map[string]interface{}, omitEmpty bool) (*structpb.Struct, error) {
+	if omitEmpty && len(fields) == 0 {
+		return nil, nil //nolint: nilnil
+	}
+
+	return structpb.NewStruct(fields)
+}
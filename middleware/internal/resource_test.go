@@ -0,0 +1,23 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewResource(t *testing.T) {
+	resource, err := internal.NewResource(map[string]interface{}{}, false)
+	assert.NoError(t, err)
+	assert.NotNil(t, resource)
+	assert.Empty(t, resource.GetFields())
+
+	resource, err = internal.NewResource(map[string]interface{}{}, true)
+	assert.NoError(t, err)
+	assert.Nil(t, resource)
+
+	resource, err = internal.NewResource(map[string]interface{}{"id": "doc1"}, true)
+	assert.NoError(t, err)
+	assert.Equal(t, "doc1", resource.GetFields()["id"].GetStringValue())
+}
@@ -0,0 +1,38 @@
+package internal
+
+import (
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/pkg/aerr"
+)
+
+// SelectDecision returns the outcome of the named decision in an authorizer response.
+// The second return value is false if the response doesn't include a decision with the given name.
+func SelectDecision(resp *authz.IsResponse, name string) (allowed, ok bool) {
+	for _, decision := range resp.GetDecisions() {
+		if decision.GetDecision() == name {
+			return decision.GetIs(), true
+		}
+	}
+
+	return false, false
+}
+
+// Outcome returns the outcome of an authorizer response.
+// If name is empty, the response must include exactly one decision. Otherwise, the outcome is that of the decision
+// with the given name and all other decisions are ignored.
+func Outcome(resp *authz.IsResponse, name string) (bool, error) {
+	if name == "" {
+		if len(resp.GetDecisions()) != 1 {
+			return false, aerr.ErrInvalidDecision
+		}
+
+		return resp.GetDecisions()[0].GetIs(), nil
+	}
+
+	allowed, ok := SelectDecision(resp, name)
+	if !ok {
+		return false, aerr.ErrInvalidDecision
+	}
+
+	return allowed, nil
+}
@@ -0,0 +1,50 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware/internal"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/pkg/aerr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelectDecision(t *testing.T) {
+	resp := &authz.IsResponse{Decisions: []*authz.Decision{
+		{Decision: "allowed", Is: false},
+		{Decision: "visible", Is: true},
+	}}
+
+	allowed, ok := internal.SelectDecision(resp, "visible")
+	assert.True(t, ok)
+	assert.True(t, allowed)
+
+	allowed, ok = internal.SelectDecision(resp, "allowed")
+	assert.True(t, ok)
+	assert.False(t, allowed)
+
+	_, ok = internal.SelectDecision(resp, "enabled")
+	assert.False(t, ok)
+}
+
+func TestOutcome(t *testing.T) {
+	single := &authz.IsResponse{Decisions: []*authz.Decision{{Decision: "allowed", Is: true}}}
+	multiple := &authz.IsResponse{Decisions: []*authz.Decision{
+		{Decision: "allowed", Is: true},
+		{Decision: "visible", Is: false},
+	}}
+
+	allowed, err := internal.Outcome(single, "")
+	assert.NoError(t, err)
+	assert.True(t, allowed)
+
+	_, err = internal.Outcome(multiple, "")
+	assert.ErrorIs(t, err, aerr.ErrInvalidDecision)
+
+	allowed, err = internal.Outcome(multiple, "visible")
+	assert.NoError(t, err)
+	assert.False(t, allowed)
+
+	_, err = internal.Outcome(single, "visible")
+	assert.ErrorIs(t, err, aerr.ErrInvalidDecision)
+}
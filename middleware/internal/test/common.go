@@ -29,6 +29,7 @@ type Options struct {
 	ExpectedRequest *authz.IsRequest
 	Reject          bool
 	PolicyPath      string
+	Err             error
 }
 
 func (opts *Options) HasPolicy() bool {
@@ -40,7 +41,7 @@ func NewTest(t *testing.T, name string, options *Options) *Case {
 		options.ExpectedRequest = Request(PolicyPath(options.PolicyPath))
 	}
 
-	mockAuth := mock.New(t, options.ExpectedRequest, Decision(!options.Reject))
+	mockAuth := mock.New(t, options.ExpectedRequest, Decision(!options.Reject)).WithError(options.Err)
 
 	return &Case{Name: name, Client: mockAuth}
 }
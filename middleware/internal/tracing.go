@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/aserto-dev/go-authorizer/pkg/aerr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// AuthorizeSpanName is the name of the spans that wrap authorization calls.
+	AuthorizeSpanName = "aserto.authorize"
+
+	policyPathKey = attribute.Key("aserto.policy.path")
+	decisionKey   = attribute.Key("aserto.decision")
+	allowedKey    = attribute.Key("aserto.allowed")
+)
+
+// StartAuthorizeSpan starts a span for an authorization call and records the policy path and decisions.
+func StartAuthorizeSpan(
+	ctx context.Context,
+	tracer trace.Tracer,
+	policyContext *api.PolicyContext,
+) (context.Context, trace.Span) {
+	return tracer.Start(ctx, AuthorizeSpanName, trace.WithAttributes(
+		policyPathKey.String(policyContext.GetPath()),
+		decisionKey.StringSlice(policyContext.GetDecisions()),
+	))
+}
+
+// EndAuthorizeSpan records the outcome of an authorization call and ends its span. Denials are reported either
+// with allowed set to false or with aerr.ErrAuthorizationFailed.
+func EndAuthorizeSpan(span trace.Span, allowed bool, err error) {
+	defer span.End()
+
+	denied := errors.Is(err, aerr.ErrAuthorizationFailed)
+
+	switch {
+	case err != nil && !denied:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	case denied || !allowed:
+		span.SetStatus(codes.Error, "denied")
+	}
+
+	span.SetAttributes(allowedKey.Bool(allowed && err == nil))
+}
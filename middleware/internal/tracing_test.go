@@ -0,0 +1,49 @@
+package internal_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware/internal"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/aserto-dev/go-authorizer/pkg/aerr"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestAuthorizeSpan(t *testing.T) {
+	for name, tc := range map[string]struct {
+		allowed bool
+		err     error
+		status  codes.Code
+		events  int
+	}{
+		"allowed":              {allowed: true, status: codes.Unset},
+		"denied":               {allowed: false, status: codes.Error},
+		"authorization failed": {allowed: false, err: aerr.ErrAuthorizationFailed, status: codes.Error},
+		"error":                {allowed: false, err: errors.New("boom"), status: codes.Error, events: 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+			policyContext := &api.PolicyContext{Path: "myapp.GET.foo", Decisions: []string{"allowed"}}
+
+			_, span := internal.StartAuthorizeSpan(context.Background(), tracer, policyContext)
+			internal.EndAuthorizeSpan(span, tc.allowed, tc.err)
+
+			spans := recorder.Ended()
+			assert.Len(t, spans, 1)
+			assert.Equal(t, internal.AuthorizeSpanName, spans[0].Name())
+			assert.Contains(t, spans[0].Attributes(), attribute.String("aserto.policy.path", "myapp.GET.foo"))
+			assert.Contains(t, spans[0].Attributes(), attribute.StringSlice("aserto.decision", []string{"allowed"}))
+			assert.Contains(t, spans[0].Attributes(), attribute.Bool("aserto.allowed", tc.allowed))
+			assert.Equal(t, tc.status, spans[0].Status().Code)
+			assert.Len(t, spans[0].Events(), tc.events)
+		})
+	}
+}
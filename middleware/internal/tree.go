@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"strings"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// DecisionTree converts a decision tree response to a map from policy path to decision outcomes.
+// Decisions with non-boolean values are omitted. Paths and decisions are transformed according to format.
+func DecisionTree(resp *authz.DecisionTreeResponse, format middleware.DecisionTreeFormat) map[string]map[string]bool {
+	tree := map[string]map[string]bool{}
+
+	for path, value := range resp.GetPath().GetFields() {
+		decisions := map[string]bool{}
+
+		for name, decision := range value.GetStructValue().GetFields() {
+			b, ok := decision.GetKind().(*structpb.Value_BoolValue)
+			if !ok || (format.AllowedOnly && !b.BoolValue) {
+				continue
+			}
+
+			decisions[name] = b.BoolValue
+		}
+
+		if format.AllowedOnly && len(decisions) == 0 {
+			continue
+		}
+
+		tree[treePath(path, format)] = decisions
+	}
+
+	return tree
+}
+
+func treePath(path string, format middleware.DecisionTreeFormat) string {
+	if format.TrimPrefix != "" {
+		switch {
+		case path == format.TrimPrefix:
+			path = ""
+		case strings.HasPrefix(path, format.TrimPrefix+"."):
+			path = path[len(format.TrimPrefix)+1:]
+		}
+	}
+
+	if format.Separator != "" {
+		path = strings.ReplaceAll(path, ".", format.Separator)
+	}
+
+	return path
+}
@@ -0,0 +1,81 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	"github.com/aserto-dev/go-aserto/middleware/internal"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestDecisionTree(t *testing.T) {
+	path, err := structpb.NewStruct(map[string]interface{}{
+		"app.GET.users": map[string]interface{}{"allowed": true, "visible": false},
+		"app.DELETE.users": map[string]interface{}{
+			"allowed": false,
+			"reason":  "not an admin",
+		},
+	})
+	assert.NoError(t, err)
+
+	tree := internal.DecisionTree(&authz.DecisionTreeResponse{PathRoot: "app", Path: path}, middleware.DecisionTreeFormat{})
+
+	assert.Equal(t, map[string]map[string]bool{
+		"app.GET.users":    {"allowed": true, "visible": false},
+		"app.DELETE.users": {"allowed": false},
+	}, tree)
+}
+
+func TestEmptyDecisionTree(t *testing.T) {
+	assert.Empty(t, internal.DecisionTree(&authz.DecisionTreeResponse{}, middleware.DecisionTreeFormat{}))
+}
+
+func TestDecisionTreeFormat(t *testing.T) {
+	path, err := structpb.NewStruct(map[string]interface{}{
+		"app":              map[string]interface{}{"visible": true},
+		"app.GET.users":    map[string]interface{}{"visible": true, "enabled": false},
+		"app.DELETE.users": map[string]interface{}{"visible": false, "enabled": false},
+		"apple.GET":        map[string]interface{}{"visible": true},
+	})
+	assert.NoError(t, err)
+
+	resp := &authz.DecisionTreeResponse{PathRoot: "app", Path: path}
+
+	for name, tc := range map[string]struct {
+		format   middleware.DecisionTreeFormat
+		expected map[string]map[string]bool
+	}{
+		"trim prefix": {
+			format: middleware.DecisionTreeFormat{TrimPrefix: "app"},
+			expected: map[string]map[string]bool{
+				"":             {"visible": true},
+				"GET.users":    {"visible": true, "enabled": false},
+				"DELETE.users": {"visible": false, "enabled": false},
+				"apple.GET":    {"visible": true},
+			},
+		},
+		"separator": {
+			format: middleware.DecisionTreeFormat{Separator: "/"},
+			expected: map[string]map[string]bool{
+				"app":              {"visible": true},
+				"app/GET/users":    {"visible": true, "enabled": false},
+				"app/DELETE/users": {"visible": false, "enabled": false},
+				"apple/GET":        {"visible": true},
+			},
+		},
+		"allowed only": {
+			format: middleware.DecisionTreeFormat{TrimPrefix: "app", Separator: "/", AllowedOnly: true},
+			expected: map[string]map[string]bool{
+				"":          {"visible": true},
+				"GET/users": {"visible": true},
+				"apple/GET": {"visible": true},
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, internal.DecisionTree(resp, tc.format))
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package middleware
+
+// Outcome determines how middleware respond to an error returned by the authorizer.
+type Outcome int
+
+const (
+	// OutcomeError fails the request with an error. This is the default.
+	OutcomeError Outcome = iota
+
+	// OutcomeDeny treats the error as a denied authorization decision.
+	OutcomeDeny
+
+	// OutcomeAllow treats the error as an allowed authorization decision.
+	OutcomeAllow
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeError:
+		return "error"
+	case OutcomeDeny:
+		return "deny"
+	case OutcomeAllow:
+		return "allow"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import "strings"
+
+const (
+	// DefaultPathSeparator separates the segments of policy paths derived from requests.
+	DefaultPathSeparator = "."
+
+	// DefaultPathParamPrefix is prepended to the names of route parameters in policy paths derived from route
+	// patterns (e.g. "GET.users.__id").
+	DefaultPathParamPrefix = "__"
+)
+
+// PathCase determines the case of policy paths derived from requests.
+type PathCase int
+
+const (
+	// PathCasePreserve leaves the case of path segments unchanged.
+	PathCasePreserve PathCase = iota
+
+	// PathCaseLower converts path segments to lower case.
+	PathCaseLower
+
+	// PathCaseUpper converts path segments to upper case.
+	PathCaseUpper
+)
+
+// PathFormat determines how the default policy path mappers of HTTP and gRPC middleware build policy paths from
+// the segments of incoming requests (e.g. the HTTP method and URL path, or the gRPC service and method names).
+type PathFormat struct {
+	// Separator joins path segments.
+	Separator string
+
+	// Case is applied to path segments. The policy root or prefix is left unchanged.
+	Case PathCase
+
+	// ParamPrefix is prepended to the names of route parameters. It can be empty.
+	ParamPrefix string
+}
+
+// DefaultPathFormat returns the format used by policy path mappers unless configured otherwise.
+// Segments are joined with dots, their case is preserved, and route parameters are prefixed with "__".
+func DefaultPathFormat() PathFormat {
+	return PathFormat{
+		Separator:   DefaultPathSeparator,
+		Case:        PathCasePreserve,
+		ParamPrefix: DefaultPathParamPrefix,
+	}
+}
+
+// Param returns the path segment that stands for the route parameter with the given name.
+func (f *PathFormat) Param(name string) string {
+	return f.ParamPrefix + name
+}
+
+// Join returns the policy path made of the given prefix and segments. An empty prefix is omitted.
+func (f *PathFormat) Join(prefix string, segments ...string) string {
+	path := strings.Join(segments, f.Separator)
+
+	switch f.Case {
+	case PathCaseLower:
+		path = strings.ToLower(path)
+	case PathCaseUpper:
+		path = strings.ToUpper(path)
+	case PathCasePreserve:
+	}
+
+	if prefix = strings.Trim(prefix, DefaultPathSeparator+f.Separator); prefix != "" {
+		return prefix + f.Separator + path
+	}
+
+	return path
+}
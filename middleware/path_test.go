@@ -0,0 +1,28 @@
+package middleware_test
+
+import (
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathFormat(t *testing.T) {
+	for name, tc := range map[string]struct {
+		format   middleware.PathFormat
+		prefix   string
+		expected string
+	}{
+		"default":       {format: middleware.DefaultPathFormat(), prefix: "myapp.", expected: "myapp.GET.users.__id"},
+		"no prefix":     {format: middleware.DefaultPathFormat(), expected: "GET.users.__id"},
+		"separator":     {format: middleware.PathFormat{Separator: "/", ParamPrefix: "__"}, prefix: "myapp", expected: "myapp/GET/users/__id"},
+		"lower case":    {format: middleware.PathFormat{Separator: ".", Case: middleware.PathCaseLower, ParamPrefix: "__"}, prefix: "MyApp", expected: "MyApp.get.users.__id"},
+		"upper case":    {format: middleware.PathFormat{Separator: ".", Case: middleware.PathCaseUpper, ParamPrefix: "__"}, expected: "GET.USERS.__ID"},
+		"bare params":   {format: middleware.PathFormat{Separator: "."}, expected: "GET.users.id"},
+		"custom params": {format: middleware.PathFormat{Separator: "_", ParamPrefix: "by_"}, expected: "GET_users_by_id"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.format.Join(tc.prefix, "GET", "users", tc.format.Param("id")))
+		})
+	}
+}
@@ -0,0 +1,7 @@
+package middleware
+
+import "github.com/pkg/errors"
+
+// ErrInvalidTrustedPeer is returned by middleware configured with a trusted peer that isn't a valid CIDR block or IP
+// address.
+var ErrInvalidTrustedPeer = errors.New("invalid trusted peer")
@@ -7,11 +7,21 @@ Package middleware provides components that integrate Aserto authorization to gR
 */
 package middleware
 
+import (
+	"os"
+
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+)
+
 // Policy holds authorization options that apply to all requests.
 type Policy struct {
 	// Name is the Name of the policy being queried for authorization.
 	Name string
 
+	// InstanceLabel is the label of the policy instance to query. It allows targeting one of several instances of
+	// the same policy (e.g. "myapp-canary"). If left empty, Name is used.
+	InstanceLabel string
+
 	// Path is the package name of the rego policy to evaluate.
 	// If left empty, a policy mapper must be attached to the middleware to provide
 	// the policy path from incoming messages.
@@ -23,3 +33,41 @@ type Policy struct {
 	// Root is an optional prefix shared by all policy modules being evaluated.
 	Root string
 }
+
+// ToContext returns the policy context sent in authorization calls made with the policy.
+// Middleware may override the path of the returned context using their policy mappers.
+func (p *Policy) ToContext() *api.PolicyContext {
+	return &api.PolicyContext{
+		Path:      p.Path,
+		Decisions: []string{p.Decision},
+	}
+}
+
+// ToInstance returns the policy instance sent in authorization calls made with the policy.
+// The instance label defaults to the policy's name.
+func (p *Policy) ToInstance() *api.PolicyInstance {
+	label := p.InstanceLabel
+	if label == "" {
+		label = p.Name
+	}
+
+	return &api.PolicyInstance{
+		Name:          p.Name,
+		InstanceLabel: label,
+	}
+}
+
+// PolicyInstanceEnv is the environment variable read by Policy.WithInstanceFromEnv.
+const PolicyInstanceEnv = "ASERTO_POLICY_INSTANCE"
+
+// WithInstanceFromEnv sets the policy instance name from the ASERTO_POLICY_INSTANCE environment variable.
+// It is a no-op if Name is already set or the variable is empty, so explicit configuration takes precedence.
+//
+// This allows the same binary to target different policy instances depending on its deployment.
+func (p *Policy) WithInstanceFromEnv() *Policy {
+	if p.Name == "" {
+		p.Name = os.Getenv(PolicyInstanceEnv)
+	}
+
+	return p
+}
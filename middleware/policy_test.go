@@ -0,0 +1,38 @@
+package middleware_test
+
+import (
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyInstanceFromEnv(t *testing.T) {
+	t.Setenv(middleware.PolicyInstanceEnv, "myapp-canary")
+
+	policy := (&middleware.Policy{Decision: "allowed"}).WithInstanceFromEnv()
+	assert.Equal(t, "myapp-canary", policy.Name)
+
+	explicit := (&middleware.Policy{Name: "myapp"}).WithInstanceFromEnv()
+	assert.Equal(t, "myapp", explicit.Name)
+}
+
+func TestPolicyInstanceFromEmptyEnv(t *testing.T) {
+	t.Setenv(middleware.PolicyInstanceEnv, "")
+
+	policy := (&middleware.Policy{}).WithInstanceFromEnv()
+	assert.Empty(t, policy.Name)
+}
+
+func TestPolicyConversion(t *testing.T) {
+	policy := &middleware.Policy{Name: "myapp", Path: "myapp.GET.users", Decision: "allowed"}
+
+	assert.Equal(t, "myapp.GET.users", policy.ToContext().GetPath())
+	assert.Equal(t, []string{"allowed"}, policy.ToContext().GetDecisions())
+	assert.Equal(t, "myapp", policy.ToInstance().GetName())
+	assert.Equal(t, "myapp", policy.ToInstance().GetInstanceLabel())
+
+	policy.InstanceLabel = "myapp-canary"
+	assert.Equal(t, "myapp", policy.ToInstance().GetName())
+	assert.Equal(t, "myapp-canary", policy.ToInstance().GetInstanceLabel())
+}
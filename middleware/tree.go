@@ -0,0 +1,16 @@
+package middleware
+
+// DecisionTreeFormat determines how the results of decision tree evaluations are presented.
+// The zero value returns policy paths and decisions as they are received from the authorizer.
+type DecisionTreeFormat struct {
+	// TrimPrefix is removed from the start of each policy path along with the separator that follows it.
+	// It is typically the policy root (e.g. "myapp" turns "myapp.GET.users" into "GET.users").
+	// Paths that don't start with the prefix are left unchanged.
+	TrimPrefix string
+
+	// Separator replaces the dots that separate the segments of policy paths (e.g. "/").
+	Separator string
+
+	// AllowedOnly omits decisions that evaluate to false and paths that have no allowed decisions.
+	AllowedOnly bool
+}
@@ -0,0 +1,4 @@
+/*
+Package twirpz provides authorization components for servers built with Twirp (github.com/twitchtv/twirp).
+*/
+package twirpz
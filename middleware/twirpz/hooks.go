@@ -0,0 +1,309 @@
+package twirpz
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	"github.com/aserto-dev/go-aserto/middleware/internal"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/aserto-dev/go-authorizer/pkg/aerr"
+	"github.com/twitchtv/twirp"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+type (
+	Policy           = middleware.Policy
+	AuthorizerClient = authz.AuthorizerClient
+)
+
+/*
+Middleware authorizes calls to Twirp servers using server hooks.
+
+To authorize incoming calls, the middleware needs information about:
+
+1. The user making the request.
+
+2. The Aserto authorization policy to evaluate.
+
+3. Optional, additional input data to the authorization policy.
+
+Calls are authorized when they are routed to a method, before the request message is read, so mappers only have
+access to the request context. To make the headers of incoming requests available, wrap the Twirp server with
+WithRequestHeaders.
+*/
+type Middleware struct {
+	// Identity determines the caller identity used in authorization calls.
+	Identity *IdentityBuilder
+
+	client            AuthorizerClient
+	policy            *Policy
+	policyMapper      StringMapper
+	resourceMappers   []ResourceMapper
+	omitEmptyResource bool
+	manualIdentity    string
+	allowedMethods    internal.Lookup[string]
+	decision          string
+}
+
+type (
+	// StringMapper functions are used to extract string values from the context of incoming calls.
+	// They are used to define policy path mappers.
+	StringMapper func(context.Context) string
+
+	// ResourceMapper functions are used to extract structured data from the context of incoming calls.
+	ResourceMapper func(context.Context, map[string]interface{})
+)
+
+type headersKey struct{}
+
+// New creates middleware for the specified policy.
+//
+// The new middleware is created with default identity and policy path mapper.
+// Those can be overridden using `Middleware.Identity` to specify the caller's identity, or using
+// the middleware's ".With...()" functions to set policy path and resource mappers.
+func New(authzClient AuthorizerClient, policy *Policy) *Middleware {
+	policyMapper := methodPolicyMapper("")
+	if policy.Path != "" {
+		policyMapper = nil
+	}
+
+	return &Middleware{
+		Identity:        (&IdentityBuilder{}).FromHeader("Authorization").byDefault(),
+		client:          authzClient,
+		policy:          policy,
+		policyMapper:    policyMapper,
+		resourceMappers: []ResourceMapper{},
+	}
+}
+
+// WithRequestHeaders makes the headers of incoming HTTP requests available to the middleware's identity builder and
+// mappers. Twirp doesn't add request headers to the context of server hooks, so servers are wrapped with it:
+//
+//	server := example.NewHaberdasherServer(svc, twirp.WithServerHooks(mw.ServerHooks()))
+//	http.Handle(server.PathPrefix(), twirpz.WithRequestHeaders(server))
+func WithRequestHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), headersKey{}, r.Header.Clone())
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestHeaders returns the HTTP headers added to the context by WithRequestHeaders.
+// If there are none, it returns an empty header.
+func RequestHeaders(ctx context.Context) http.Header {
+	if headers, ok := ctx.Value(headersKey{}).(http.Header); ok {
+		return headers
+	}
+
+	return http.Header{}
+}
+
+// WithAllowedMethods takes a list of Twirp methods that are allowed to proceed without authorization.
+// Methods are in the format "/package.Service/Method".
+func (m *Middleware) WithAllowedMethods(methods ...string) *Middleware {
+	m.allowedMethods = internal.NewLookup(methods...)
+	return m
+}
+
+// WithDecision selects the decision used to authorize requests by name, overriding the policy's Decision.
+//
+// The authorizer's response may include additional decisions. The outcome is that of the decision whose name
+// matches. Responses without a matching decision fail.
+func (m *Middleware) WithDecision(name string) *Middleware {
+	m.decision = name
+	return m
+}
+
+// WithPolicyPathMapper takes a custom StringMapper for extracting the authorization policy path from
+// the context of incoming calls.
+func (m *Middleware) WithPolicyPathMapper(mapper StringMapper) *Middleware {
+	m.policyMapper = mapper
+	return m
+}
+
+// WithPolicyFromMethod derives the policy path from the called method, prefixed with the given policy root.
+// For example, with the root "myapp", calls to the GetUser method of the example.v1.UserService service are
+// authorized using the policy path "myapp.example.v1.UserService.GetUser".
+func (m *Middleware) WithPolicyFromMethod(root string) *Middleware {
+	m.policyMapper = methodPolicyMapper(root)
+	return m
+}
+
+// WithResourceFromContextValue instructs the middleware to read the specified value from the incoming request
+// context and add it to the authorization resource context under the given field.
+func (m *Middleware) WithResourceFromContextValue(ctxKey interface{}, field string) *Middleware {
+	m.resourceMappers = append(m.resourceMappers, func(ctx context.Context, res map[string]interface{}) {
+		if v := ctx.Value(ctxKey); v != nil {
+			res[field] = v
+		}
+	})
+
+	return m
+}
+
+// WithResourceMapper takes a custom ResourceMapper for extracting the authorization resource context from
+// the context of incoming calls.
+func (m *Middleware) WithResourceMapper(mapper ResourceMapper) *Middleware {
+	m.resourceMappers = append(m.resourceMappers, mapper)
+	return m
+}
+
+// WithoutEmptyResource sends authorization calls without a resource context when resource mappers don't produce any
+// fields, instead of sending an empty resource. This lets policies distinguish "no resource" from "empty resource".
+func (m *Middleware) WithoutEmptyResource() *Middleware {
+	m.omitEmptyResource = true
+	return m
+}
+
+// WithResourceFallback adds the fields produced by the primary ResourceMapper to the resource context. If primary
+// produces no fields, the fields produced by fallback are added instead.
+func (m *Middleware) WithResourceFallback(primary, fallback ResourceMapper) *Middleware {
+	return m.WithResourceMapper(func(ctx context.Context, resource map[string]interface{}) {
+		fields := map[string]interface{}{}
+
+		primary(ctx, fields)
+
+		if len(fields) == 0 {
+			fallback(ctx, fields)
+		}
+
+		for k, v := range fields {
+			resource[k] = v
+		}
+	})
+}
+
+// WithManualIdentityInResource adds the caller's identity to the resource context under the given field when the
+// identity type is manual. Manual identities are passed to policies as-is, so this lets policies that don't read
+// input.identity access them.
+func (m *Middleware) WithManualIdentityInResource(field string) *Middleware {
+	m.manualIdentity = field
+
+	return m
+}
+
+// ServerHooks returns Twirp server hooks that authorize calls once they are routed to a method.
+// Denied calls fail with a twirp.PermissionDenied error.
+//
+// Use twirp.ChainHooks to combine them with other hooks.
+func (m *Middleware) ServerHooks() *twirp.ServerHooks {
+	return &twirp.ServerHooks{RequestRouted: m.authorize}
+}
+
+// authorize returns a copy of ctx that carries the caller's identity if the request is allowed.
+func (m *Middleware) authorize(ctx context.Context) (context.Context, error) {
+	if m.allowedMethods.Contains(fullMethod(ctx)) {
+		return ctx, nil
+	}
+
+	policyContext := internal.DefaultPolicyContext(m.policy)
+	if m.decision != "" {
+		policyContext.Decisions = []string{m.decision}
+	}
+
+	if m.policyMapper != nil {
+		policyContext.Path = m.policyMapper(ctx)
+	}
+
+	identity, err := m.Identity.resolve(ctx)
+	if err != nil {
+		return ctx, twirp.WrapError(twirp.InternalError("failed to resolve identity"), err)
+	}
+
+	resource, err := m.resourceContext(ctx, identity)
+	if err != nil {
+		return ctx, twirp.WrapError(twirp.InternalError("failed to apply resource mapper"), err)
+	}
+
+	resp, err := m.client.Is(ctx, &authz.IsRequest{
+		IdentityContext: identity,
+		PolicyContext:   policyContext,
+		ResourceContext: resource,
+		PolicyInstance:  internal.DefaultPolicyInstance(m.policy),
+	})
+	if err != nil {
+		code := twirp.Internal
+		if status.Code(err) == codes.Unavailable {
+			code = twirp.Unavailable
+		}
+
+		return ctx, twirp.WrapError(twirp.NewError(code, "authorization call failed"), err)
+	}
+
+	allowed, err := m.outcome(resp)
+	if err != nil {
+		return ctx, twirp.InternalErrorWith(err)
+	}
+
+	if !allowed {
+		return ctx, twirp.WrapError(
+			twirp.NewError(twirp.PermissionDenied, aerr.ErrAuthorizationFailed.Message),
+			aerr.ErrAuthorizationFailed,
+		)
+	}
+
+	return middleware.WithIdentity(ctx, identity), nil
+}
+
+// outcome returns the outcome of the authorizer's response. Unless a decision is selected with WithDecision, the
+// first decision is used.
+func (m *Middleware) outcome(resp *authz.IsResponse) (bool, error) {
+	if m.decision != "" {
+		return internal.Outcome(resp, m.decision)
+	}
+
+	if len(resp.GetDecisions()) == 0 {
+		return false, aerr.ErrInvalidDecision
+	}
+
+	return resp.GetDecisions()[0].GetIs(), nil
+}
+
+func (m *Middleware) resourceContext(ctx context.Context, identity *api.IdentityContext) (*structpb.Struct, error) {
+	res := map[string]interface{}{}
+	for _, mapper := range m.resourceMappers {
+		mapper(ctx, res)
+	}
+
+	if m.manualIdentity != "" {
+		internal.AddManualIdentity(identity, m.manualIdentity, res)
+	}
+
+	return internal.NewResource(res, m.omitEmptyResource)
+}
+
+// PolicyPathFor returns the policy path the middleware's default policy mapper derives from a full method name
+// (e.g. "/package.Service/Method"), optionally prefixed with a policy root.
+func PolicyPathFor(method, root string) string {
+	path := internal.ToPolicyPath(method)
+
+	if root == "" {
+		return path
+	}
+
+	return fmt.Sprintf("%s.%s", root, path)
+}
+
+func methodPolicyMapper(root string) StringMapper {
+	return func(ctx context.Context) string {
+		return PolicyPathFor(fullMethod(ctx), root)
+	}
+}
+
+// fullMethod returns the name of the called method in the format "/package.Service/Method".
+func fullMethod(ctx context.Context) string {
+	pkg, _ := twirp.PackageName(ctx)
+	service, _ := twirp.ServiceName(ctx)
+	method, _ := twirp.MethodName(ctx)
+
+	if pkg != "" {
+		service = pkg + "." + service
+	}
+
+	return "/" + service + "/" + method
+}
@@ -0,0 +1,91 @@
+package twirpz_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	"github.com/aserto-dev/go-aserto/middleware/internal/test"
+	"github.com/aserto-dev/go-aserto/middleware/twirpz"
+	"github.com/aserto-dev/go-authorizer/pkg/aerr"
+	"github.com/stretchr/testify/assert"
+	"github.com/twitchtv/twirp"
+	"github.com/twitchtv/twirp/ctxsetters"
+)
+
+// routed returns the context of a call to example.v1.Service/Get with the given request headers, as seen by the
+// RequestRouted hook.
+func routed(t *testing.T, headers map[string]string) context.Context {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/twirp/example.v1.Service/Get", http.NoBody)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	var ctx context.Context
+
+	twirpz.WithRequestHeaders(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	})).ServeHTTP(httptest.NewRecorder(), req)
+
+	ctx = ctxsetters.WithPackageName(ctx, "example.v1")
+	ctx = ctxsetters.WithServiceName(ctx, "Service")
+
+	return ctxsetters.WithMethodName(ctx, "Get")
+}
+
+func TestServerHooks(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		reject bool
+	}{
+		{"allowed", false},
+		{"denied", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			base := test.NewTest(t, tc.name, &test.Options{PolicyPath: "myapp.example.v1.Service.Get", Reject: tc.reject})
+
+			mw := twirpz.New(base.Client, test.Policy("")).WithPolicyFromMethod("myapp")
+			mw.Identity.Subject()
+
+			ctx, err := mw.ServerHooks().RequestRouted(routed(t, map[string]string{"Authorization": test.DefaultUsername}))
+			if tc.reject {
+				var twerr twirp.Error
+				assert.True(t, errors.As(err, &twerr))
+				assert.Equal(t, twirp.PermissionDenied, twerr.Code())
+				assert.ErrorIs(t, err, aerr.ErrAuthorizationFailed)
+
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.DefaultUsername, middleware.IdentityFromContext(ctx).GetIdentity())
+		})
+	}
+}
+
+func TestAllowedMethods(t *testing.T) {
+	base := test.NewTest(t, "allowed methods", &test.Options{Reject: true})
+
+	mw := twirpz.New(base.Client, test.Policy("")).WithAllowedMethods("/example.v1.Service/Get")
+
+	_, err := mw.ServerHooks().RequestRouted(routed(t, nil))
+	assert.NoError(t, err)
+}
+
+func TestAuthorizerError(t *testing.T) {
+	base := test.NewTest(t, "error", &test.Options{PolicyPath: "example.v1.Service.Get", Err: errors.New("boom")})
+
+	mw := twirpz.New(base.Client, test.Policy(""))
+	mw.Identity.Subject()
+
+	_, err := mw.ServerHooks().RequestRouted(routed(t, map[string]string{"Authorization": test.DefaultUsername}))
+
+	var twerr twirp.Error
+	assert.True(t, errors.As(err, &twerr))
+	assert.Equal(t, twirp.Internal, twerr.Code())
+}
@@ -0,0 +1,217 @@
+package twirpz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	"github.com/aserto-dev/go-aserto/middleware/internal"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+)
+
+// IdentityMapper is the type of callback functions that can inspect incoming requests and set the caller's identity.
+// Twirp hooks only have access to the request context. HTTP headers are available through RequestHeaders.
+type IdentityMapper func(context.Context, middleware.Identity)
+
+// IdentityBuilder is used to configure what information about caller identity is sent in authorization calls.
+type IdentityBuilder struct {
+	identityType    api.IdentityType
+	defaultIdentity string
+	anonymous       string
+	sources         []internal.IdentitySource[IdentityMapper]
+	precedence      middleware.IdentityPrecedence
+	replaceSources  bool
+	rejectExpired   bool
+	expirySkew      time.Duration
+}
+
+// Static values
+
+// Call JWT() to indicate that the user's identity is expressed as a string-encoded JWT.
+//
+// JWT() is always called in conjunction with another method that provides the user ID itself.
+// For example:
+//
+//	idBuilder.JWT().FromHeader("Authorization")
+func (b *IdentityBuilder) JWT() *IdentityBuilder {
+	b.identityType = api.IdentityType_IDENTITY_TYPE_JWT
+	return b
+}
+
+// Call Subject() to indicate that the user's identity is a subject name (email, userid, etc.).
+//
+// Subject() is always used in conjunction with another method that provides the user ID itself.
+// For example:
+//
+//	idBuilder.Subject().FromContextValue("username")
+func (b *IdentityBuilder) Subject() *IdentityBuilder {
+	b.identityType = api.IdentityType_IDENTITY_TYPE_SUB
+	return b
+}
+
+// Call Manual() to indicate that the user's identity is set manually and isn't resolved to a user by the authorizer.
+//
+// Manually set identities are available in the authorizer's policy language through the "input.identity" variable.
+func (b *IdentityBuilder) Manual() *IdentityBuilder {
+	b.identityType = api.IdentityType_IDENTITY_TYPE_MANUAL
+	return b
+}
+
+// Call None() to indicate that requests are unauthenticated.
+func (b *IdentityBuilder) None() *IdentityBuilder {
+	b.identityType = api.IdentityType_IDENTITY_TYPE_NONE
+	b.defaultIdentity = ""
+
+	return b
+}
+
+// AnonymousAs sets a well-known subject used when no caller identity is resolved.
+//
+// Instead of being sent as anonymous (NONE) identities, unauthenticated requests are authorized using the given
+// subject. This lets policies grant limited access to a concrete anonymous principal.
+// For example:
+//
+//	idBuilder.FromHeader("Authorization").AnonymousAs("anonymous")
+func (b *IdentityBuilder) AnonymousAs(subject string) *IdentityBuilder {
+	b.anonymous = subject
+	return b
+}
+
+// RejectExpired treats requests with expired JWTs as anonymous. A token is expired if its "exp" claim is more
+// than skew in the past. Tokens without an "exp" claim are accepted. Identities from all sources, including
+// custom mappers, are checked.
+//
+// The token's signature isn't verified. RejectExpired only catches stale credentials and doesn't replace
+// token validation.
+func (b *IdentityBuilder) RejectExpired(skew time.Duration) *IdentityBuilder {
+	b.rejectExpired = true
+	b.expirySkew = skew
+
+	return b
+}
+
+// Call ID(...) to set the user's identity. If neither JWT() or Subject() are called too, IdentityMapper
+// tries to infer whether the specified identity is a JWT or not.
+// Passing an empty string is the same as calling .None() and results in an authorization check for anonymous access.
+func (b *IdentityBuilder) ID(identity string) *IdentityBuilder {
+	b.defaultIdentity = identity
+	return b
+}
+
+// FromHeader retrieves caller identity from the headers of incoming requests.
+// The Twirp server must be wrapped with WithRequestHeaders for headers to be available.
+//
+// Headers are attempted in order. The first non-empty header is used.
+// If none of the specified headers have a value, the request is considered anonymous.
+func (b *IdentityBuilder) FromHeader(header ...string) *IdentityBuilder {
+	return b.addSource("header:"+strings.Join(header, ","), func(ctx context.Context, identity middleware.Identity) {
+		headers := RequestHeaders(ctx)
+
+		for _, h := range header {
+			id := headers.Get(h)
+			if id == "" {
+				continue
+			}
+
+			if strings.EqualFold(h, "authorization") {
+				// Authorization header is special. Need to remove "Bearer" auth scheme.
+				id = b.fromAuthzHeader(id)
+			}
+
+			identity.ID(id)
+
+			return
+		}
+
+		// None of the specified headers are present in the request.
+		identity.None()
+	})
+}
+
+// FromContextValue extracts caller identity from a value in the incoming request context.
+//
+// If the value is not present, not a string, or an empty string then the request is considered anonymous.
+func (b *IdentityBuilder) FromContextValue(key interface{}) *IdentityBuilder {
+	return b.addSource(fmt.Sprintf("context:%v", key), func(ctx context.Context, identity middleware.Identity) {
+		identity.ID(internal.ValueOrEmpty(ctx, key))
+	})
+}
+
+// Precedence determines how the caller's identity is resolved when multiple identity sources are configured.
+// Each call to a From...() method or Mapper() adds a source. The default source set by the middleware is replaced
+// by the first source added. Default: middleware.IdentityLastSource.
+//
+// For example, to use the identity from a context value and fall back to the Authorization header:
+//
+//	idBuilder.FromContextValue("user").FromHeader("Authorization").Precedence(middleware.IdentityFirstSource)
+func (b *IdentityBuilder) Precedence(precedence middleware.IdentityPrecedence) *IdentityBuilder {
+	b.precedence = precedence
+	return b
+}
+
+// Mapper takes a custom IdentityMapper to be used for extracting identity information from incoming requests.
+func (b *IdentityBuilder) Mapper(mapper IdentityMapper) *IdentityBuilder {
+	return b.addSource("mapper", mapper)
+}
+
+// Build constructs an IdentityContext that can be used in authorization requests.
+func (b *IdentityBuilder) Build(ctx context.Context) *api.IdentityContext {
+	identity, _ := b.resolve(ctx)
+	return identity
+}
+
+// resolve returns the caller's identity. It fails if identity sources conflict and IdentityStrict precedence is used.
+func (b *IdentityBuilder) resolve(ctx context.Context) (*api.IdentityContext, error) {
+	identity, err := internal.ResolveIdentity(b.identityType, b.defaultIdentity, b.precedence, b.sources, b.fromToken,
+		func(mapper IdentityMapper, identity middleware.Identity) { mapper(ctx, identity) })
+
+	return identity.ContextOrAnonymous(b.anonymous), err
+}
+
+func (b *IdentityBuilder) fromAuthzHeader(value string) string {
+	// Authorization header is special. Need to remove "Bearer" auth scheme.
+	return strings.TrimSpace(strings.TrimPrefix(value, "Bearer"))
+}
+
+// fromToken returns the subject of a JWT if identityType is subject and value is a well-formed token.
+// It is applied to the identity resolved by each source.
+// If RejectExpired is set, expired tokens are replaced with an empty string. Otherwise, value is returned as is.
+//
+// The token's signature isn't verified. Callers are expected to be authenticated before reaching the middleware.
+func (b *IdentityBuilder) fromToken(identityType api.IdentityType, value string) string {
+	if identityType != api.IdentityType_IDENTITY_TYPE_SUB && !b.rejectExpired {
+		return value
+	}
+
+	token, err := jwt.ParseString(value, jwt.WithVerify(false), jwt.WithAcceptableSkew(b.expirySkew))
+	if b.rejectExpired && errors.Is(err, jwt.ErrTokenExpired()) {
+		return ""
+	}
+
+	if err == nil && identityType == api.IdentityType_IDENTITY_TYPE_SUB {
+		// Use the token's subject
+		return token.Subject()
+	}
+
+	return value
+}
+
+// byDefault marks the configured identity sources as defaults that are replaced by the next source added.
+func (b *IdentityBuilder) byDefault() *IdentityBuilder {
+	b.replaceSources = true
+	return b
+}
+
+func (b *IdentityBuilder) addSource(name string, mapper IdentityMapper) *IdentityBuilder {
+	if b.replaceSources {
+		b.sources = nil
+		b.replaceSources = false
+	}
+
+	b.sources = append(b.sources, internal.IdentitySource[IdentityMapper]{Name: name, Mapper: mapper})
+	return b
+}
@@ -0,0 +1,64 @@
+package twirpz_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware/twirpz"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwt"
+	"github.com/stretchr/testify/assert"
+)
+
+const username = "george"
+
+type user struct{}
+
+func TestIdentityFromHeader(t *testing.T) {
+	builder := (&twirpz.IdentityBuilder{}).JWT().FromHeader("X-Identity", "Authorization")
+
+	identity := builder.Build(routed(t, map[string]string{"Authorization": "Bearer " + username}))
+
+	assert.Equal(t, api.IdentityType_IDENTITY_TYPE_JWT, identity.GetType())
+	assert.Equal(t, username, identity.GetIdentity())
+}
+
+func TestIdentityFromMissingHeader(t *testing.T) {
+	builder := (&twirpz.IdentityBuilder{}).Subject().FromHeader("Authorization")
+
+	identity := builder.Build(routed(t, nil))
+
+	assert.Equal(t, api.IdentityType_IDENTITY_TYPE_NONE, identity.GetType())
+}
+
+func TestSubjectFromHeaderToken(t *testing.T) {
+	token, err := jwt.NewBuilder().Subject(username).Build()
+	assert.NoError(t, err)
+
+	signed, err := jwt.Sign(token, jwt.WithKey(jwa.HS256, []byte("secret")))
+	assert.NoError(t, err)
+
+	builder := (&twirpz.IdentityBuilder{}).Subject().FromHeader("Authorization")
+
+	identity := builder.Build(routed(t, map[string]string{"Authorization": "Bearer " + string(signed)}))
+
+	assert.Equal(t, api.IdentityType_IDENTITY_TYPE_SUB, identity.GetType())
+	assert.Equal(t, username, identity.GetIdentity())
+}
+
+func TestIdentityFromContextValue(t *testing.T) {
+	builder := (&twirpz.IdentityBuilder{}).Subject().FromContextValue(user{})
+
+	identity := builder.Build(context.WithValue(context.TODO(), user{}, username))
+
+	assert.Equal(t, api.IdentityType_IDENTITY_TYPE_SUB, identity.GetType())
+	assert.Equal(t, username, identity.GetIdentity())
+}
+
+func TestManualIdentity(t *testing.T) {
+	identity := (&twirpz.IdentityBuilder{}).Manual().ID("object_id").Build(routed(t, nil))
+
+	assert.Equal(t, api.IdentityType_IDENTITY_TYPE_MANUAL, identity.GetType())
+	assert.Equal(t, "object_id", identity.GetIdentity())
+}
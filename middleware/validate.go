@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrInvalidPolicy   = errors.New("invalid policy")
+	ErrUnknownPolicy   = errors.New("policy not found")
+	ErrUnknownDecision = errors.New("decision not found")
+)
+
+// Validate verifies that the policy configured for a middleware is known to the authorizer.
+//
+// If policy.Path is set, the authorizer must have a policy module with that package path. Otherwise, if policy.Root
+// is set, at least one module must be under the root. The matching modules must define policy.Decision.
+//
+// Validate can be called on startup to catch misconfigured policies before serving requests.
+func Validate(ctx context.Context, client authz.AuthorizerClient, policy *Policy) error {
+	if policy == nil || policy.Decision == "" {
+		return errors.Wrap(ErrInvalidPolicy, "decision not specified")
+	}
+
+	resp, err := client.ListPolicies(ctx, &authz.ListPoliciesRequest{
+		PolicyInstance: policy.ToInstance(),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to list policies")
+	}
+
+	modules := matchingModules(resp.GetResult(), policy)
+	if len(modules) == 0 {
+		return errors.Wrapf(ErrUnknownPolicy, "policy %q", policyName(policy))
+	}
+
+	rule := regexp.MustCompile(`(?m)^\s*(default\s+)?` + regexp.QuoteMeta(policy.Decision) + `\b`)
+
+	for _, module := range modules {
+		// Module sources aren't always included. Only reject decisions that are known to be missing.
+		if module.Raw == nil || rule.MatchString(module.GetRaw()) {
+			return nil
+		}
+	}
+
+	return errors.Wrapf(ErrUnknownDecision, "decision %q in policy %q", policy.Decision, policyName(policy))
+}
+
+func matchingModules(modules []*api.Module, policy *Policy) []*api.Module {
+	var matches []*api.Module
+
+	for _, module := range modules {
+		path := strings.TrimPrefix(module.GetPackagePath(), "data.")
+
+		switch {
+		case policy.Path != "":
+			if path != policy.Path {
+				continue
+			}
+		case policy.Root != "":
+			if path != policy.Root && !strings.HasPrefix(path, policy.Root+".") {
+				continue
+			}
+		}
+
+		matches = append(matches, module)
+	}
+
+	return matches
+}
+
+func policyName(policy *Policy) string {
+	if policy.Path != "" {
+		return policy.Path
+	}
+
+	return policy.Root
+}
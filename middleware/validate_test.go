@@ -0,0 +1,62 @@
+package middleware_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aserto-dev/go-aserto/middleware"
+	authz "github.com/aserto-dev/go-authorizer/aserto/authorizer/v2"
+	"github.com/aserto-dev/go-authorizer/aserto/authorizer/v2/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+type policiesClient struct {
+	authz.AuthorizerClient
+
+	modules []*api.Module
+}
+
+func (c *policiesClient) ListPolicies(
+	_ context.Context,
+	_ *authz.ListPoliciesRequest,
+	_ ...grpc.CallOption,
+) (*authz.ListPoliciesResponse, error) {
+	return &authz.ListPoliciesResponse{Result: c.modules}, nil
+}
+
+func TestValidate(t *testing.T) {
+	client := &policiesClient{modules: []*api.Module{
+		{
+			PackagePath: proto.String("myapp.GET.users"),
+			Raw:         proto.String("package myapp.GET.users\n\ndefault allowed := false\n\nallowed if { true }\n"),
+		},
+		{PackagePath: proto.String("myapp.POST.users")},
+	}}
+
+	ctx := context.Background()
+
+	assert.NoError(t, middleware.Validate(ctx, client, &middleware.Policy{Path: "myapp.GET.users", Decision: "allowed"}))
+	assert.NoError(t, middleware.Validate(ctx, client, &middleware.Policy{Root: "myapp", Decision: "allowed"}))
+
+	// Modules without sources can't be checked for decisions.
+	assert.NoError(t, middleware.Validate(ctx, client, &middleware.Policy{Path: "myapp.POST.users", Decision: "visible"}))
+
+	assert.ErrorIs(t,
+		middleware.Validate(ctx, client, &middleware.Policy{Path: "myap.GET.users", Decision: "allowed"}),
+		middleware.ErrUnknownPolicy,
+	)
+	assert.ErrorIs(t,
+		middleware.Validate(ctx, client, &middleware.Policy{Root: "other", Decision: "allowed"}),
+		middleware.ErrUnknownPolicy,
+	)
+	assert.ErrorIs(t,
+		middleware.Validate(ctx, client, &middleware.Policy{Path: "myapp.GET.users", Decision: "alowed"}),
+		middleware.ErrUnknownDecision,
+	)
+	assert.ErrorIs(t,
+		middleware.Validate(ctx, client, &middleware.Policy{Path: "myapp.GET.users"}),
+		middleware.ErrInvalidPolicy,
+	)
+}
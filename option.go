@@ -1,16 +1,25 @@
 package aserto
 
 import (
+	"context"
 	"net/url"
+	"time"
 
 	"github.com/pkg/errors"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/stats"
 
 	"github.com/aserto-dev/go-aserto/internal/client"
 )
 
 var ErrInvalidOptions = errors.New("invalid connection options")
 
+const (
+	DefaultKeepaliveTime    = 30 * time.Second
+	DefaultKeepaliveTimeout = 10 * time.Second
+)
+
 // ConnectionOption functions are used to configure ConnectionOptions instances.
 type ConnectionOption func(*ConnectionOptions) error
 
@@ -76,6 +85,39 @@ func WithCACertPath(path string) ConnectionOption {
 	}
 }
 
+// WithCACertPaths treats each of the specified certificate files as a trusted root CA.
+//
+// It can be called more than once and combined with WithCACertPath, for example to trust
+// both the old and new CAs during certificate rotation.
+func WithCACertPaths(paths ...string) ConnectionOption {
+	return func(options *ConnectionOptions) error {
+		options.CACertPaths = append(options.CACertPaths, paths...)
+
+		return nil
+	}
+}
+
+// WithCACertDir treats all certificate files in the specified directory as trusted root CAs.
+//
+// Files with a .pem, .crt, or .cer extension are loaded.
+func WithCACertDir(dir string) ConnectionOption {
+	return func(options *ConnectionOptions) error {
+		options.CACertDir = dir
+
+		return nil
+	}
+}
+
+// WithSystemCertPool determines whether CA certificates specified with WithCACertPath, WithCACertPaths, or
+// WithCACertDir are appended to the system's root CAs (true) or replace them (false). Default: true.
+func WithSystemCertPool(useSystemPool bool) ConnectionOption {
+	return func(options *ConnectionOptions) error {
+		options.NoSystemCertPool = !useSystemPool
+
+		return nil
+	}
+}
+
 // WithClientCert configure the client certificate for mTLS connections.
 func WithClientCert(certPath, keyPath string) ConnectionOption {
 	return func(options *ConnectionOptions) error {
@@ -103,6 +145,22 @@ func WithTokenAuth(token string) ConnectionOption {
 	}
 }
 
+// WithTokenProvider uses OAuth2.0 tokens returned by provider to authenticate with the authorizer service.
+//
+// The provider is called on every call to the service, which allows tokens to be refreshed without recreating
+// the client. Providers are expected to cache tokens until they expire.
+func WithTokenProvider(provider func(context.Context) (string, error)) ConnectionOption {
+	return func(options *ConnectionOptions) error {
+		if options.Creds != nil {
+			return errors.Wrap(ErrInvalidOptions, "only one set of credentials allowed")
+		}
+
+		options.Creds = client.NewTokenProviderAuth(provider)
+
+		return nil
+	}
+}
+
 // WithAPIKeyAuth uses an Aserto API key to authenticate with the authorizer service.
 func WithAPIKeyAuth(key string) ConnectionOption {
 	return func(options *ConnectionOptions) error {
@@ -165,6 +223,60 @@ func WithDialOptions(opts ...grpc.DialOption) ConnectionOption {
 	}
 }
 
+// WithStatsHandler adds a stats handler to the grpc connection, for observability tools that collect RPC-level stats
+// (e.g. otelgrpc). Multiple handlers can be added.
+func WithStatsHandler(h stats.Handler) ConnectionOption {
+	return WithDialOptions(grpc.WithStatsHandler(h))
+}
+
+// WithKeepalive sends keepalive pings on idle connections to detect connections that were dropped by the network,
+// for example by load balancers that silently close idle connections.
+//
+// If params is the zero value, DefaultKeepaliveTime and DefaultKeepaliveTimeout are used and pings are permitted
+// without active streams. Otherwise, zero Time and Timeout fields are replaced with their defaults.
+func WithKeepalive(params keepalive.ClientParameters) ConnectionOption {
+	return func(options *ConnectionOptions) error {
+		if params == (keepalive.ClientParameters{}) {
+			params.PermitWithoutStream = true
+		}
+
+		if params.Time <= 0 {
+			params.Time = DefaultKeepaliveTime
+		}
+
+		if params.Timeout <= 0 {
+			params.Timeout = DefaultKeepaliveTimeout
+		}
+
+		options.DialOptions = append(options.DialOptions, grpc.WithKeepaliveParams(params))
+
+		return nil
+	}
+}
+
+// WithWaitForReady makes calls wait for the connection to become ready instead of failing immediately when the
+// service can't be reached. Calls wait until their context is done.
+//
+// Connections are established lazily on first use. Combined with WithWaitForReady, calls made while the connection
+// is being established are queued rather than failed, which avoids dialing ahead of time in environments where
+// startup latency matters.
+func WithWaitForReady() ConnectionOption {
+	return func(options *ConnectionOptions) error {
+		options.DialOptions = append(options.DialOptions, grpc.WithDefaultCallOptions(grpc.WaitForReady(true)))
+
+		return nil
+	}
+}
+
+// WithUserAgent sets the user agent that identifies the client in requests to the service, so that services
+// sharing an authorizer can be told apart in its access logs. Default: UserAgent().
+func WithUserAgent(userAgent string) ConnectionOption {
+	return func(options *ConnectionOptions) error {
+		options.UserAgent = userAgent
+		return nil
+	}
+}
+
 // WithHeader adds an header to the client config instance.
 func WithHeader(key, value string) ConnectionOption {
 	return func(options *ConnectionOptions) error {
@@ -2,11 +2,14 @@ package aserto_test
 
 import (
 	"context"
+	"errors"
 	"net/url"
 	"testing"
 
 	"github.com/aserto-dev/go-aserto"
 	assrt "github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/keepalive"
 )
 
 func TestWithAddr(t *testing.T) {
@@ -77,6 +80,40 @@ func TestWithBearerTokenAuth(t *testing.T) {
 	assert.Equal("bearer <token>", token)
 }
 
+func TestWithTokenProvider(t *testing.T) {
+	assert := assrt.New(t)
+
+	tokens := []string{"<token1>", "<token2>"}
+	calls := 0
+
+	options, err := aserto.NewConnectionOptions(
+		aserto.WithTokenProvider(func(context.Context) (string, error) {
+			calls++
+			return tokens[calls-1], nil
+		}),
+		aserto.WithTenantID("<tenant>"),
+	)
+	assert.NoError(err)
+
+	for _, expected := range []string{"bearer <token1>", "bearer <token2>"} {
+		md, err := options.Creds.GetRequestMetadata(context.TODO())
+		assert.NoError(err)
+		assert.Equal(expected, md["authorization"])
+	}
+}
+
+func TestWithTokenProviderError(t *testing.T) {
+	options, err := aserto.NewConnectionOptions(
+		aserto.WithTokenProvider(func(context.Context) (string, error) {
+			return "", errors.New("token expired")
+		}),
+	)
+	assrt.NoError(t, err)
+
+	_, err = options.Creds.GetRequestMetadata(context.TODO())
+	assrt.ErrorContains(t, err, "token expired")
+}
+
 func TestWithAPIKey(t *testing.T) {
 	assert := assrt.New(t)
 
@@ -104,6 +141,14 @@ func TestWithTenantID(t *testing.T) {
 	assert.Equal("<tenantid>", options.TenantID)
 }
 
+func TestWithUserAgent(t *testing.T) {
+	assert := assrt.New(t)
+	options, err := aserto.NewConnectionOptions(aserto.WithUserAgent("my-service/1.0"))
+	assert.NoError(err)
+
+	assert.Equal("my-service/1.0", options.UserAgent)
+}
+
 const (
 	caPath   = "/path/to/ca.crt"
 	certPath = "/path/to/cert.crt"
@@ -119,6 +164,39 @@ func TestWithCACertPath(t *testing.T) {
 	assert.Equal(caPath, options.CACertPath)
 }
 
+func TestWithCACertPaths(t *testing.T) {
+	assert := assrt.New(t)
+
+	options, err := aserto.NewConnectionOptions(
+		aserto.WithCACertPaths(caPath, "/path/to/ca2.crt"),
+		aserto.WithCACertPaths("/path/to/ca3.crt"),
+	)
+	assert.NoError(err)
+
+	assert.Equal([]string{caPath, "/path/to/ca2.crt", "/path/to/ca3.crt"}, options.CACertPaths)
+}
+
+func TestWithCACertDir(t *testing.T) {
+	assert := assrt.New(t)
+
+	options, err := aserto.NewConnectionOptions(aserto.WithCACertDir("/path/to/cas"))
+	assert.NoError(err)
+
+	assert.Equal("/path/to/cas", options.CACertDir)
+}
+
+func TestWithSystemCertPool(t *testing.T) {
+	assert := assrt.New(t)
+
+	options, err := aserto.NewConnectionOptions()
+	assert.NoError(err)
+	assert.False(options.NoSystemCertPool)
+
+	options, err = aserto.NewConnectionOptions(aserto.WithSystemCertPool(false))
+	assert.NoError(err)
+	assert.True(options.NoSystemCertPool)
+}
+
 func TestWithClientCert(t *testing.T) {
 	assert := assrt.New(t)
 
@@ -175,3 +253,24 @@ func TestWithAccountID(t *testing.T) {
 	assert.NoError(err)
 	assert.Equal("accountID", options.AccountID)
 }
+
+func TestWithKeepalive(t *testing.T) {
+	assert := assrt.New(t)
+	options, err := aserto.NewConnectionOptions(
+		aserto.WithDialOptions(grpc.WithUserAgent("test")),
+		aserto.WithKeepalive(keepalive.ClientParameters{}),
+	)
+	assert.NoError(err)
+	assert.Len(options.DialOptions, 2)
+
+	dialOptions, err := options.ToDialOptions()
+	assert.NoError(err)
+	assert.Subset(dialOptions, options.DialOptions)
+}
+
+func TestWithWaitForReady(t *testing.T) {
+	assert := assrt.New(t)
+	options, err := aserto.NewConnectionOptions(aserto.WithWaitForReady())
+	assert.NoError(err)
+	assert.Len(options.DialOptions, 1)
+}
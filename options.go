@@ -3,6 +3,7 @@ package aserto
 import (
 	"context"
 
+	"github.com/aserto-dev/header"
 	"github.com/hashicorp/go-multierror"
 	"github.com/pkg/errors"
 	"github.com/samber/lo"
@@ -62,8 +63,14 @@ func (o *ConnectionOptions) ToDialOptions() ([]grpc.DialOption, error) {
 		return nil, err
 	}
 
+	userAgent := o.UserAgent
+	if userAgent == "" {
+		userAgent = UserAgent()
+	}
+
 	opts := []grpc.DialOption{
 		transportCreds,
+		grpc.WithUserAgent(userAgent),
 		grpc.WithChainStreamInterceptor(o.StreamClientInterceptors...),
 		grpc.WithChainUnaryInterceptor(o.UnaryClientInterceptors...),
 	}
@@ -99,9 +106,13 @@ func (o *ConnectionOptions) transportCredentials() (grpc.DialOption, error) {
 	}
 
 	cfg := &TLSConfig{
-		Cert: o.ClientCertPath,
-		Key:  o.ClientKeyPath,
-		CA:   o.CACertPath,
+		Cert:    o.ClientCertPath,
+		Key:     o.ClientKeyPath,
+		CA:      o.CACertPath,
+		CAPaths: o.CACertPaths,
+		CADir:   o.CACertDir,
+
+		NoSystemCertPool: o.NoSystemCertPool,
 	}
 
 	creds, err := cfg.ClientCredentials(o.Insecure)
@@ -112,7 +123,12 @@ func (o *ConnectionOptions) transportCredentials() (grpc.DialOption, error) {
 	return grpc.WithTransportCredentials(creds), nil
 }
 
+// tenantContext adds the connection's tenant ID to outgoing calls that don't already specify a tenant.
 func (o *ConnectionOptions) tenantContext(ctx context.Context) context.Context {
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(string(header.HeaderAsertoTenantID))) > 0 {
+		return ctx
+	}
+
 	return SetTenantContext(ctx, o.TenantID)
 }
 
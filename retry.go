@@ -0,0 +1,228 @@
+package aserto
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	DefaultRetryMaxAttempts = 3
+	DefaultRetryBaseDelay   = 100 * time.Millisecond
+	DefaultRetryMaxDelay    = 5 * time.Second
+
+	DefaultRetryBudgetRatio     = 0.1
+	DefaultRetryBudgetMaxTokens = 10
+)
+
+// RetryPolicy determines how failed calls are retried.
+//
+// Zero values are replaced with defaults: DefaultRetryMaxAttempts, DefaultRetryBaseDelay, DefaultRetryMaxDelay,
+// and a predicate that retries calls that fail with codes.Unavailable or codes.DeadlineExceeded.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times a call is attempted, including the first attempt.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry. Subsequent delays grow exponentially.
+	BaseDelay time.Duration
+
+	// MaxDelay caps the delay between attempts.
+	MaxDelay time.Duration
+
+	// Retryable reports whether a call that failed with the given code should be retried.
+	Retryable func(codes.Code) bool
+
+	// Budget limits the number of retries relative to the number of calls. If nil, retries aren't limited.
+	Budget *RetryBudget
+}
+
+// RetryBudget is a token bucket that limits the ratio of retries to calls across all calls that share it.
+//
+// Each call deposits a fraction of a token into the bucket and each retry withdraws a whole token. When the bucket is
+// empty, failed calls aren't retried. This keeps retries from amplifying the load on a struggling authorizer during
+// sustained failures, while still allowing occasional transient errors to be retried.
+//
+// A RetryBudget can be shared by multiple connections to limit their combined retries.
+type RetryBudget struct {
+	ratio     float64
+	maxTokens float64
+
+	mu     sync.Mutex
+	tokens float64
+}
+
+// NewRetryBudget returns a retry budget that allows, on average, ratio retries per call (e.g. 0.1 allows one retry
+// for every ten calls). maxTokens is the bucket's capacity, which caps the number of retries allowed in a burst.
+// The bucket starts full.
+//
+// Non-positive values are replaced with DefaultRetryBudgetRatio and DefaultRetryBudgetMaxTokens.
+func NewRetryBudget(ratio float64, maxTokens int) *RetryBudget {
+	if ratio <= 0 {
+		ratio = DefaultRetryBudgetRatio
+	}
+
+	if maxTokens <= 0 {
+		maxTokens = DefaultRetryBudgetMaxTokens
+	}
+
+	return &RetryBudget{ratio: ratio, maxTokens: float64(maxTokens), tokens: float64(maxTokens)}
+}
+
+// deposit adds the budget's ratio to the bucket. It is called once for each call.
+func (b *RetryBudget) deposit() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = min(b.tokens+b.ratio, b.maxTokens)
+}
+
+// withdraw removes a token from the bucket and returns true if a retry is allowed.
+func (b *RetryBudget) withdraw() bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+
+	return true
+}
+
+// WithRetry retries calls that fail with transient errors using exponential backoff with jitter.
+//
+// Retries stop once the call's context is done or its deadline doesn't leave room for another attempt.
+// Streaming calls are only retried if the stream fails to be established.
+func WithRetry(policy RetryPolicy) ConnectionOption {
+	return func(options *ConnectionOptions) error {
+		r := policy.withDefaults()
+
+		options.UnaryClientInterceptors = append(options.UnaryClientInterceptors, r.unaryInterceptor)
+		options.StreamClientInterceptors = append(options.StreamClientInterceptors, r.streamInterceptor)
+
+		return nil
+	}
+}
+
+func (p RetryPolicy) withDefaults() *RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = DefaultRetryMaxAttempts
+	}
+
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = DefaultRetryBaseDelay
+	}
+
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = DefaultRetryMaxDelay
+	}
+
+	if p.Retryable == nil {
+		p.Retryable = isTransient
+	}
+
+	return &p
+}
+
+func (p *RetryPolicy) unaryInterceptor(
+	ctx context.Context,
+	method string,
+	req, reply interface{},
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	return p.retry(ctx, func() error {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	})
+}
+
+func (p *RetryPolicy) streamInterceptor(
+	ctx context.Context,
+	desc *grpc.StreamDesc,
+	cc *grpc.ClientConn,
+	method string,
+	streamer grpc.Streamer,
+	opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	var stream grpc.ClientStream
+
+	err := p.retry(ctx, func() error {
+		var err error
+		stream, err = streamer(ctx, desc, cc, method, opts...)
+
+		return err
+	})
+
+	return stream, err
+}
+
+func (p *RetryPolicy) retry(ctx context.Context, call func() error) error {
+	var err error
+
+	p.Budget.deposit()
+
+	for attempt := range p.MaxAttempts {
+		if err = call(); err == nil || !p.Retryable(status.Code(err)) {
+			return err
+		}
+
+		if attempt == p.MaxAttempts-1 {
+			break
+		}
+
+		// Retries that the deadline doesn't leave room for don't spend the budget.
+		delay := p.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			break
+		}
+
+		if !p.Budget.withdraw() {
+			break
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+
+	return err
+}
+
+// backoff returns the delay before the given retry attempt, using exponential backoff with full jitter.
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	delay := min(p.BaseDelay, p.MaxDelay)
+	for range attempt {
+		if delay >= p.MaxDelay/2 {
+			delay = p.MaxDelay
+			break
+		}
+
+		delay *= 2
+	}
+
+	return time.Duration(rand.Int64N(int64(delay)) + 1) //nolint:gosec
+}
+
+func isTransient(code codes.Code) bool {
+	return code == codes.Unavailable || code == codes.DeadlineExceeded
+}
@@ -0,0 +1,138 @@
+package aserto_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aserto-dev/go-aserto"
+	assrt "github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func retryInterceptor(t *testing.T, policy aserto.RetryPolicy) grpc.UnaryClientInterceptor {
+	options, err := aserto.NewConnectionOptions(aserto.WithRetry(policy))
+	assrt.NoError(t, err)
+	assrt.Len(t, options.UnaryClientInterceptors, 1)
+	assrt.Len(t, options.StreamClientInterceptors, 1)
+
+	return options.UnaryClientInterceptors[0]
+}
+
+func failingInvoker(calls *int, failures int, code codes.Code) grpc.UnaryInvoker {
+	return func(_ context.Context, _ string, _, _ interface{}, _ *grpc.ClientConn, _ ...grpc.CallOption) error {
+		*calls++
+		if *calls <= failures {
+			return status.Error(code, "failed")
+		}
+
+		return nil
+	}
+}
+
+func TestRetrySucceeds(t *testing.T) {
+	assert := assrt.New(t)
+	interceptor := retryInterceptor(t, aserto.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+
+	calls := 0
+	err := interceptor(context.Background(), "/svc/Method", nil, nil, nil, failingInvoker(&calls, 2, codes.Unavailable))
+	assert.NoError(err)
+	assert.Equal(3, calls)
+}
+
+func TestRetryMaxAttempts(t *testing.T) {
+	assert := assrt.New(t)
+	interceptor := retryInterceptor(t, aserto.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond})
+
+	calls := 0
+	err := interceptor(context.Background(), "/svc/Method", nil, nil, nil, failingInvoker(&calls, 5, codes.Unavailable))
+	assert.Equal(codes.Unavailable, status.Code(err))
+	assert.Equal(2, calls)
+}
+
+func TestRetryNonRetryableCode(t *testing.T) {
+	assert := assrt.New(t)
+	interceptor := retryInterceptor(t, aserto.RetryPolicy{BaseDelay: time.Millisecond})
+
+	calls := 0
+	err := interceptor(context.Background(), "/svc/Method", nil, nil, nil, failingInvoker(&calls, 5, codes.PermissionDenied))
+	assert.Equal(codes.PermissionDenied, status.Code(err))
+	assert.Equal(1, calls)
+}
+
+func TestRetryCustomPredicate(t *testing.T) {
+	assert := assrt.New(t)
+	interceptor := retryInterceptor(t, aserto.RetryPolicy{
+		BaseDelay: time.Millisecond,
+		Retryable: func(code codes.Code) bool { return code == codes.Internal },
+	})
+
+	calls := 0
+	err := interceptor(context.Background(), "/svc/Method", nil, nil, nil, failingInvoker(&calls, 1, codes.Internal))
+	assert.NoError(err)
+	assert.Equal(2, calls)
+}
+
+func TestRetryRespectsDeadline(t *testing.T) {
+	assert := assrt.New(t)
+	interceptor := retryInterceptor(t, aserto.RetryPolicy{MaxAttempts: 10, BaseDelay: time.Second, MaxDelay: time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	calls := 0
+	start := time.Now()
+	err := interceptor(ctx, "/svc/Method", nil, nil, nil, failingInvoker(&calls, 10, codes.Unavailable))
+
+	assert.Equal(codes.Unavailable, status.Code(err))
+	assert.Less(calls, 10)
+	assert.Less(time.Since(start), time.Second)
+}
+
+func TestRetryBudget(t *testing.T) {
+	assert := assrt.New(t)
+
+	budget := aserto.NewRetryBudget(0.5, 2)
+	interceptor := retryInterceptor(t, aserto.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, Budget: budget})
+
+	// The bucket starts with 2 tokens, so the first call is retried twice.
+	calls := 0
+	err := interceptor(context.Background(), "/svc/Method", nil, nil, nil, failingInvoker(&calls, 10, codes.Unavailable))
+	assert.Equal(codes.Unavailable, status.Code(err))
+	assert.Equal(3, calls)
+
+	// Each call deposits half a token. The second call adds to an empty bucket and isn't retried.
+	calls = 0
+	err = interceptor(context.Background(), "/svc/Method", nil, nil, nil, failingInvoker(&calls, 10, codes.Unavailable))
+	assert.Equal(codes.Unavailable, status.Code(err))
+	assert.Equal(1, calls)
+
+	// After the third call, the bucket has a full token again and allows one retry.
+	calls = 0
+	err = interceptor(context.Background(), "/svc/Method", nil, nil, nil, failingInvoker(&calls, 1, codes.Unavailable))
+	assert.NoError(err)
+	assert.Equal(2, calls)
+}
+
+func TestRetryBudgetDeadline(t *testing.T) {
+	assert := assrt.New(t)
+
+	budget := aserto.NewRetryBudget(0.5, 1)
+	interceptor := retryInterceptor(t, aserto.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, Budget: budget})
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	// The deadline doesn't leave room for a retry, so the call doesn't spend the budget.
+	calls := 0
+	err := interceptor(ctx, "/svc/Method", nil, nil, nil, failingInvoker(&calls, 10, codes.Unavailable))
+	assert.Equal(codes.Unavailable, status.Code(err))
+	assert.Equal(1, calls)
+
+	calls = 0
+	err = interceptor(context.Background(), "/svc/Method", nil, nil, nil, failingInvoker(&calls, 1, codes.Unavailable))
+	assert.NoError(err)
+	assert.Equal(2, calls)
+}
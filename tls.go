@@ -2,7 +2,9 @@ package aserto
 
 import (
 	"crypto/tls"
+	"crypto/x509"
 	"os"
+	"path/filepath"
 
 	"github.com/pkg/errors"
 	"google.golang.org/grpc/credentials"
@@ -17,6 +19,15 @@ type TLSConfig struct {
 	Cert string `json:"tls_cert_path"`
 	Key  string `json:"tls_key_path"`
 	CA   string `json:"tls_ca_cert_path"`
+
+	// CAPaths holds paths of additional CA certificates.
+	CAPaths []string `json:"tls_ca_cert_paths"`
+
+	// CADir is a directory of CA certificates. All files with a .pem, .crt, or .cer extension are loaded.
+	CADir string `json:"tls_ca_cert_dir"`
+
+	// NoSystemCertPool causes the configured CAs to replace the system roots instead of being appended to them.
+	NoSystemCertPool bool `json:"tls_no_system_cert_pool"`
 }
 
 func (c *TLSConfig) HasCert() bool {
@@ -24,7 +35,7 @@ func (c *TLSConfig) HasCert() bool {
 }
 
 func (c *TLSConfig) HasCA() bool {
-	return c != nil && c.CA != ""
+	return c != nil && (c.CA != "" || len(c.CAPaths) > 0 || c.CADir != "")
 }
 
 // ServerConfig returns TLS configuration for a server.
@@ -59,19 +70,21 @@ func (c *TLSConfig) ClientConfig(skipVerify bool) (*tls.Config, error) {
 		return conf, nil
 	}
 
-	certPool, err := tlsconf.CertPool(c.CA)
+	certPool, err := tlsconf.CertPool(c.HasCA(), !c.NoSystemCertPool)
 	if err != nil {
 		return conf, errors.Wrap(err, "failed to create certificate pool")
 	}
 
 	if c.HasCA() {
-		caCertBytes, err := os.ReadFile(c.CA)
+		caPaths, err := c.caPaths()
 		if err != nil {
-			return conf, errors.Wrapf(err, "failed to read ca cert: %s", c.CA)
+			return conf, err
 		}
 
-		if !certPool.AppendCertsFromPEM(caCertBytes) {
-			return conf, errors.Wrap(err, "failed to append client ca cert: %s")
+		for _, path := range caPaths {
+			if err := appendCACert(certPool, path); err != nil {
+				return conf, err
+			}
 		}
 	}
 
@@ -80,6 +93,52 @@ func (c *TLSConfig) ClientConfig(skipVerify bool) (*tls.Config, error) {
 	return conf, nil
 }
 
+// caPaths returns the paths of all configured CA certificates.
+func (c *TLSConfig) caPaths() ([]string, error) {
+	var paths []string
+
+	if c.CA != "" {
+		paths = append(paths, c.CA)
+	}
+
+	paths = append(paths, c.CAPaths...)
+
+	if c.CADir == "" {
+		return paths, nil
+	}
+
+	entries, err := os.ReadDir(c.CADir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read ca cert directory: %s", c.CADir)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		switch filepath.Ext(entry.Name()) {
+		case ".pem", ".crt", ".cer":
+			paths = append(paths, filepath.Join(c.CADir, entry.Name()))
+		}
+	}
+
+	return paths, nil
+}
+
+func appendCACert(certPool *x509.CertPool, path string) error {
+	caCertBytes, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read ca cert: %s", path)
+	}
+
+	if !certPool.AppendCertsFromPEM(caCertBytes) {
+		return errors.Errorf("failed to append ca cert: %s", path)
+	}
+
+	return nil
+}
+
 // ServerCredentials returns transport credentials for a GRPC server.
 func (c *TLSConfig) ServerCredentials() (credentials.TransportCredentials, error) {
 	if !c.HasCert() {
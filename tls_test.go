@@ -0,0 +1,180 @@
+package aserto_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aserto-dev/go-aserto"
+	assrt "github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeCACert creates a self-signed CA certificate and writes it to dir/name in PEM format.
+func writeCACert(t *testing.T, dir, name string) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), certPEM, 0o600))
+
+	return certPEM
+}
+
+func poolOf(pems ...[]byte) *x509.CertPool {
+	pool := x509.NewCertPool()
+	for _, p := range pems {
+		pool.AppendCertsFromPEM(p)
+	}
+
+	return pool
+}
+
+func TestTLSConfigCAPaths(t *testing.T) {
+	assert := assrt.New(t)
+	dir := t.TempDir()
+
+	ca1 := writeCACert(t, dir, "ca1.pem")
+	ca2 := writeCACert(t, dir, "ca2.pem")
+
+	cfg := &aserto.TLSConfig{
+		CA:               filepath.Join(dir, "ca1.pem"),
+		CAPaths:          []string{filepath.Join(dir, "ca2.pem")},
+		NoSystemCertPool: true,
+	}
+
+	conf, err := cfg.ClientConfig(false)
+	require.NoError(t, err)
+	assert.True(conf.RootCAs.Equal(poolOf(ca1, ca2)))
+}
+
+func TestTLSConfigCADir(t *testing.T) {
+	assert := assrt.New(t)
+	dir := t.TempDir()
+
+	ca1 := writeCACert(t, dir, "ca1.crt")
+	ca2 := writeCACert(t, dir, "ca2.pem")
+	_ = writeCACert(t, dir, "ignored.txt")
+
+	cfg := &aserto.TLSConfig{CADir: dir, NoSystemCertPool: true}
+
+	conf, err := cfg.ClientConfig(false)
+	require.NoError(t, err)
+	assert.True(conf.RootCAs.Equal(poolOf(ca1, ca2)))
+}
+
+func TestTLSConfigInvalidCA(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "invalid.pem")
+	require.NoError(t, os.WriteFile(path, []byte("not a certificate"), 0o600))
+
+	cfg := &aserto.TLSConfig{CAPaths: []string{path}}
+
+	_, err := cfg.ClientConfig(false)
+	assrt.Error(t, err)
+}
+
+func TestTLSConfigMissingCADir(t *testing.T) {
+	cfg := &aserto.TLSConfig{CADir: filepath.Join(t.TempDir(), "missing")}
+
+	_, err := cfg.ClientConfig(false)
+	assrt.Error(t, err)
+}
+
+func TestTLSConfigAppendsToSystemCertPool(t *testing.T) {
+	assert := assrt.New(t)
+	dir := t.TempDir()
+
+	ca := writeCACert(t, dir, "private.pem")
+
+	expected, err := x509.SystemCertPool()
+	require.NoError(t, err)
+	expected.AppendCertsFromPEM(ca)
+
+	cfg := &aserto.TLSConfig{CA: filepath.Join(dir, "private.pem")}
+
+	conf, err := cfg.ClientConfig(false)
+	require.NoError(t, err)
+
+	// Hosts with certificates issued by public CAs still verify.
+	assert.True(conf.RootCAs.Equal(expected))
+	assert.False(conf.RootCAs.Equal(poolOf(ca)))
+}
+
+// writeKeyPair creates a self-signed client certificate and writes it and its private key to dir in PEM format.
+// It returns the paths of the certificate and key files and the DER-encoded certificate.
+func writeKeyPair(t *testing.T, dir string) (string, string, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certPath := filepath.Join(dir, "client.crt")
+	keyPath := filepath.Join(dir, "client.key")
+
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+
+	return certPath, keyPath, der
+}
+
+func TestTLSConfigClientCert(t *testing.T) {
+	assert := assrt.New(t)
+
+	certPath, keyPath, der := writeKeyPair(t, t.TempDir())
+
+	cfg := &aserto.TLSConfig{Cert: certPath, Key: keyPath}
+
+	conf, err := cfg.ClientConfig(false)
+	require.NoError(t, err)
+	require.Len(t, conf.Certificates, 1)
+	assert.Equal(der, conf.Certificates[0].Certificate[0])
+}
+
+func TestClientCertConnection(t *testing.T) {
+	certPath, keyPath, _ := writeKeyPair(t, t.TempDir())
+
+	conn, err := aserto.NewConnection(aserto.WithAddr("localhost:8282"), aserto.WithClientCert(certPath, keyPath))
+	require.NoError(t, err)
+	assrt.NoError(t, conn.Close())
+
+	_, err = aserto.NewConnection(aserto.WithAddr("localhost:8282"), aserto.WithClientCert(certPath, certPath))
+	assrt.Error(t, err)
+}
@@ -0,0 +1,52 @@
+package aserto
+
+import "runtime/debug"
+
+const modulePath = "github.com/aserto-dev/go-aserto"
+
+// Version is the version of go-aserto linked into the binary.
+//
+// It can be set at build time with:
+//
+//	go build -ldflags "-X github.com/aserto-dev/go-aserto.Version=v1.2.3"
+//
+// Otherwise, it is read from the binary's build information. If that isn't available (e.g. in tests), it is "dev".
+var Version = ""
+
+func init() { //nolint:gochecknoinits // Version must be set before it is read
+	if Version == "" {
+		Version = buildVersion()
+	}
+}
+
+// UserAgent returns the default user agent of connections to Aserto services, "go-aserto/<Version>".
+func UserAgent() string {
+	return "go-aserto/" + Version
+}
+
+func buildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "dev"
+	}
+
+	if info.Main.Path == modulePath && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path != modulePath {
+			continue
+		}
+
+		if dep.Replace != nil {
+			dep = dep.Replace
+		}
+
+		if dep.Version != "" && dep.Version != "(devel)" {
+			return dep.Version
+		}
+	}
+
+	return "dev"
+}
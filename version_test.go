@@ -0,0 +1,15 @@
+package aserto_test
+
+import (
+	"testing"
+
+	"github.com/aserto-dev/go-aserto"
+	assrt "github.com/stretchr/testify/assert"
+)
+
+func TestUserAgent(t *testing.T) {
+	assert := assrt.New(t)
+
+	assert.NotEmpty(aserto.Version)
+	assert.Equal("go-aserto/"+aserto.Version, aserto.UserAgent())
+}